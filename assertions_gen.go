@@ -0,0 +1,61 @@
+// Code generated by genassert; DO NOT EDIT.
+
+package main
+
+// Compile-time checks that each type implements its interfaces.
+// Regenerate with: go generate ./...
+var (
+	_ Annotated      = (*AudioBook)(nil)
+	_ Annotated      = (*Book)(nil)
+	_ Annotated      = (*Bundle)(nil)
+	_ Annotated      = (*EBook)(nil)
+	_ Annotated      = (*Magazine)(nil)
+	_ Annotated      = (*annotations)(nil)
+	_ Categorized    = (*AudioBook)(nil)
+	_ Categorized    = (*Book)(nil)
+	_ Categorized    = (*Bundle)(nil)
+	_ Categorized    = (*EBook)(nil)
+	_ Categorized    = (*Magazine)(nil)
+	_ DiscountPolicy = (*BulkDiscount)(nil)
+	_ DiscountPolicy = (*Coupon)(nil)
+	_ DiscountPolicy = (*EditionBundleDiscount)(nil)
+	_ DiscountPolicy = (*MemberPricing)(nil)
+	_ DiscountPolicy = (*MembersOnly)(nil)
+	_ DiscountPolicy = (*PercentageDiscount)(nil)
+	_ DiscountPolicy = (*SeasonalDiscount)(nil)
+	_ DiscountPolicy = (*StoreSale)(nil)
+	_ Event          = (*ItemsSynced)(nil)
+	_ Event          = (*OrderPlaced)(nil)
+	_ Event          = (*PriceChanged)(nil)
+	_ Event          = (*Restocked)(nil)
+	_ Event          = (*StockDepleted)(nil)
+	_ Notifier       = (*EmailNotifier)(nil)
+	_ Notifier       = (*TerminalNotifier)(nil)
+	_ Notifier       = (*WebhookNotifier)(nil)
+	_ OrderSequence  = (*FileSequence)(nil)
+	_ OrderSequence  = (*SQLRepository)(nil)
+	_ OutboxStore    = (*MemoryRepository)(nil)
+	_ OutboxStore    = (*SQLRepository)(nil)
+	_ PriceExplainer = (*AudioBook)(nil)
+	_ PriceHistorian = (*AudioBook)(nil)
+	_ PriceHistorian = (*Book)(nil)
+	_ PriceHistorian = (*EBook)(nil)
+	_ PriceHistorian = (*Magazine)(nil)
+	_ PricedItem     = (*AudioBook)(nil)
+	_ PricedItem     = (*Book)(nil)
+	_ PricedItem     = (*Bundle)(nil)
+	_ PricedItem     = (*EBook)(nil)
+	_ PricedItem     = (*Magazine)(nil)
+	_ RateProvider   = (*HTTPRates)(nil)
+	_ RateProvider   = (*StaticRates)(nil)
+	_ Repository     = (*CachedRepository)(nil)
+	_ Repository     = (*MemoryRepository)(nil)
+	_ Repository     = (*SQLRepository)(nil)
+	_ TaxCalculator  = (*FlatTax)(nil)
+	_ TaxCalculator  = (*RegionalTax)(nil)
+	_ Translatable   = (*AudioBook)(nil)
+	_ Translatable   = (*Book)(nil)
+	_ Translatable   = (*Bundle)(nil)
+	_ Translatable   = (*EBook)(nil)
+	_ Translatable   = (*Magazine)(nil)
+)
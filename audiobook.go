@@ -0,0 +1,170 @@
+package main
+
+// ------------------- AUDIOBOOKS ------------------------------
+// AudioBook is another PricedItem, priced in one of two ways:
+//   - bought outright, at its list price
+//   - with a subscription credit: subscribers pay nothing per title, so
+//     Price reports zero, and PriceNote says why
+//
+// Code that wants the explanation asks for the optional PriceExplainer
+// interface with a type assertion (see printItemPriceInfo), the same way
+// io.Copy checks whether a Reader also has a WriteTo method.
+
+import (
+	"fmt"
+	"time"
+)
+
+// AudioBookCategoryCode is the category of every audiobook
+const AudioBookCategoryCode = "AUDIOBOOK"
+
+// AudioPricing says how an audiobook is paid for
+type AudioPricing int
+
+const (
+	PayPerTitle AudioPricing = iota
+	SubscriptionCredit
+)
+
+func (p AudioPricing) String() string {
+	switch p {
+	case PayPerTitle:
+		return "purchase"
+	case SubscriptionCredit:
+		return "credit"
+	default:
+		return fmt.Sprintf("AudioPricing(%d)", int(p))
+	}
+}
+
+// PriceExplainer is implemented by items whose price needs a word of
+// explanation, such as a zero price
+type PriceExplainer interface {
+	PriceNote() string
+}
+
+type AudioBook struct {
+	title     string
+	author    string
+	narrator  string
+	listPrice Money
+	duration  time.Duration
+	// Pricing chooses between the list price and a subscription credit
+	Pricing     AudioPricing
+	Description string
+	translations
+	annotations
+	priceLog
+}
+
+// NewAudioBook returns an audiobook sold at price
+func NewAudioBook(title, author, narrator string, price Money, duration time.Duration) (*AudioBook, error) {
+	a := &AudioBook{
+		title:     title,
+		author:    author,
+		narrator:  narrator,
+		listPrice: price,
+		duration:  duration,
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// HourlyPrice prices a recording of length d at rate per hour, the
+// way many publishers set audiobook prices
+func HourlyPrice(rate Money, d time.Duration) Money {
+	return rate.Scale(d.Hours())
+}
+
+func (a *AudioBook) Summary() string {
+	price := a.Price().String()
+	if a.Pricing == SubscriptionCredit {
+		price = "1 credit"
+	}
+	return fmt.Sprintf("%s by %s, read by %s (%s) - %s",
+		a.title, a.author, a.narrator, formatListeningTime(a.duration), price)
+}
+
+func (a *AudioBook) Category() string {
+	return AudioBookCategoryCode
+}
+
+// Price is what the customer pays: zero with a subscription credit
+func (a *AudioBook) Price() Money {
+	if a.Pricing == SubscriptionCredit {
+		return Money{currency: a.listPrice.currency}
+	}
+	return a.listPrice
+}
+
+// ListPrice is the price for buying the title outright
+func (a *AudioBook) ListPrice() Money {
+	return a.listPrice
+}
+
+// SetPrice sets the list price, also in credit mode
+func (a *AudioBook) SetPrice(price Money) error {
+	return a.SetPriceBecause(price, "")
+}
+
+// SetPriceBecause sets the list price and records why; the history is
+// of list prices
+func (a *AudioBook) SetPriceBecause(price Money, reason string) error {
+	if price.IsNegative() {
+		return ErrNegativePrice
+	}
+	a.recordPrice(a, a.listPrice, price, reason)
+	a.listPrice = price
+	return nil
+}
+
+func (a *AudioBook) CalculateDiscount(percentage Percent) (Money, error) {
+	discounted, _ := PercentageDiscount{Percent: percentage}.Apply(a.Price(), DiscountContext{Item: a})
+	return discounted, nil
+}
+
+// PriceNote explains a zero price; it is empty when the price needs
+// no explanation
+func (a *AudioBook) PriceNote() string {
+	if a.Pricing == SubscriptionCredit {
+		return fmt.Sprintf("included with a subscription credit (%v to buy)", a.listPrice)
+	}
+	return ""
+}
+
+func (a *AudioBook) Narrator() string {
+	return a.narrator
+}
+
+func (a *AudioBook) Duration() time.Duration {
+	return a.duration
+}
+
+// MaxAudioDuration rejects lengths that are surely a typo; the longest
+// commercial audiobooks run to a few hundred hours
+const MaxAudioDuration = 1000 * time.Hour
+
+func (a *AudioBook) SetDuration(d time.Duration) error {
+	if d <= 0 || d > MaxAudioDuration {
+		return fmt.Errorf("duration must be between 1s and %v", MaxAudioDuration)
+	}
+	a.duration = d
+	return nil
+}
+
+// PricePerHour is the list price divided by the listening time, handy
+// for comparing titles of different lengths
+func (a *AudioBook) PricePerHour() (Money, error) {
+	if a.duration <= 0 {
+		return Money{}, fmt.Errorf("audiobook has no duration")
+	}
+	return a.listPrice.Scale(1 / a.duration.Hours()), nil
+}
+
+// formatListeningTime prints "11h 5m" rather than Duration's "11h5m0s"
+func formatListeningTime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
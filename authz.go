@@ -0,0 +1,222 @@
+package main
+
+// ------------------- ROLES AND PERMISSIONS -------------------
+// Every staff action is checked in ONE place, the Authorizer, instead of
+// each feature testing roles itself. Roles are ordered: each one can do
+// everything the previous one can, plus a bit more.
+//
+//	viewer  -> look at the catalog and reports
+//	clerk   -> + adjust stock and read and write staff notes
+//	manager -> + change prices and the catalog
+//	admin   -> + manage staff and impersonate other users
+//
+// Every decision, allowed or denied, is written to an audit log.
+// An admin can impersonate another user to see exactly what they see;
+// the audit log then records both names.
+//
+// The CLI checks each command (see cli.go) and the HTTP API each write
+// (see server.go), where callers identify themselves with an API key.
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrPermissionDenied matches every denial from Authorize via errors.Is
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Role is a staff member's level of access
+type Role int
+
+const (
+	RoleViewer Role = iota
+	RoleClerk
+	RoleManager
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleClerk:
+		return "clerk"
+	case RoleManager:
+		return "manager"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+// ParseRole turns "manager" into RoleManager
+func ParseRole(s string) (Role, error) {
+	for r := RoleViewer; r <= RoleAdmin; r++ {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q", s)
+}
+
+// Permission names one kind of action
+type Permission string
+
+const (
+	PermViewCatalog   Permission = "catalog:view"
+	PermAdjustStock   Permission = "stock:adjust"
+	PermManageNotes   Permission = "notes:manage"
+	PermEditPrices    Permission = "prices:edit"
+	PermManageCatalog Permission = "catalog:manage"
+	PermManageStaff   Permission = "staff:manage"
+	PermImpersonate   Permission = "staff:impersonate"
+)
+
+// minimumRole is the lowest role holding each permission.
+// Because roles are ordered, "role >= minimum" is the whole check.
+var minimumRole = map[Permission]Role{
+	PermViewCatalog:   RoleViewer,
+	PermAdjustStock:   RoleClerk,
+	PermManageNotes:   RoleClerk,
+	PermEditPrices:    RoleManager,
+	PermManageCatalog: RoleManager,
+	PermManageStaff:   RoleAdmin,
+	PermImpersonate:   RoleAdmin,
+}
+
+// Actor is whoever performs an action
+type Actor struct {
+	Name string
+	Role Role
+	// ImpersonatedBy is the admin acting as this user, if any
+	ImpersonatedBy string
+}
+
+func (a Actor) String() string {
+	if a.ImpersonatedBy != "" {
+		return fmt.Sprintf("%s (%s, impersonated by %s)", a.Name, a.Role, a.ImpersonatedBy)
+	}
+	return fmt.Sprintf("%s (%s)", a.Name, a.Role)
+}
+
+// AuditEntry records one authorization decision
+type AuditEntry struct {
+	At         time.Time
+	Actor      Actor
+	Permission Permission
+	Action     string
+	Allowed    bool
+}
+
+// Authorizer decides what actors may do and remembers every decision.
+// It is safe for concurrent use: the API authorizes from many requests
+// at once.
+type Authorizer struct {
+	mu    sync.Mutex
+	audit []AuditEntry
+	// keys maps the SHA-256 of each API key to its holder; the keys
+	// themselves are not kept
+	keys map[[sha256.Size]byte]Actor
+	now  func() time.Time
+}
+
+func NewAuthorizer() *Authorizer {
+	return &Authorizer{keys: make(map[[sha256.Size]byte]Actor), now: currentTime}
+}
+
+// AddKey lets the holder of key act as actor, e.g. from the HTTP API
+func (a *Authorizer) AddKey(key string, actor Actor) error {
+	// Short keys can be guessed
+	if len(key) < 16 {
+		return fmt.Errorf("API key for %s must be at least 16 characters", actor.Name)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[sha256.Sum256([]byte(key))] = actor
+	return nil
+}
+
+// ActorForKey returns who holds key; ok is false for an unknown key
+func (a *Authorizer) ActorForKey(key string) (actor Actor, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	actor, ok = a.keys[sha256.Sum256([]byte(key))]
+	return actor, ok
+}
+
+// LoadKeys reads API keys from a staff file, which should be readable
+// by the server's account only:
+//
+//	[{"name": "alex", "role": "admin", "key": "..."}]
+func (a *Authorizer) LoadKeys(r io.Reader) error {
+	var staff []struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+		Key  string `json:"key"`
+	}
+	if err := json.NewDecoder(r).Decode(&staff); err != nil {
+		return fmt.Errorf("reading staff file: %w", err)
+	}
+	for _, s := range staff {
+		role, err := ParseRole(s.Role)
+		if err != nil {
+			return fmt.Errorf("staff member %q: %w", s.Name, err)
+		}
+		if err := a.AddKey(s.Key, Actor{Name: s.Name, Role: role}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Authorize checks that actor holds perm for the described action.
+// Both outcomes are audited; a denial is returned as an error.
+func (a *Authorizer) Authorize(actor Actor, perm Permission, action string) error {
+	minimum, known := minimumRole[perm]
+	allowed := known && actor.Role >= minimum
+	a.record(actor, perm, action, allowed)
+	if !allowed {
+		return fmt.Errorf("%w: %s may not %s (needs %s)", ErrPermissionDenied, actor, action, perm)
+	}
+	return nil
+}
+
+// record adds one entry to the audit log. Authorize calls it for every
+// decision; code undoing an action it was already allowed calls it
+// directly, so the undo is on record without being a new decision.
+func (a *Authorizer) record(actor Actor, perm Permission, action string, allowed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.audit = append(a.audit, AuditEntry{
+		At:         a.now(),
+		Actor:      actor,
+		Permission: perm,
+		Action:     action,
+		Allowed:    allowed,
+	})
+}
+
+// Impersonate lets an admin act as target. The returned Actor has the
+// target's role, so checks behave exactly as they would for target,
+// while audit entries still name the admin.
+func (a *Authorizer) Impersonate(admin, target Actor) (Actor, error) {
+	if err := a.Authorize(admin, PermImpersonate, "impersonate "+target.Name); err != nil {
+		return Actor{}, err
+	}
+	target.ImpersonatedBy = admin.Name
+	return target, nil
+}
+
+// AuditLog returns a copy of all recorded decisions, oldest first
+func (a *Authorizer) AuditLog() []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.audit)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestAuthorizeConcurrent(t *testing.T) {
+	authz := NewAuthorizer()
+	clerk := Actor{Name: "sam", Role: RoleClerk}
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			authz.Authorize(clerk, PermAdjustStock, "restock MG-001")
+			authz.Authorize(clerk, PermEditPrices, "change the price of BK-001")
+		}()
+	}
+	wg.Wait()
+	log := authz.AuditLog()
+	assert.Equal(t, len(log), 200)
+	allowed := 0
+	for _, e := range log {
+		if e.Allowed {
+			allowed++
+		}
+	}
+	assert.Equal(t, allowed, 100)
+}
+
+func TestAuthorizeDenial(t *testing.T) {
+	authz := NewAuthorizer()
+	err := authz.Authorize(Actor{Name: "sam", Role: RoleClerk}, PermEditPrices, "change a price")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.NoError(t, authz.Authorize(Actor{Name: "kim", Role: RoleManager}, PermEditPrices, "change a price"))
+}
+
+func TestLoadKeys(t *testing.T) {
+	authz := NewAuthorizer()
+	err := authz.LoadKeys(strings.NewReader(`[
+		{"name": "kim", "role": "manager", "key": "kim-0123456789abcdef"}]`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	actor, ok := authz.ActorForKey("kim-0123456789abcdef")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, actor, Actor{Name: "kim", Role: RoleManager})
+	_, ok = authz.ActorForKey("kim")
+	assert.Equal(t, ok, false)
+
+	for _, bad := range []string{
+		`[{"name": "sam", "role": "boss", "key": "sam-0123456789abcdef"}]`,
+		`[{"name": "sam", "role": "clerk", "key": "short"}]`,
+		`{"name": "sam"}`,
+	} {
+		if err := NewAuthorizer().LoadKeys(strings.NewReader(bad)); err == nil {
+			t.Errorf("LoadKeys(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestCLIAuthorizesCommands(t *testing.T) {
+	s := &cliSession{
+		catalog: sampleCatalog(),
+		user:    Actor{Name: "sam", Role: RoleClerk},
+		authz:   NewAuthorizer(),
+	}
+	var out bytes.Buffer
+	assert.NoError(t, s.runCommand([]string{"price", "-sku", "BK-001"}, &out))
+	err := s.runCommand([]string{"discount", "-sku", "BK-001", "-percent", "50"}, &out)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Equal(t, Must(s.catalog.Get("BK-001")).Price(), Dollars(12.99))
+	assert.Equal(t, len(s.authz.AuditLog()), 2)
+}
+
+func TestCLIUserFromEnvironment(t *testing.T) {
+	t.Setenv("BOOKSTORE_USER", "sam")
+	t.Setenv("BOOKSTORE_ROLE", "clerk")
+	user, err := cliUser()
+	if assert.NoError(t, err) {
+		assert.Equal(t, user, Actor{Name: "sam", Role: RoleClerk})
+	}
+	t.Setenv("BOOKSTORE_ROLE", "boss")
+	if _, err := cliUser(); err == nil {
+		t.Error("accepted an unknown role")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+// ------------------- BATCH API -------------------------------
+// POST /batch runs several operations in one request, so an admin tool
+// importing 500 items doesn't need 500 round trips:
+//
+//	{"transactional": true, "operations": [
+//	  {"op": "create", "sku": "BK-002", "type": "book", "item": {...}},
+//	  {"op": "update_price", "sku": "BK-002", "price": 9.99},
+//	  {"op": "adjust_stock", "sku": "BK-002", "delta": 5}
+//	]}
+//
+// Operations run in order and each gets its own result. Normally a
+// failed operation doesn't stop the others. With "transactional" the
+// batch is all-or-nothing: the first failure undoes everything done so
+// far, like a database transaction's ROLLBACK.
+//
+// Undoing works with an undo log: every successful operation returns a
+// function that reverses it, and a rollback calls them newest first.
+// Undoing a price change bypasses the throttle but not the audit log.
+//
+// Each operation needs the permission its own request would, and
+// adjust_stock needs stock:adjust; a denied one fails with 403.
+//
+// If the request's context ends (timeout, client gone) between two
+// operations, the rest are not run; a transactional batch rolls back.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// maxBatchOperations keeps one request from holding the lock for long
+const maxBatchOperations = 100
+
+type batchRequest struct {
+	Transactional bool             `json:"transactional"`
+	Operations    []batchOperation `json:"operations"`
+}
+
+// batchOperation is one entry of a batch; which fields apply depends on Op
+type batchOperation struct {
+	Op  string `json:"op"` // "create", "update_price" or "adjust_stock"
+	SKU string `json:"sku"`
+	// create
+	Type string          `json:"type,omitempty"`
+	Item json.RawMessage `json:"item,omitempty"`
+	// update_price
+	Price    json.Number `json:"price,omitempty"`
+	Currency string      `json:"currency,omitempty"`
+	Reason   string      `json:"reason,omitempty"`
+	// adjust_stock: positive to add units, negative to write them off
+	Delta int `json:"delta,omitempty"`
+}
+
+type batchResult struct {
+	Op     string        `json:"op"`
+	SKU    string        `json:"sku"`
+	Status int           `json:"status"` // the HTTP status the single request would get
+	Error  string        `json:"error,omitempty"`
+	Item   *itemResponse `json:"item,omitempty"`
+	// Available is the stock after an adjust_stock
+	Available *int `json:"available,omitempty"`
+	// RolledBack marks work undone because a later operation failed
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+type batchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []batchResult `json:"results"`
+}
+
+func (s *CatalogServer) batch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := decodeBody(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("a batch needs 1 to %d operations", maxBatchOperations))
+		return
+	}
+
+	// One batch at a time: a rollback must not undo another batch's work
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	resp := batchResponse{Committed: true, Results: make([]batchResult, 0, len(req.Operations))}
+	var undo []func()
+	for _, op := range req.Operations {
+		// Out of time: op is not run, and neither is anything after it
+		if err := r.Context().Err(); err != nil {
+			resp.Results = append(resp.Results, batchResult{
+				Op: op.Op, SKU: op.SKU, Status: http.StatusServiceUnavailable, Error: err.Error(),
+			})
+			if !req.Transactional {
+				writeJSON(w, http.StatusServiceUnavailable, resp)
+				return
+			}
+		} else {
+			result, rollback := s.runBatchOperation(op, requestCaller(r), requestLanguages(r))
+			resp.Results = append(resp.Results, result)
+			if result.Error == "" {
+				undo = append(undo, rollback)
+				continue
+			}
+		}
+		if req.Transactional {
+			for _, u := range slices.Backward(undo) {
+				u()
+			}
+			for i := range len(resp.Results) - 1 {
+				resp.Results[i].RolledBack = true
+				resp.Results[i].Item = nil
+				resp.Results[i].Available = nil
+			}
+			resp.Committed = false
+			// Operations after the failure were never attempted
+			writeJSON(w, http.StatusConflict, resp)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchPermissions is what the caller needs for each op
+var batchPermissions = map[string]Permission{
+	"create":       PermManageCatalog,
+	"update_price": PermEditPrices,
+	"adjust_stock": PermAdjustStock,
+}
+
+// runBatchOperation applies op for caller and returns how to undo it.
+// The caller holds s.batchMu.
+func (s *CatalogServer) runBatchOperation(op batchOperation, caller Actor, langs []string) (batchResult, func()) {
+	result := batchResult{Op: op.Op, SKU: op.SKU}
+	fail := func(status int, err error) (batchResult, func()) {
+		result.Status = status
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	perm, ok := batchPermissions[op.Op]
+	if !ok {
+		return fail(http.StatusBadRequest, fmt.Errorf("unknown op %q: want create, update_price or adjust_stock", op.Op))
+	}
+	if err := s.Authz.Authorize(caller, perm, fmt.Sprintf("%s %s", op.Op, op.SKU)); err != nil {
+		return fail(http.StatusForbidden, err)
+	}
+
+	if op.Op == "create" {
+		item, err := decodeItem(op.Type, op.Item)
+		if err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		if err := s.catalog.Add(op.SKU, item); err != nil {
+			return fail(addErrorStatus(s.catalog, op.SKU), err)
+		}
+		created := s.response(op.SKU, langs)
+		result.Status, result.Item = http.StatusCreated, &created
+		return result, func() { s.catalog.Remove(op.SKU) }
+	}
+
+	item, err := s.catalog.Get(op.SKU)
+	if err != nil {
+		return fail(http.StatusNotFound, err)
+	}
+	switch op.Op {
+	case "update_price":
+		price, err := parsePrice(op.Price, op.Currency)
+		if err != nil {
+			return fail(http.StatusBadRequest, err)
+		}
+		old, err := s.catalog.Price(op.SKU)
+		if err != nil {
+			return fail(http.StatusNotFound, err)
+		}
+		if err := s.changePrice(op.SKU, item, price, op.Reason); err != nil {
+			return fail(priceErrorStatus(err), err)
+		}
+		resp := s.response(op.SKU, langs)
+		result.Status, result.Item = http.StatusOK, &resp
+		// The undo skips the throttle: it puts back a price the throttle
+		// already let go, and a rollback that could be refused would
+		// leave the batch half applied. It is still audited, and the
+		// price history records it with its reason.
+		return result, func() {
+			s.Authz.record(caller, PermEditPrices, fmt.Sprintf("roll back update_price %s to %v", op.SKU, old), true)
+			s.catalog.SetPrice(op.SKU, old, "batch rolled back")
+		}
+	case "adjust_stock":
+		restore := s.inventory.snapshot(item)
+		if err := s.inventory.Adjust(item, op.Delta); err != nil {
+			return fail(http.StatusUnprocessableEntity, err)
+		}
+		available := s.inventory.AvailableQuantity(item)
+		result.Status, result.Available = http.StatusOK, &available
+		return result, restore
+	default:
+		return fail(http.StatusBadRequest, fmt.Errorf("unknown op %q: want create, update_price or adjust_stock", op.Op))
+	}
+}
+
+// snapshot saves item's stock level and returns a function that puts it
+// back exactly, lots and pack counts included
+func (inv *Inventory) snapshot(item PricedItem) func() {
+	l, ok := inv.levels[item]
+	if !ok {
+		return func() { delete(inv.levels, item) }
+	}
+	saved := *l
+	// The struct copy shares the slices' arrays, so copy those too
+	saved.sales = slices.Clone(l.sales)
+	saved.lots = slices.Clone(l.lots)
+	saved.packs.breaks = slices.Clone(l.packs.breaks)
+	return func() { *l = saved }
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+// Operations for the batch tests; batchFail's item is missing, so it fails with 404
+const (
+	batchCreate = `{"op": "create", "sku": "BK-002", "type": "book", "item": {"title": "Dune", "author": "Frank Herbert", "price": 9.99}}`
+	batchPrice  = `{"op": "update_price", "sku": "MG-001", "price": 10.49, "reason": "spring sale"}`
+	batchStock  = `{"op": "adjust_stock", "sku": "MG-001", "delta": 5}`
+	batchFail   = `{"op": "update_price", "sku": "BK-404", "price": 1}`
+)
+
+// sendBatch posts a batch of ops as kim, with ctx as the request's context
+func sendBatch(t *testing.T, server *CatalogServer, ctx context.Context, transactional bool, ops ...string) (int, batchResponse) {
+	t.Helper()
+	body := `{"transactional": ` + strconv.FormatBool(transactional) +
+		`, "operations": [` + strings.Join(ops, ",") + `]}`
+	req := httptest.NewRequestWithContext(ctx, "POST", "/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+managerKey)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	var resp batchResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestBatchRollback(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		transactional bool
+		ops           []string
+		wantCode      int
+		wantStatus    []int
+		// wantKept is whether the successful operations' effects remain
+		wantKept bool
+	}{
+		{"all succeed", context.Background(), true,
+			[]string{batchCreate, batchPrice, batchStock}, http.StatusOK, []int{201, 200, 200}, true},
+		{"create undone", context.Background(), true,
+			[]string{batchCreate, batchFail}, http.StatusConflict, []int{201, 404}, false},
+		{"price undone", context.Background(), true,
+			[]string{batchPrice, batchFail}, http.StatusConflict, []int{200, 404}, false},
+		{"stock undone", context.Background(), true,
+			[]string{batchStock, batchFail}, http.StatusConflict, []int{200, 404}, false},
+		{"everything undone", context.Background(), true,
+			[]string{batchCreate, batchPrice, batchStock, batchFail, batchStock}, http.StatusConflict, []int{201, 200, 200, 404}, false},
+		{"not transactional", context.Background(), false,
+			[]string{batchCreate, batchPrice, batchStock, batchFail}, http.StatusOK, []int{201, 200, 200, 404}, true},
+		{"cancelled, transactional", cancelled, true,
+			[]string{batchPrice}, http.StatusConflict, []int{503}, false},
+		{"cancelled", cancelled, false,
+			[]string{batchPrice, batchStock}, http.StatusServiceUnavailable, []int{503}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			code, resp := sendBatch(t, server, tt.ctx, tt.transactional, tt.ops...)
+			assert.Equal(t, code, tt.wantCode)
+			// Only a transactional batch undoes work, answering 409
+			assert.Equal(t, resp.Committed, tt.wantCode != http.StatusConflict)
+			assert.Equal(t, Map(resp.Results, func(r batchResult) int { return r.Status }), tt.wantStatus)
+
+			// Every result before a transactional batch's failure is
+			// marked rolled back and carries no item or stock level
+			for i, r := range resp.Results {
+				undone := !resp.Committed && tt.transactional && i < len(resp.Results)-1
+				assert.Equal(t, r.RolledBack, undone)
+				if undone && (r.Item != nil || r.Available != nil) {
+					t.Errorf("result %d was rolled back but reports %+v", i, r)
+				}
+			}
+
+			magazine := Must(server.catalog.Get("MG-001"))
+			_, err := server.catalog.Get("BK-002")
+			created := err == nil
+			priced := Must(server.catalog.Price("MG-001")) == Dollars(10.49)
+			stocked := server.inventory.AvailableQuantity(magazine) == 5
+			ran := strings.Join(tt.ops, ",")
+			assert.Equal(t, created, tt.wantKept && strings.Contains(ran, batchCreate))
+			assert.Equal(t, priced, tt.wantKept && strings.Contains(ran, batchPrice))
+			assert.Equal(t, stocked, tt.wantKept && strings.Contains(ran, batchStock))
+		})
+	}
+}
+
+func TestBatchRollbackIsAudited(t *testing.T) {
+	server := newTestServer(t)
+	code, _ := sendBatch(t, server, context.Background(), true, batchPrice, batchFail)
+	assert.Equal(t, code, http.StatusConflict)
+
+	// The price history shows the change and its undoing
+	history := Must(server.catalog.Get("MG-001")).(PriceHistorian).PriceHistory()
+	assert.Equal(t, Map(history, func(c PriceChange) string { return c.Reason }), []string{"spring sale", "batch rolled back"})
+
+	var undo []AuditEntry
+	for _, e := range server.Authz.AuditLog() {
+		if strings.HasPrefix(e.Action, "roll back") {
+			undo = append(undo, e)
+		}
+	}
+	if assert.Equal(t, len(undo), 1) {
+		assert.Equal(t, undo[0].Actor.Name, "kim")
+		assert.Equal(t, undo[0].Action, "roll back update_price MG-001 to $12.99")
+	}
+}
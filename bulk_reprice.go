@@ -0,0 +1,163 @@
+package main
+
+// ------------------- BULK REPRICING --------------------------
+// RepriceAll runs a price adjustment over the whole catalog with a pool
+// of worker goroutines, the Go version of Python's
+// ThreadPoolExecutor.map:
+//
+//	producer --jobs--> worker 1 --results--> collector
+//	                   worker 2
+//	                   ...
+//
+// The producer sends every catalog entry on the jobs channel and closes
+// it; each worker takes entries until the channel is closed; a
+// sync.WaitGroup tells when the last worker is done, so the results
+// channel can be closed too. One failing item doesn't stop the others:
+// errors are collected per SKU.
+//
+// adjust runs concurrently on different items, so it must be safe for
+// concurrent use. It is a good fit for slow work, such as asking a
+// supplier's API for the new price.
+//
+// The context stops a run early, on a timeout or when the caller gives
+// up: no new items are started, and adjust should watch ctx.Done() to
+// abandon the item it is working on. Items never reached are Skipped.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// PriceAdjuster returns the new price for item, or ctx.Err() if ctx is
+// done first
+type PriceAdjuster func(ctx context.Context, item PricedItem) (Money, error)
+
+// BulkRepricer is the configuration of RepriceAll
+type BulkRepricer struct {
+	// Workers is the number of goroutines (runtime.NumCPU() if zero)
+	Workers int
+	// Reason is recorded in each item's price history
+	Reason string
+}
+
+// BulkRepriceResult says what RepriceAll did
+type BulkRepriceResult struct {
+	Changed   int // prices that are now different
+	Unchanged int
+	Skipped   int // never started because the context was done
+	// Errors holds the failures by SKU; those items keep their price
+	Errors map[string]error
+	// Stopped is the context's error if the run was cut short
+	Stopped error
+}
+
+// Err joins the per-item errors, in SKU order, and Stopped, or returns
+// nil
+func (r BulkRepriceResult) Err() error {
+	var errs []error
+	for _, sku := range slices.Sorted(maps.Keys(r.Errors)) {
+		errs = append(errs, fmt.Errorf("%s: %w", sku, r.Errors[sku]))
+	}
+	if r.Stopped != nil {
+		errs = append(errs, fmt.Errorf("%d skipped: %w", r.Skipped, r.Stopped))
+	}
+	return errors.Join(errs...)
+}
+
+// repriceOutcome is what a worker reports for one item
+type repriceOutcome struct {
+	sku     string
+	changed bool
+	err     error
+}
+
+// RepriceAll sets the price of every item in catalog to what adjust
+// returns for it
+func (b BulkRepricer) RepriceAll(ctx context.Context, catalog *Catalog, adjust PriceAdjuster) BulkRepriceResult {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan CatalogEntry)
+	results := make(chan repriceOutcome)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// range over a channel ends when the channel is closed
+			for e := range jobs {
+				if ctx.Err() != nil {
+					// Drain the channel without starting more work
+					continue
+				}
+				results <- b.reprice(ctx, catalog, e, adjust)
+			}
+		}()
+	}
+	entries := catalog.Entries()
+	go func() {
+		defer close(jobs)
+		for _, e := range entries {
+			// select sends the job, unless ctx is done first
+			select {
+			case jobs <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	result := BulkRepriceResult{Errors: make(map[string]error)}
+	for out := range results {
+		switch {
+		case out.err != nil:
+			result.Errors[out.sku] = out.err
+		case out.changed:
+			result.Changed++
+		default:
+			result.Unchanged++
+		}
+	}
+	result.Skipped = len(entries) - result.Changed - result.Unchanged - len(result.Errors)
+	if result.Skipped > 0 {
+		result.Stopped = ctx.Err()
+	}
+	return result
+}
+
+// reprice handles one entry
+func (b BulkRepricer) reprice(ctx context.Context, catalog *Catalog, e CatalogEntry, adjust PriceAdjuster) repriceOutcome {
+	out := repriceOutcome{sku: e.SKU}
+	old, err := catalog.Price(e.SKU)
+	if err != nil {
+		out.err = err
+		return out
+	}
+	price, err := adjust(ctx, e.Item)
+	if err != nil {
+		out.err = err
+		return out
+	}
+	if price == old {
+		return out
+	}
+	out.err = catalog.SetPrice(e.SKU, price, b.Reason)
+	out.changed = out.err == nil
+	return out
+}
+
+// RepriceAll reprices catalog with the default worker pool
+func RepriceAll(ctx context.Context, catalog *Catalog, adjust PriceAdjuster) BulkRepriceResult {
+	return BulkRepricer{Reason: "bulk repricing"}.RepriceAll(ctx, catalog, adjust)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+// repriceCatalog has n books at $10, BK-000 to BK-(n-1)
+func repriceCatalog(n int) *Catalog {
+	c := NewCatalog()
+	for i := range n {
+		c.Add(fmt.Sprintf("BK-%03d", i), Must(NewBook(fmt.Sprintf("Book %d", i), "Anon", Dollars(10), "")))
+	}
+	return c
+}
+
+func TestRepriceAll(t *testing.T) {
+	leaktest.Check(t)
+	catalog := repriceCatalog(50)
+	errSupplier := errors.New("supplier down")
+	result := BulkRepricer{Workers: 4}.RepriceAll(context.Background(), catalog, func(_ context.Context, item PricedItem) (Money, error) {
+		switch itemTitle(item) {
+		case "Book 7":
+			return Money{}, errSupplier
+		case "Book 8":
+			return item.Price(), nil
+		}
+		return Dollars(9), nil
+	})
+	assert.Equal(t, result.Changed, 48)
+	assert.Equal(t, result.Unchanged, 1)
+	assert.Equal(t, result.Skipped, 0)
+	assert.ErrorIs(t, result.Errors["BK-007"], errSupplier)
+	assert.Equal(t, Must(catalog.Price("BK-007")), Dollars(10))
+	assert.Equal(t, Must(catalog.Price("BK-049")), Dollars(9))
+}
+
+func TestRepriceAllCancelled(t *testing.T) {
+	leaktest.Check(t)
+	catalog := repriceCatalog(50)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := 0
+	// One worker, which gives up after its third item: the producer
+	// and the collector must still finish
+	result := BulkRepricer{Workers: 1}.RepriceAll(ctx, catalog, func(ctx context.Context, item PricedItem) (Money, error) {
+		if started++; started == 3 {
+			cancel()
+			return Money{}, ctx.Err()
+		}
+		return Dollars(9), nil
+	})
+	assert.Equal(t, result.Changed, 2)
+	assert.ErrorIs(t, result.Stopped, context.Canceled)
+	assert.Equal(t, result.Changed+result.Unchanged+result.Skipped+len(result.Errors), 50)
+}
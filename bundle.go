@@ -0,0 +1,150 @@
+package main
+
+// ------------------- BUNDLES ---------------------------------
+// A Bundle (box set, "book + e-book" pack...) is a PricedItem made of
+// other PricedItems - the composite pattern. Anything that handles a
+// PricedItem handles a bundle too, including a bundle inside a bundle.
+//
+//   - Price is the sum of the contents' prices, less the bundle's own
+//     Discount for buying them together
+//   - CalculateDiscount asks every item for its own discounted price
+//     (a nested bundle does the same for its contents), then takes the
+//     bundle's Discount off the sum
+//
+// A bundle's price always follows its contents, so SetPrice refuses;
+// change the contents' prices or the bundle Discount instead.
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// BundleCategoryCode is the category of every bundle
+const BundleCategoryCode = "BUNDLE"
+
+type Bundle struct {
+	title string
+	items []PricedItem
+	// Discount comes off the contents' total, e.g. 15% for the box set
+	Discount    Percent
+	Description string
+	translations
+	annotations
+}
+
+// NewBundle returns an empty bundle; fill it with Add
+func NewBundle(title string, discount Percent) (*Bundle, error) {
+	b := &Bundle{title: title, Discount: discount}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Add puts item in the bundle. Every item must be priced in the same
+// currency, and a bundle cannot contain itself, not even indirectly.
+func (b *Bundle) Add(item PricedItem) error {
+	if item == nil {
+		return fmt.Errorf("cannot add a nil item to a bundle")
+	}
+	if inner, ok := item.(*Bundle); ok && (inner == b || inner.contains(b)) {
+		return fmt.Errorf("bundle %q cannot contain itself", b.title)
+	}
+	if len(b.items) > 0 {
+		if _, err := item.Price().Cmp(b.items[0].Price()); err != nil {
+			return fmt.Errorf("bundle %q: %w", b.title, err)
+		}
+	}
+	b.items = append(b.items, item)
+	return nil
+}
+
+// contains reports whether target is anywhere inside b
+func (b *Bundle) contains(target *Bundle) bool {
+	for _, item := range b.items {
+		if inner, ok := item.(*Bundle); ok && (inner == target || inner.contains(target)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Items returns a copy of the contents
+func (b *Bundle) Items() []PricedItem {
+	return slices.Clone(b.items)
+}
+
+func (b *Bundle) Category() string {
+	return BundleCategoryCode
+}
+
+// ContentsTotal is what the contents cost bought one by one. It fails
+// if they no longer share a currency or the sum overflows.
+func (b *Bundle) ContentsTotal() (Money, error) {
+	return sumPrices(b.items, func(item PricedItem) (Money, error) { return item.Price(), nil })
+}
+
+// Total is the bundle price: ContentsTotal less Discount
+func (b *Bundle) Total() (Money, error) {
+	contents, err := b.ContentsTotal()
+	if err != nil {
+		return Money{}, err
+	}
+	return contents.Off(b.Discount), nil
+}
+
+// Price is Total, or zero if Total fails; PricedItem's Price has no
+// error result, so call Total where that case matters
+func (b *Bundle) Price() Money {
+	total, _ := b.Total()
+	return total
+}
+
+// SetPrice always fails: the price is the sum of the contents
+func (b *Bundle) SetPrice(price Money) error {
+	return errors.New("a bundle's price is the sum of its contents")
+}
+
+// CalculateDiscount discounts every item by percentage, each in its own
+// way, then applies the bundle's Discount to the sum
+func (b *Bundle) CalculateDiscount(percentage Percent) (Money, error) {
+	sum, err := sumPrices(b.items, func(item PricedItem) (Money, error) {
+		return item.CalculateDiscount(percentage)
+	})
+	if err != nil {
+		return Money{}, fmt.Errorf("bundle %q: %w", b.title, err)
+	}
+	return sum.Off(b.Discount), nil
+}
+
+// Savings is how much cheaper the bundle is than its contents bought
+// one by one
+func (b *Bundle) Savings() (Money, error) {
+	contents, err := b.ContentsTotal()
+	if err != nil {
+		return Money{}, err
+	}
+	return contents.Sub(contents.Off(b.Discount))
+}
+
+func (b *Bundle) Summary() string {
+	titles := Map(b.items, itemTitle)
+	return fmt.Sprintf("%s (%d items: %v) - %v, %v off buying them separately",
+		b.title, len(b.items), titles, b.Price(), b.Discount)
+}
+
+// sumPrices adds up price(item) over items
+func sumPrices(items []PricedItem, price func(PricedItem) (Money, error)) (Money, error) {
+	var sum lineSum
+	for _, item := range items {
+		p, err := price(item)
+		if err != nil {
+			return Money{}, err
+		}
+		if err := sum.add(p, 1); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum.total, nil
+}
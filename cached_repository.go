@@ -0,0 +1,186 @@
+package main
+
+// ------------------- READ-THROUGH CACHE ----------------------
+// CachedRepository wraps another Repository and keeps recently read
+// items in an LRUCache. It is a decorator: it implements the same
+// interface as what it wraps, so callers can't tell the difference,
+// much like a Python function wrapped in @lru_cache.
+//
+//	FindByID  cache hit?  yes -> return it
+//	                      no  -> ask the repository, remember the answer
+//	Create, Update, Delete -> pass through, then forget the SKU
+//
+// Items can also change behind the repository's back, when the sync
+// layer repairs a drifted catalog. Those changes arrive as ItemsSynced
+// events, which evict the SKUs concerned.
+//
+// A miss that overlaps a write could read the old item, and then cache
+// it after the write has evicted the SKU. So every eviction bumps a
+// per-SKU generation, and a miss only caches what it read if the
+// generation is still the one it started with.
+//
+// The cache size is set per deployment with BOOKSTORE_CACHE_SIZE
+// (0 turns caching off); see CacheConfigFromEnv.
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// CacheSizeEnv sets the number of items a CachedRepository keeps
+const CacheSizeEnv = "BOOKSTORE_CACHE_SIZE"
+
+// DefaultCacheSize is used when CacheSizeEnv is not set
+const DefaultCacheSize = 1000
+
+// CacheConfig holds the settings of a CachedRepository
+type CacheConfig struct {
+	// Size is how many items to keep; 0 caches nothing
+	Size int
+}
+
+// CacheConfigFromEnv reads CacheSizeEnv, falling back to the defaults
+func CacheConfigFromEnv() (CacheConfig, error) {
+	cfg := CacheConfig{Size: DefaultCacheSize}
+	if v := os.Getenv(CacheSizeEnv); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 0 {
+			return cfg, fmt.Errorf("%s must be a whole number of items, got %q", CacheSizeEnv, v)
+		}
+		cfg.Size = size
+	}
+	return cfg, nil
+}
+
+// CachedRepository serves FindByID from a cache in front of another
+// repository. It is safe for concurrent use if the wrapped repository
+// is; call Close to stop listening for ItemsSynced.
+type CachedRepository struct {
+	next  Repository
+	cache *LRUCache[string, PricedItem]
+	// mu orders fills against evictions; fills holds the SKUs being
+	// read from next right now
+	mu    sync.Mutex
+	fills map[string]*cacheFill
+	// atomic counters need no mutex, like itertools.count but thread-safe
+	hits, misses atomic.Int64
+	unsubscribe  func()
+}
+
+// NewCachedRepository wraps next with a cache configured by cfg
+func NewCachedRepository(next Repository, cfg CacheConfig) *CachedRepository {
+	r := &CachedRepository{
+		next:  next,
+		cache: NewLRUCache[string, PricedItem](cfg.Size),
+		fills: make(map[string]*cacheFill),
+	}
+	r.unsubscribe = Subscribe(Events, func(e ItemsSynced) {
+		for _, sku := range e.SKUs {
+			r.evict(sku)
+		}
+	})
+	return r
+}
+
+// cacheFill tracks the misses reading one SKU from the repository
+type cacheFill struct {
+	generation uint64 // bumped by every eviction of the SKU
+	readers    int
+}
+
+func (r *CachedRepository) Create(sku string, item PricedItem) error {
+	// Evict even on failure: the repository may have changed anyway
+	defer r.evict(sku)
+	return r.next.Create(sku, item)
+}
+
+func (r *CachedRepository) FindByID(sku string) (PricedItem, error) {
+	if item, ok := r.cache.Get(sku); ok {
+		r.hits.Add(1)
+		return item, nil
+	}
+	r.misses.Add(1)
+
+	r.mu.Lock()
+	fill, ok := r.fills[sku]
+	if !ok {
+		fill = &cacheFill{}
+		r.fills[sku] = fill
+	}
+	fill.readers++
+	generation := fill.generation
+	r.mu.Unlock()
+
+	item, err := r.next.FindByID(sku)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fill.readers--; fill.readers == 0 {
+		delete(r.fills, sku)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// An eviction since the read began means item may be stale
+	if fill.generation == generation {
+		r.cache.Put(sku, item)
+	}
+	return item, nil
+}
+
+// evict forgets sku and makes misses already reading it skip the cache
+func (r *CachedRepository) evict(sku string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fill, ok := r.fills[sku]; ok {
+		fill.generation++
+	}
+	r.cache.Remove(sku)
+}
+
+// FindAll always asks the repository: the cache never knows whether it
+// holds every item
+func (r *CachedRepository) FindAll() ([]CatalogEntry, error) {
+	return r.next.FindAll()
+}
+
+func (r *CachedRepository) Update(sku string, item PricedItem) error {
+	defer r.evict(sku)
+	return r.next.Update(sku, item)
+}
+
+func (r *CachedRepository) Delete(sku string) error {
+	defer r.evict(sku)
+	return r.next.Delete(sku)
+}
+
+// Close stops the cache from listening for ItemsSynced events
+func (r *CachedRepository) Close() {
+	r.unsubscribe()
+}
+
+// CacheStats are a CachedRepository's metrics
+type CacheStats struct {
+	Hits, Misses int64
+	Size         int // items cached right now
+}
+
+// HitRate is the share of reads served from the cache, from 0 to 1
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("%d hits, %d misses (%.0f%% hit rate), %d cached", s.Hits, s.Misses, 100*s.HitRate(), s.Size)
+}
+
+// Stats returns the hit and miss counts so far
+func (r *CachedRepository) Stats() CacheStats {
+	return CacheStats{Hits: r.hits.Load(), Misses: r.misses.Load(), Size: r.cache.Len()}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+// pausingRepository stops each FindByID after it has read the item,
+// until the test lets it go on
+type pausingRepository struct {
+	Repository
+	read, resume chan struct{}
+}
+
+func (r pausingRepository) FindByID(sku string) (PricedItem, error) {
+	item, err := r.Repository.FindByID(sku)
+	r.read <- struct{}{}
+	<-r.resume
+	return item, err
+}
+
+func TestCachedRepositoryMissOverlappingUpdate(t *testing.T) {
+	repo := NewMemoryRepository()
+	assert.NoError(t, repo.Create("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))))
+	slow := pausingRepository{Repository: repo, read: make(chan struct{}), resume: make(chan struct{})}
+	cached := NewCachedRepository(slow, CacheConfig{Size: 10})
+	defer cached.Close()
+
+	// A miss reads $9.99, then an update to $8.99 lands before it can
+	// cache what it read
+	stale := make(chan PricedItem)
+	go func() {
+		item, err := cached.FindByID("BK-001")
+		assert.NoError(t, err)
+		stale <- item
+	}()
+	<-slow.read
+	assert.NoError(t, cached.Update("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(8.99), ""))))
+	close(slow.resume)
+	assert.Equal(t, (<-stale).Price(), Dollars(9.99))
+
+	// The old item must not have been cached
+	go func() { <-slow.read }()
+	item, err := cached.FindByID("BK-001")
+	if assert.NoError(t, err) {
+		assert.Equal(t, item.Price(), Dollars(8.99))
+	}
+	assert.Equal(t, cached.Stats().Hits, int64(0))
+
+	// Without a write in between, a miss fills the cache as usual
+	item, err = cached.FindByID("BK-001")
+	if assert.NoError(t, err) {
+		assert.Equal(t, item.Price(), Dollars(8.99))
+	}
+	assert.Equal(t, cached.Stats().Hits, int64(1))
+	assert.Equal(t, len(cached.fills), 0)
+}
+
+func TestCacheConfigFromEnv(t *testing.T) {
+	t.Setenv(CacheSizeEnv, "")
+	assert.Equal(t, Must(CacheConfigFromEnv()), CacheConfig{Size: DefaultCacheSize})
+	t.Setenv(CacheSizeEnv, "0")
+	assert.Equal(t, Must(CacheConfigFromEnv()), CacheConfig{Size: 0})
+	for _, bad := range []string{"-1", "lots"} {
+		t.Setenv(CacheSizeEnv, bad)
+		if _, err := CacheConfigFromEnv(); err == nil {
+			t.Errorf("%s=%s accepted", CacheSizeEnv, bad)
+		}
+	}
+}
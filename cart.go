@@ -0,0 +1,230 @@
+package main
+
+// ------------------- SHOPPING CART ---------------------------
+// A cart collects items and quantities; Checkout freezes it into an
+// Order. Prices are looked up live while shopping, but an Order keeps
+// the prices it was placed at - later price changes must not alter it.
+//
+// Discounts come from a PricingEngine (see discounts.go), which sees the
+// quantity of each line, so bulk discounts work naturally. Tax, if the
+// cart has a TaxCalculator, is added on the discounted prices (see tax.go).
+
+import (
+	"fmt"
+	"time"
+)
+
+// CartLine is one item in the cart
+type CartLine struct {
+	Item     PricedItem
+	Quantity int
+}
+
+// Cart keeps lines in the order items were first added
+type Cart struct {
+	// Tax is added at checkout for the buyer's Region; nil means no tax
+	Tax    TaxCalculator
+	Region string
+	// Member gets member prices and member-only promotions
+	Member bool
+	// Coupons are the coupon codes entered for this cart
+	Coupons []string
+	// Numbers numbers the order at checkout, counting for Store; nil
+	// leaves the order without a number
+	Numbers OrderSequence
+	Store   string
+	// Save, if set, stores the placed order. A numbered order is saved
+	// while its number is taken, and a failed save hands it back.
+	Save func(*Order) error
+
+	lines []CartLine
+}
+
+// AddItem adds qty units; adding an item again increases its quantity
+func (c *Cart) AddItem(item PricedItem, qty int) error {
+	if qty <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if i := c.index(item); i >= 0 {
+		c.lines[i].Quantity += qty
+		return nil
+	}
+	c.lines = append(c.lines, CartLine{Item: item, Quantity: qty})
+	return nil
+}
+
+// RemoveItem takes the item out of the cart entirely
+func (c *Cart) RemoveItem(item PricedItem) error {
+	i := c.index(item)
+	if i < 0 {
+		return fmt.Errorf("item is not in the cart")
+	}
+	c.lines = append(c.lines[:i], c.lines[i+1:]...)
+	return nil
+}
+
+func (c *Cart) index(item PricedItem) int {
+	for i, l := range c.lines {
+		if l.Item == item {
+			return i
+		}
+	}
+	return -1
+}
+
+// Lines returns a copy, so callers can't change the cart behind its back
+func (c *Cart) Lines() []CartLine {
+	return append([]CartLine(nil), c.lines...)
+}
+
+// Subtotal is the total at regular prices
+func (c *Cart) Subtotal() (Money, error) {
+	var sum lineSum
+	for _, l := range c.lines {
+		if err := sum.add(l.Item.Price(), l.Quantity); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum.total, nil
+}
+
+// TotalWithDiscounts prices every line with engine at time at
+func (c *Cart) TotalWithDiscounts(engine *PricingEngine, at time.Time) (Money, error) {
+	var sum lineSum
+	for _, l := range c.priceLines(engine, at) {
+		if err := sum.add(l.Paid, l.Quantity); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum.total, nil
+}
+
+// lineSum adds up lines (unit × qty) of one currency. The first line
+// sets the currency, even if it is free: a zero total can't tell which
+// currency it is in, so it can't be what decides.
+type lineSum struct {
+	total   Money
+	started bool
+}
+
+// add adds unit × qty; on error the sum is left as it was
+func (s *lineSum) add(unit Money, qty int) error {
+	line, err := unit.Times(qty)
+	if err != nil {
+		return err
+	}
+	if !s.started {
+		s.total, s.started = line, true
+		return nil
+	}
+	total, err := s.total.Add(line)
+	if err != nil {
+		return err
+	}
+	s.total = total
+	return nil
+}
+
+// Checkout turns the cart into an Order and empties the cart
+func (c *Cart) Checkout(engine *PricingEngine, at time.Time) (*Order, error) {
+	if len(c.lines) == 0 {
+		return nil, fmt.Errorf("cannot check out an empty cart")
+	}
+	order, err := orderFromLines(c.priceLines(engine, at), c.Tax, c.Region, at)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.place(order); err != nil {
+		return nil, err
+	}
+	c.lines = nil
+	Events.Publish(OrderPlaced{Order: order})
+	return order, nil
+}
+
+// place numbers order, if the cart has Numbers, and saves it, if it
+// has Save. The number counts only if the order was saved.
+func (c *Cart) place(order *Order) error {
+	save := func() error {
+		if c.Save == nil {
+			return nil
+		}
+		return c.Save(order)
+	}
+	if c.Numbers == nil {
+		return save()
+	}
+	_, err := c.Numbers.NumberOrder(c.Store, func(n int64) error {
+		order.Number = FormatOrderNumber(c.Store, n)
+		return save()
+	})
+	if err != nil {
+		order.Number = ""
+		return fmt.Errorf("placing the order: %w", err)
+	}
+	return nil
+}
+
+// orderFromLines totals already priced lines into a new order, adding
+// tax if tax is not nil
+func orderFromLines(lines []OrderLine, tax TaxCalculator, region string, at time.Time) (*Order, error) {
+	order := newOrder(at)
+	order.Lines = lines
+	var subtotal, total, taxTotal lineSum
+	for _, l := range order.Lines {
+		if err := subtotal.add(l.UnitPrice, l.Quantity); err != nil {
+			return nil, err
+		}
+		if err := total.add(l.Paid, l.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	if tax != nil {
+		var err error
+		if order.Tax, err = taxBreakdown(tax, region, order.Lines); err != nil {
+			return nil, err
+		}
+		for _, t := range order.Tax {
+			if err := taxTotal.add(t.Amount, 1); err != nil {
+				return nil, err
+			}
+			if err := total.add(t.Amount, 1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	order.Subtotal, order.Total, order.TaxTotal = subtotal.total, total.total, taxTotal.total
+	return order, nil
+}
+
+// Taxes previews the tax breakdown Checkout would add
+func (c *Cart) Taxes(engine *PricingEngine, at time.Time) ([]TaxLine, error) {
+	if c.Tax == nil {
+		return nil, nil
+	}
+	return taxBreakdown(c.Tax, c.Region, c.priceLines(engine, at))
+}
+
+// priceLines prices every line with engine at time at
+func (c *Cart) priceLines(engine *PricingEngine, at time.Time) []OrderLine {
+	lines := make([]OrderLine, 0, len(c.lines))
+	basket := Map(c.lines, func(l CartLine) PricedItem { return l.Item })
+	for _, l := range c.lines {
+		result := engine.Price(DiscountContext{
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			At:       at,
+			Basket:   basket,
+			Member:   c.Member,
+			Coupons:  c.Coupons,
+		})
+		lines = append(lines, OrderLine{
+			Item:      l.Item,
+			Quantity:  l.Quantity,
+			UnitPrice: result.Base,
+			Paid:      result.Final,
+			Discounts: result.Applied,
+		})
+	}
+	return lines
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestCartRejectsMixedCurrenciesAfterFreeLine(t *testing.T) {
+	free := Must(NewBook("Faust", "Goethe", Must(NewMoney(0, "EUR")), ""))
+	dune := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	var cart Cart
+	assert.NoError(t, cart.AddItem(free, 1))
+	assert.NoError(t, cart.AddItem(dune, 1))
+
+	_, err := cart.Subtotal()
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = cart.Checkout(NewPricingEngine(StackAll), time.Now())
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestCartFreeFirstLineKeepsCurrency(t *testing.T) {
+	free := Must(NewBook("Faust", "Goethe", Must(NewMoney(0, "EUR")), ""))
+	kafka := Must(NewBook("Der Process", "Franz Kafka", Must(NewMoney(1250, "EUR")), ""))
+	var cart Cart
+	assert.NoError(t, cart.AddItem(free, 1))
+	assert.NoError(t, cart.AddItem(kafka, 2))
+	total, err := cart.Subtotal()
+	if assert.NoError(t, err) {
+		assert.Equal(t, total, Must(NewMoney(2500, "EUR")))
+	}
+
+	// An empty cart has no currency to go by; a free euro line does
+	var empty Cart
+	total, err = empty.Subtotal()
+	if assert.NoError(t, err) {
+		assert.Equal(t, total, Money{})
+	}
+	var sum lineSum
+	assert.NoError(t, sum.add(Must(NewMoney(0, "EUR")), 3))
+	assert.Equal(t, sum.total.Currency(), "EUR")
+	// A failed add leaves the sum as it was
+	assert.ErrorIs(t, sum.add(Dollars(1), 1), ErrCurrencyMismatch)
+	assert.Equal(t, sum.total, Must(NewMoney(0, "EUR")))
+}
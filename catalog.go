@@ -0,0 +1,151 @@
+package main
+
+// ------------------- CATALOG ---------------------------------
+// The catalog holds every item the store sells, keyed by SKU
+// (stock keeping unit, the store's own product code).
+// It stores PricedItems, so books, magazines and any future item type
+// live side by side - like a Python dict[str, PricedItem].
+//
+// A catalog is safe for concurrent use. A sync.RWMutex lets any number
+// of readers in at once, while a writer waits for them and then has the
+// catalog to itself. The items inside are not locked themselves, so
+// code running concurrently changes prices with Catalog.SetPrice and
+// reads them with Catalog.Price rather than calling the item directly.
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Catalog maps SKUs to items
+type Catalog struct {
+	mu    sync.RWMutex
+	items map[string]PricedItem
+}
+
+// NewCatalog returns an empty catalog ready for use
+func NewCatalog() *Catalog {
+	return &Catalog{items: make(map[string]PricedItem)}
+}
+
+// Add registers item under sku. SKUs are unique: adding a second item
+// with the same SKU is an error rather than a silent overwrite.
+func (c *Catalog) Add(sku string, item PricedItem) error {
+	if sku == "" {
+		return fmt.Errorf("SKU cannot be empty")
+	}
+	if item == nil {
+		return fmt.Errorf("item cannot be nil")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The "comma ok" idiom: ok is false when the key is missing
+	if _, exists := c.items[sku]; exists {
+		return fmt.Errorf("SKU %q is already in the catalog", sku)
+	}
+	c.items[sku] = item
+	return nil
+}
+
+// put adds or replaces the item under sku
+func (c *Catalog) put(sku string, item PricedItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[sku] = item
+}
+
+// Remove deletes the item registered under sku
+func (c *Catalog) Remove(sku string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.items[sku]; !exists {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	delete(c.items, sku)
+	return nil
+}
+
+// Get returns the item registered under sku
+func (c *Catalog) Get(sku string) (PricedItem, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[sku]
+	if !ok {
+		return nil, &ItemNotFoundError{SKU: sku}
+	}
+	return item, nil
+}
+
+// SetPrice changes the price of the item under sku, recording reason
+// in its price history. Price change listeners run while the catalog is
+// locked, so they must not use the catalog themselves.
+func (c *Catalog) SetPrice(sku string, price Money, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[sku]
+	if !ok {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	return setPriceBecause(item, price, reason)
+}
+
+// Read runs fn on the item under sku while holding the read lock, so
+// fn sees the item whole even while SetPrice is called concurrently.
+// fn must not keep the item or use the catalog itself.
+func (c *Catalog) Read(sku string, fn func(PricedItem)) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[sku]
+	if !ok {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	fn(item)
+	return nil
+}
+
+// Price returns the current price of the item under sku
+func (c *Catalog) Price(sku string) (Money, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[sku]
+	if !ok {
+		return Money{}, &ItemNotFoundError{SKU: sku}
+	}
+	return item.Price(), nil
+}
+
+// SKUs returns every SKU in sorted order
+func (c *Catalog) SKUs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sortedSKUs()
+}
+
+// sortedSKUs is SKUs for callers that hold c.mu
+func (c *Catalog) sortedSKUs() []string {
+	// Go randomizes map iteration order on purpose, so sort for
+	// output that is the same on every run
+	return slices.Sorted(maps.Keys(c.items))
+}
+
+// List returns every item, ordered by SKU
+func (c *Catalog) List() []PricedItem {
+	return Map(c.Entries(), func(e CatalogEntry) PricedItem { return e.Item })
+}
+
+// Entries returns every item with its SKU, ordered by SKU. It is a
+// snapshot: items added or removed later don't show up in it.
+func (c *Catalog) Entries() []CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Map(c.sortedSKUs(), func(sku string) CatalogEntry { return CatalogEntry{SKU: sku, Item: c.items[sku]} })
+}
+
+// Len returns the number of items, like Python's len(catalog)
+func (c *Catalog) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
@@ -0,0 +1,179 @@
+package main
+
+// ------------------- CATALOG CSV -----------------------------
+// Bookstores, distributors and spreadsheets exchange catalogs as CSV.
+// One file holds books and magazines side by side; the "type" column
+// says which a row is, and columns that don't apply stay empty:
+//
+//	type,sku,title,author,price,currency,pages,issue,seller,description
+//	book,BK-001,Harry Potter,J.K. Rowling,12.99,USD,407,,Obscurus Books,
+//	magazine,MG-001,Vogue,,12.99,USD,,123,,
+//
+// encoding/csv does the quoting, so a title such as `Dune, Part One`
+// or one with "quotes" round-trips unchanged. On import, columns are
+// found by header name in any order, and a bad row is reported with
+// its line number and skipped; the rest of the file still goes in.
+// The price import (price_import.go) reports problems the same way.
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// catalogCSVColumns is the header ExportCSV writes
+var catalogCSVColumns = []string{"type", "sku", "title", "author", "price", "currency", "pages", "issue", "seller", "description"}
+
+// CSVExportOptions adjust what ExportCSV writes
+type CSVExportOptions struct {
+	// Notes adds a last "notes" column with the items' staff notes
+	// (see notes.go), one per line. Such a file is for staff only.
+	// ImportCSV ignores the column.
+	Notes bool
+}
+
+// ExportCSV writes every book and magazine as CSV, in SKU order, and
+// returns how many rows it wrote. Other item types are left out.
+func (c *Catalog) ExportCSV(w io.Writer, opts CSVExportOptions) (int, error) {
+	cw := csv.NewWriter(w)
+	header := catalogCSVColumns
+	if opts.Notes {
+		header = append(slices.Clip(header), "notes")
+	}
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range c.Entries() {
+		var row []string
+		switch item := e.Item.(type) {
+		case *Book:
+			pages := ""
+			if item.pageCount > 0 {
+				pages = strconv.Itoa(item.pageCount)
+			}
+			row = []string{"book", e.SKU, item.title, item.author, item.price.Decimal(), item.price.Currency(),
+				pages, "", item.Seller, item.Description}
+		case *Magazine:
+			row = []string{"magazine", e.SKU, item.name, "", item.price.Decimal(), item.price.Currency(),
+				"", strconv.Itoa(item.issueNumber), "", item.Description}
+		default:
+			continue
+		}
+		if opts.Notes {
+			notes := Map(itemNotes(e.Item), func(n Note) string { return fmt.Sprintf("#%d %s: %s", n.ID, n.Author, n.Text) })
+			row = append(row, strings.Join(notes, "\n"))
+		}
+		if err := cw.Write(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	// csv.Writer buffers; Flush writes the rest and Error reports any
+	// failure along the way
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// CSVImportResult says what ImportCSV did
+type CSVImportResult struct {
+	Added  int
+	Issues []ImportIssue // one per skipped line
+}
+
+// ImportCSV adds the books and magazines in r to the catalog. Only an
+// unreadable header stops it; problems with single rows end up in the
+// result's Issues.
+func (c *Catalog) ImportCSV(r io.Reader) (CSVImportResult, error) {
+	var result CSVImportResult
+	reader := csv.NewReader(r)
+	// Rows are checked by hand, so a short row is one issue, not a stop
+	reader.FieldsPerRecord = -1
+	headers, err := reader.Read()
+	if err != nil {
+		return result, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, h := range headers {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"type", "sku", "title", "price"} {
+		if _, ok := columns[required]; !ok {
+			return result, fmt.Errorf("no %q column (columns: %s)", required, strings.Join(headers, ", "))
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = c.importCSVRow(record, columns)
+		}
+		if err != nil {
+			result.Issues = append(result.Issues, ImportIssue{line, err.Error()})
+			continue
+		}
+		result.Added++
+	}
+	return result, nil
+}
+
+// importCSVRow builds the item described by one row and adds it
+func (c *Catalog) importCSVRow(record []string, columns map[string]int) error {
+	// field returns a column's value, "" if the row or file lacks it
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	currency := field("currency")
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	price, err := ParseMoney(field("price"), currency)
+	if err != nil {
+		return fmt.Errorf("price %q is not an amount like 9.99", field("price"))
+	}
+
+	var item PricedItem
+	switch typ := strings.ToLower(field("type")); typ {
+	case "book":
+		book, err := NewBook(field("title"), field("author"), price, field("seller"))
+		if err != nil {
+			return err
+		}
+		// Pages are optional, but must be valid when given
+		book.pageCount = 0
+		if pages := field("pages"); pages != "" {
+			n, err := strconv.Atoi(pages)
+			if err != nil {
+				return fmt.Errorf("pages %q is not a number", pages)
+			}
+			if err := book.SetPageCount(n); err != nil {
+				return err
+			}
+		}
+		book.Description = field("description")
+		item = book
+	case "magazine":
+		issue, err := strconv.Atoi(field("issue"))
+		if err != nil {
+			return fmt.Errorf("issue %q is not a number", field("issue"))
+		}
+		magazine, err := NewMagazine(field("title"), price, issue)
+		if err != nil {
+			return err
+		}
+		magazine.Description = field("description")
+		item = magazine
+	default:
+		return fmt.Errorf("type %q is not book or magazine", typ)
+	}
+	return c.Add(field("sku"), item)
+}
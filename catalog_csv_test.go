@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestCSVExportCommand(t *testing.T) {
+	s := &cliSession{catalog: sampleCatalog(), user: Actor{Name: "kim", Role: RoleManager}, authz: NewAuthorizer()}
+	var want bytes.Buffer
+	_, err := s.catalog.ExportCSV(&want, CSVExportOptions{})
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, s.runCommand([]string{"csv", "export"}, &out))
+	assert.Equal(t, out.String(), want.String())
+
+	path := filepath.Join(t.TempDir(), "catalog.csv")
+	out.Reset()
+	assert.NoError(t, s.runCommand([]string{"csv", "export", "-out", path}, &out))
+	assert.Equal(t, out.String(), "Exported 2 items to "+path+"\n")
+	assert.Equal(t, string(Must(os.ReadFile(path))), want.String())
+
+	// A write that fails is reported, not lost
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full to fill")
+	}
+	out.Reset()
+	if err := s.runCommand([]string{"csv", "export", "-out", "/dev/full"}, &out); err == nil {
+		t.Errorf("export to a full device succeeded: %s", out.String())
+	}
+}
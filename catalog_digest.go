@@ -0,0 +1,250 @@
+package main
+
+// ------------------- CATALOG DRIFT DETECTION -----------------
+// Two copies of a catalog (say, a local replica and the primary) should
+// hold the same items. Comparing them item by item is slow over a
+// network, so each side computes a digest instead:
+//
+//   - every item is hashed (SHA-256 of its JSON form)
+//   - the hashes, ordered by SKU, are combined pairwise into a single
+//     "Merkle root", like a tournament bracket
+//
+// Equal roots mean equal catalogs; only when they differ do we need the
+// per-item hashes to find out which items drifted.
+//
+//	bookstore -store local.json verify-sync -primary primary.json [-repair]
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// CatalogDigest summarizes a catalog's contents
+type CatalogDigest struct {
+	Root  string
+	Items map[string]string // SKU -> item hash
+}
+
+// ItemHash fingerprints one item; any change to a serialized field
+// changes the hash
+func ItemHash(item PricedItem) (string, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Digest hashes every item and the catalog as a whole
+func (c *Catalog) Digest() (CatalogDigest, error) {
+	d := CatalogDigest{Items: make(map[string]string, c.Len())}
+	var leaves [][]byte
+	for _, e := range c.Entries() {
+		h, err := ItemHash(e.Item)
+		if err != nil {
+			return CatalogDigest{}, fmt.Errorf("hashing %q: %w", e.SKU, err)
+		}
+		d.Items[e.SKU] = h
+		// The SKU is part of the leaf, so moving an item to another SKU
+		// also changes the root
+		leaf := sha256.Sum256([]byte(e.SKU + ":" + h))
+		leaves = append(leaves, leaf[:])
+	}
+	d.Root = hex.EncodeToString(merkleRoot(leaves))
+	return d, nil
+}
+
+// merkleRoot combines pairs of hashes level by level until one is left.
+// An odd hash out is carried up to the next level unchanged.
+func merkleRoot(level [][]byte) []byte {
+	if len(level) == 0 {
+		empty := sha256.Sum256(nil)
+		return empty[:]
+	}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			pair := sha256.Sum256(append(slices.Clone(level[i]), level[i+1]...))
+			next = append(next, pair[:])
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// DriftKind says how a local item differs from the primary
+type DriftKind int
+
+const (
+	DriftChanged DriftKind = iota // both have it, contents differ
+	DriftMissing                  // only the primary has it
+	DriftExtra                    // only the local catalog has it
+)
+
+func (k DriftKind) String() string {
+	switch k {
+	case DriftChanged:
+		return "changed"
+	case DriftMissing:
+		return "missing"
+	case DriftExtra:
+		return "extra"
+	default:
+		return fmt.Sprintf("DriftKind(%d)", int(k))
+	}
+}
+
+// Drift is one SKU that is out of sync
+type Drift struct {
+	SKU  string
+	Kind DriftKind
+}
+
+// CompareDigests lists the drifted SKUs, sorted; nil means in sync
+func CompareDigests(local, primary CatalogDigest) []Drift {
+	if local.Root == primary.Root {
+		return nil
+	}
+	skus := slices.Sorted(maps.Keys(primary.Items))
+	for sku := range local.Items {
+		if _, ok := primary.Items[sku]; !ok {
+			skus = append(skus, sku)
+		}
+	}
+	slices.Sort(skus)
+
+	var drift []Drift
+	for _, sku := range skus {
+		lh, inLocal := local.Items[sku]
+		ph, inPrimary := primary.Items[sku]
+		switch {
+		case !inLocal:
+			drift = append(drift, Drift{SKU: sku, Kind: DriftMissing})
+		case !inPrimary:
+			drift = append(drift, Drift{SKU: sku, Kind: DriftExtra})
+		case lh != ph:
+			drift = append(drift, Drift{SKU: sku, Kind: DriftChanged})
+		}
+	}
+	return drift
+}
+
+// Repair makes the drifted SKUs of c match primary: changed and missing
+// items are copied over, extra ones removed. The SKUs it repaired are
+// published as ItemsSynced, even when it stops at an error.
+func (c *Catalog) Repair(primary *Catalog, drift []Drift) error {
+	var synced []string
+	defer func() {
+		if len(synced) > 0 {
+			Events.Publish(ItemsSynced{SKUs: synced})
+		}
+	}()
+	for _, d := range drift {
+		if d.Kind == DriftExtra {
+			c.mu.Lock()
+			delete(c.items, d.SKU)
+			c.mu.Unlock()
+			synced = append(synced, d.SKU)
+			continue
+		}
+		// Copy the item before locking c, in case primary is c
+		item, err := primary.copyItem(d.SKU)
+		if err != nil {
+			return err
+		}
+		c.put(d.SKU, item)
+		synced = append(synced, d.SKU)
+	}
+	return nil
+}
+
+// copyItem returns a copy of the item under sku, notes included. Each
+// catalog locks only its own items, so two of them must never share one.
+// The copy goes through the item's JSON form, like a save and a load.
+func (c *Catalog) copyItem(sku string) (PricedItem, error) {
+	var typ string
+	var data []byte
+	var notes []Note
+	var err error
+	readErr := c.Read(sku, func(item PricedItem) {
+		if typ, err = itemType(item); err != nil {
+			return
+		}
+		data, err = json.Marshal(item)
+		notes = itemNotes(item)
+	})
+	if readErr != nil {
+		return nil, readErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("copying %q: %w", sku, err)
+	}
+	item, err := decodeItem(typ, data)
+	if err == nil {
+		err = setItemNotes(item, notes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("copying %q: %w", sku, err)
+	}
+	return item, nil
+}
+
+// cmdVerifySync compares the catalog with a primary copy saved in a
+// file and lists the drifted items; -repair also brings them in line.
+// There is no network sync protocol yet, so the primary is whatever
+// file was last copied from the main store.
+func cmdVerifySync(s *cliSession, args []string, out io.Writer) error {
+	fs := newFlagSet("verify-sync", out)
+	path := fs.String("primary", "", "catalog file of the primary (see filestore.go)")
+	repair := fs.Bool("repair", false, "copy drifted items from the primary and remove extra ones")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("verify-sync: -primary is required")
+	}
+	primary, err := FileStore{Path: *path}.Load()
+	if err != nil {
+		return err
+	}
+	local, err := s.catalog.Digest()
+	if err != nil {
+		return err
+	}
+	remote, err := primary.Digest()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Local root:   %s\nPrimary root: %s\n", local.Root, remote.Root)
+	drift := CompareDigests(local, remote)
+	if len(drift) == 0 {
+		fmt.Fprintln(out, "In sync")
+		return nil
+	}
+	for _, d := range drift {
+		fmt.Fprintf(out, "  %s: %v\n", d.SKU, d.Kind)
+	}
+	if !*repair {
+		fmt.Fprintf(out, "%d items drifted; run with -repair to fix them\n", len(drift))
+		return nil
+	}
+	// Looking is for viewers, changing the catalog is not
+	if err := s.authz.Authorize(s.user, PermManageCatalog, "repair drifted items"); err != nil {
+		return err
+	}
+	if err := s.catalog.Repair(primary, drift); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Repaired %d items\n", len(drift))
+	return nil
+}
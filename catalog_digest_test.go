@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+// driftedReplica has an outdated BK-001, no MG-001 and an extra BK-999
+func driftedReplica() *Catalog {
+	replica := NewCatalog()
+	replica.Add("BK-001", Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(10.99), "Obscurus Books")))
+	replica.Add("BK-999", Must(NewBook("Discontinued", "Nobody", Dollars(1), "")))
+	return replica
+}
+
+func TestDigest(t *testing.T) {
+	c := sampleCatalog()
+	before := Must(c.Digest())
+	assert.Equal(t, Must(c.Digest()), before)
+	assert.Equal(t, len(before.Items), 2)
+
+	assert.NoError(t, c.SetPrice("BK-001", Dollars(13.99), ""))
+	after := Must(c.Digest())
+	if after.Root == before.Root || after.Items["BK-001"] == before.Items["BK-001"] {
+		t.Error("a price change left the digest as it was")
+	}
+	assert.Equal(t, after.Items["MG-001"], before.Items["MG-001"])
+
+	// The same item under another SKU is a different catalog
+	moved := NewCatalog()
+	moved.Add("MG-002", Must(c.Get("MG-001")))
+	only := NewCatalog()
+	only.Add("MG-001", Must(c.Get("MG-001")))
+	if Must(moved.Digest()).Root == Must(only.Digest()).Root {
+		t.Error("moving an item left the root as it was")
+	}
+}
+
+func TestCompareDigests(t *testing.T) {
+	primary := Must(sampleCatalog().Digest())
+	assert.Equal(t, len(CompareDigests(primary, primary)), 0)
+	drift := CompareDigests(Must(driftedReplica().Digest()), primary)
+	assert.Equal(t, drift, []Drift{
+		{SKU: "BK-001", Kind: DriftChanged},
+		{SKU: "BK-999", Kind: DriftExtra},
+		{SKU: "MG-001", Kind: DriftMissing},
+	})
+}
+
+func TestRepair(t *testing.T) {
+	primary, replica := sampleCatalog(), driftedReplica()
+	drift := CompareDigests(Must(replica.Digest()), Must(primary.Digest()))
+	assert.NoError(t, replica.Repair(primary, drift))
+	assert.Equal(t, Must(replica.Digest()).Root, Must(primary.Digest()).Root)
+	assert.Equal(t, replica.SKUs(), []string{"BK-001", "MG-001"})
+
+	// The replica got copies: changing the primary doesn't reach it
+	assert.NoError(t, primary.SetPrice("BK-001", Dollars(20), ""))
+	assert.Equal(t, Must(replica.Price("BK-001")), Dollars(12.99))
+	if Must(replica.Get("BK-001")) == Must(primary.Get("BK-001")) {
+		t.Error("primary and replica share an item")
+	}
+}
+
+func TestVerifySyncCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "primary.json")
+	assert.NoError(t, FileStore{Path: path}.Save(sampleCatalog()))
+	s := &cliSession{catalog: driftedReplica(), user: Actor{Name: "sam", Role: RoleClerk}, authz: NewAuthorizer()}
+
+	var out bytes.Buffer
+	assert.NoError(t, s.runCommand([]string{"verify-sync", "-primary", path}, &out))
+	for _, want := range []string{"BK-001: changed", "BK-999: extra", "MG-001: missing", "3 items drifted"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	// A clerk may look but not repair
+	err := s.runCommand([]string{"verify-sync", "-primary", path, "-repair"}, &out)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Equal(t, s.catalog.Len(), 2)
+
+	s.user = Actor{Name: "kim", Role: RoleManager}
+	assert.NoError(t, s.runCommand([]string{"verify-sync", "-primary", path, "-repair"}, &out))
+	out.Reset()
+	assert.NoError(t, s.runCommand([]string{"verify-sync", "-primary", path}, &out))
+	if !strings.Contains(out.String(), "In sync") {
+		t.Errorf("still drifted after -repair:\n%s", out.String())
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+// How fast is the RWMutex catalog when many goroutines use it at once?
+// The alternative is sync.Map, a map built for concurrent use that is
+// fastest when keys are written once and read many times (like a cache):
+//
+//	go test -run '^$' -bench Catalog
+//
+// Both versions look items up and replace whole entries. sync.Map can't
+// do more than that: changing a price inside an item still needs a
+// lock, which is one reason Catalog uses a mutex.
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// syncMapCatalog is the catalog's lookup path built on sync.Map instead
+type syncMapCatalog struct {
+	items sync.Map // SKU -> PricedItem
+}
+
+func (c *syncMapCatalog) Get(sku string) (PricedItem, error) {
+	item, ok := c.items.Load(sku)
+	if !ok {
+		return nil, &ItemNotFoundError{SKU: sku}
+	}
+	// sync.Map stores values as any, so they need a type assertion
+	return item.(PricedItem), nil
+}
+
+// Put adds or replaces the item under sku
+func (c *syncMapCatalog) Put(sku string, item PricedItem) {
+	c.items.Store(sku, item)
+}
+
+const benchCatalogSize = 1000
+
+// benchCatalogItems are the SKUs and items both catalogs are filled with
+func benchCatalogItems() ([]string, []PricedItem) {
+	skus := make([]string, benchCatalogSize)
+	items := make([]PricedItem, benchCatalogSize)
+	for i := range benchCatalogSize {
+		skus[i] = fmt.Sprintf("BK-%04d", i)
+		items[i] = Must(NewBook(fmt.Sprintf("Book %d", i), "Author", Dollars(9.99), ""))
+	}
+	return skus, items
+}
+
+// benchCatalogMix runs lookups from GOMAXPROCS goroutines, making one
+// operation in every "every" a write instead (none if every is 0)
+func benchCatalogMix(b *testing.B, every int, get func(sku string), put func(sku string, item PricedItem)) {
+	skus, items := benchCatalogItems()
+	for i := range skus {
+		put(skus[i], items[i])
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for op := 0; pb.Next(); op++ {
+			i := op % benchCatalogSize
+			if every > 0 && op%every == 0 {
+				put(skus[i], items[i])
+				continue
+			}
+			get(skus[i])
+		}
+	})
+}
+
+// benchCatalogWrites are the write shares each benchmark runs with, as
+// one write in every N operations
+var benchCatalogWrites = []struct {
+	name  string
+	every int
+}{{"reads-only", 0}, {"writes-10%", 10}, {"writes-50%", 2}}
+
+func BenchmarkCatalogRWMutex(b *testing.B) {
+	for _, w := range benchCatalogWrites {
+		b.Run(w.name, func(b *testing.B) {
+			c := NewCatalog()
+			benchCatalogMix(b, w.every, func(sku string) { c.Get(sku) }, c.put)
+		})
+	}
+}
+
+func BenchmarkCatalogSyncMap(b *testing.B) {
+	for _, w := range benchCatalogWrites {
+		b.Run(w.name, func(b *testing.B) {
+			var c syncMapCatalog
+			benchCatalogMix(b, w.every, func(sku string) { c.Get(sku) }, c.Put)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+// ------------------- OVERFLOW-SAFE ARITHMETIC ----------------
+// Python integers grow without limit, so 10**30 just works.
+// Go integers have a fixed size: int64 tops out at 9,223,372,036,854,775,807
+// and going past it silently WRAPS AROUND to a negative number.
+// These helpers detect that and return an error instead.
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrOverflow is returned when a result does not fit in an int64.
+// Callers can test for it with errors.Is(err, ErrOverflow).
+var ErrOverflow = errors.New("integer overflow")
+
+// CheckedAdd returns a+b, or ErrOverflow
+func CheckedAdd(a, b int64) (int64, error) {
+	// Overflow is only possible when both operands have the same sign
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrOverflow
+	}
+	return a + b, nil
+}
+
+// CheckedSub returns a-b, or ErrOverflow. It can't be CheckedAdd(a, -b):
+// -MinInt64 itself overflows.
+func CheckedSub(a, b int64) (int64, error) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, ErrOverflow
+	}
+	return a - b, nil
+}
+
+// CheckedMul returns a*b, or ErrOverflow
+func CheckedMul(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	// MinInt64 has no positive counterpart, so -1 * MinInt64 overflows
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+	product := a * b
+	// If the multiplication wrapped, dividing back won't give a
+	if product/b != a {
+		return 0, ErrOverflow
+	}
+	return product, nil
+}
+
+// CheckedSum adds up values, stopping at the first overflow
+func CheckedSum(values ...int64) (int64, error) {
+	var total int64
+	for _, v := range values {
+		var err error
+		if total, err = CheckedAdd(total, v); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// LineTotal is quantity × unit price in integer minor units (cents)
+func LineTotal(unitCents, quantity int64) (int64, error) {
+	if quantity < 0 {
+		return 0, errors.New("quantity cannot be negative")
+	}
+	return CheckedMul(unitCents, quantity)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+const (
+	maxInt = math.MaxInt64
+	minInt = math.MinInt64
+)
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		op   string
+		fn   func(a, b int64) (int64, error)
+		a, b int64
+		want int64 // ignored when overflow is expected
+		ok   bool
+	}{
+		{"+", CheckedAdd, maxInt - 1, 1, maxInt, true},
+		{"+", CheckedAdd, maxInt, 1, 0, false},
+		{"+", CheckedAdd, minInt + 1, -1, minInt, true},
+		{"+", CheckedAdd, minInt, -1, 0, false},
+		{"+", CheckedAdd, maxInt, minInt, -1, true},
+
+		{"-", CheckedSub, minInt + 1, 1, minInt, true},
+		{"-", CheckedSub, minInt, 1, 0, false},
+		{"-", CheckedSub, maxInt - 1, -1, maxInt, true},
+		{"-", CheckedSub, maxInt, -1, 0, false},
+		{"-", CheckedSub, 0, minInt, 0, false}, // -MinInt64 doesn't fit
+		{"-", CheckedSub, -1, minInt, maxInt, true},
+		{"-", CheckedSub, minInt, minInt, 0, true},
+
+		{"*", CheckedMul, maxInt, 1, maxInt, true},
+		{"*", CheckedMul, maxInt, 2, 0, false},
+		{"*", CheckedMul, minInt, 1, minInt, true},
+		{"*", CheckedMul, minInt, -1, 0, false},
+		{"*", CheckedMul, -1, minInt, 0, false},
+		{"*", CheckedMul, minInt / 2, 2, minInt, true},
+		{"*", CheckedMul, maxInt, 0, 0, true},
+		{"*", CheckedMul, 3037000500, 3037000500, 0, false}, // just over sqrt(MaxInt64)
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d%s%d", tt.a, tt.op, tt.b), func(t *testing.T) {
+			got, err := tt.fn(tt.a, tt.b)
+			if !tt.ok {
+				assert.ErrorIs(t, err, ErrOverflow)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckedSumAndLineTotal(t *testing.T) {
+	_, err := CheckedSum(maxInt, 1, -1)
+	assert.ErrorIs(t, err, ErrOverflow)
+	sum, err := CheckedSum(maxInt, -1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, sum, int64(maxInt))
+	}
+
+	_, err = LineTotal(maxInt/2+1, 2)
+	assert.ErrorIs(t, err, ErrOverflow)
+	if _, err := LineTotal(100, -1); err == nil || errors.Is(err, ErrOverflow) {
+		t.Errorf("negative quantity: got %v", err)
+	}
+}
+
+func TestMoneyOverflow(t *testing.T) {
+	most := Must(NewMoney(maxInt, "USD"))
+	least := Must(NewMoney(minInt, "USD"))
+	cent := Must(NewMoney(1, "USD"))
+
+	_, err := most.Add(cent)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = least.Sub(cent)
+	assert.ErrorIs(t, err, ErrOverflow)
+	// Negating MinInt64 overflows; subtracting it must say so
+	_, err = Money{}.Sub(least)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = most.Times(2)
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	diff, err := least.Sub(least)
+	if assert.NoError(t, err) {
+		assert.Equal(t, diff, Money{})
+	}
+}
+
+func TestSetPageCountBoundaries(t *testing.T) {
+	tests := []struct {
+		count int
+		ok    bool
+	}{
+		{math.MinInt, false},
+		{-1, false},
+		{0, false},
+		{1, true},
+		{MaxPageCount, true},
+		{MaxPageCount + 1, false},
+		{math.MaxInt, false},
+	}
+	for _, tt := range tests {
+		book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+		before := book.PageCount()
+		err := book.SetPageCount(tt.count)
+		if tt.ok {
+			if assert.NoError(t, err) {
+				assert.Equal(t, book.PageCount(), tt.count)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("SetPageCount(%d) succeeded", tt.count)
+		}
+		// A rejected count leaves the old one
+		assert.Equal(t, book.PageCount(), before)
+	}
+}
@@ -0,0 +1,528 @@
+package main
+
+// ------------------- COMMAND-LINE INTERFACE ------------------
+// Each command has its own flag.FlagSet, like a subparser in Python's
+// argparse:
+//
+//	bookstore list
+//	bookstore add-book -sku BK-002 -title Dune -author Herbert -price 9.99
+//	bookstore discount -sku BK-001 -percent 20
+//
+// A single command runs against a small sample catalog. "bookstore
+// shell" reads one command per line from stdin, all sharing the same
+// catalog, so added items stay around for the following commands.
+// With -store FILE the catalog is loaded from FILE and saved back after
+// the command (see filestore.go), so changes survive between runs.
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command is one CLI subcommand
+type command struct {
+	usage string
+	// perm is what the user needs to run the command (see authz.go)
+	perm Permission
+	run  func(s *cliSession, args []string, out io.Writer) error
+}
+
+// commands maps each subcommand name to its implementation
+var commands = map[string]command{
+	"add-book":      {"add-book -sku SKU -title TITLE -author AUTHOR -price PRICE [-seller SELLER]", PermManageCatalog, cmdAddBook},
+	"add-magazine":  {"add-magazine -sku SKU -name NAME -price PRICE -issue N", PermManageCatalog, cmdAddMagazine},
+	"add-ebook":     {"add-ebook -sku SKU -title TITLE -author AUTHOR -price PRICE -format EPUB|PDF|MOBI -size BYTES [-drm]", PermManageCatalog, cmdAddEBook},
+	"add-audiobook": {"add-audiobook -sku SKU -title TITLE -author AUTHOR -narrator NAME -length 8h24m (-price PRICE | -hourly RATE) [-credit]", PermManageCatalog, cmdAddAudioBook},
+	"import-prices": {"import-prices -file CSV [-map field=Header ...] [-preview N]", PermEditPrices, cmdImportPrices},
+	"list":          {"list [-lang LANGUAGES] [-format CATEGORY=TEMPLATE ...]", PermViewCatalog, cmdList},
+	"price":         {"price -sku SKU [-currency CODE -rates FILE|URL]", PermViewCatalog, cmdPrice},
+	"discount":      {"discount -sku SKU -percent P", PermEditPrices, cmdDiscount},
+	"serve":         {"serve [-addr localhost:8080] [-staff FILE]", PermManageStaff, cmdServe},
+	"schema":        {"schema", PermViewCatalog, cmdSchema},
+	"csv":           {"csv import -file CSV | csv export [-notes] [-out FILE]", PermManageCatalog, cmdCatalogCSV},
+	"verify-sync":   {"verify-sync -primary FILE [-repair]", PermViewCatalog, cmdVerifySync},
+	"notes":         {"notes -sku SKU [-add TEXT | -edit ID -text TEXT]", PermManageNotes, cmdNotes},
+}
+
+// sampleCatalog is what a fresh CLI session starts with
+func sampleCatalog() *Catalog {
+	c := NewCatalog()
+	c.Add("BK-001", Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(12.99), "Obscurus Books")))
+	c.Add("MG-001", Must(NewMagazine("Vogue", Dollars(12.99), 123)))
+	return c
+}
+
+// openCatalog loads the catalog saved at path, falling back to the
+// sample catalog when path is "" or the file doesn't exist yet
+func openCatalog(path string) (*Catalog, error) {
+	if path == "" {
+		return sampleCatalog(), nil
+	}
+	c, err := FileStore{Path: path}.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return sampleCatalog(), nil
+	}
+	return c, err
+}
+
+// saveCatalog writes c back to path, if one was given
+func saveCatalog(path string, c *Catalog) error {
+	if path == "" {
+		return nil
+	}
+	return FileStore{Path: path}.Save(c)
+}
+
+// cliSession is who runs commands against which catalog
+type cliSession struct {
+	catalog *Catalog
+	user    Actor
+	authz   *Authorizer
+}
+
+// cliUser is the person at the terminal: BOOKSTORE_USER (or USER) with
+// BOOKSTORE_ROLE. Whoever runs the CLI can edit the store file anyway,
+// so the role defaults to admin; a lower one suits a shared shell.
+func cliUser() (Actor, error) {
+	name := cmp.Or(os.Getenv("BOOKSTORE_USER"), os.Getenv("USER"), "local")
+	role := RoleAdmin
+	if r := os.Getenv("BOOKSTORE_ROLE"); r != "" {
+		var err error
+		if role, err = ParseRole(r); err != nil {
+			return Actor{}, fmt.Errorf("BOOKSTORE_ROLE: %w", err)
+		}
+	}
+	return Actor{Name: name, Role: role}, nil
+}
+
+// runCLI runs one command, or the shell, on the stored catalog
+func runCLI(store string, args []string) error {
+	catalog, err := openCatalog(store)
+	if err != nil {
+		return err
+	}
+	user, err := cliUser()
+	if err != nil {
+		return err
+	}
+	s := &cliSession{catalog: catalog, user: user, authz: NewAuthorizer()}
+	if args[0] == "shell" {
+		err = s.runShell(os.Stdin, os.Stdout)
+	} else {
+		err = s.runCommand(args, os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	return saveCatalog(store, catalog)
+}
+
+// runCommand checks that the user may run args[0], then runs it
+func (s *cliSession) runCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	if err := s.authz.Authorize(s.user, cmd.perm, "run "+args[0]); err != nil {
+		return err
+	}
+	return cmd.run(s, args[1:], out)
+}
+
+// runShell runs one command per input line until EOF.
+// A failing command is reported and the shell carries on.
+func (s *cliSession) runShell(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		args, err := splitArgs(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(out, "Error:", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if err := s.runCommand(args, out); err != nil {
+			fmt.Fprintln(out, "Error:", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// splitArgs splits a line on spaces, keeping "quoted text" together
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inQuotes, inArg := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case r == ' ' && !inQuotes:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// moneyValue is a flag.Value: the flag package calls Set with the text
+// given on the command line, so prices are parsed exactly, not as floats
+type moneyValue struct {
+	m Money
+}
+
+func (v *moneyValue) String() string { return v.m.String() }
+
+// Set accepts "9.99" (in DefaultCurrency) or "9.99 EUR"
+func (v *moneyValue) Set(s string) error {
+	amount, currency, _ := strings.Cut(strings.TrimSpace(s), " ")
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		return err
+	}
+	v.m = m
+	return nil
+}
+
+// newFlagSet returns a FlagSet that reports errors instead of exiting,
+// so a typo in the shell doesn't end the session
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	return fs
+}
+
+func cmdAddBook(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("add-book", out)
+	sku := fs.String("sku", "", "SKU to register the book under")
+	title := fs.String("title", "", "book title")
+	author := fs.String("author", "", "book author")
+	var price moneyValue
+	fs.Var(&price, "price", `price such as 9.99 or "9.99 EUR"`)
+	seller := fs.String("seller", "", "seller name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	book, err := NewBook(*title, *author, price.m, *seller)
+	if err != nil {
+		return err
+	}
+	if err := c.Add(*sku, book); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %s: %s\n", *sku, book.Summary())
+	return nil
+}
+
+func cmdAddMagazine(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("add-magazine", out)
+	sku := fs.String("sku", "", "SKU to register the magazine under")
+	name := fs.String("name", "", "magazine name")
+	var price moneyValue
+	fs.Var(&price, "price", `price such as 4.99 or "4.99 EUR"`)
+	issue := fs.Int("issue", 0, "issue number")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	magazine, err := NewMagazine(*name, price.m, *issue)
+	if err != nil {
+		return err
+	}
+	if err := c.Add(*sku, magazine); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %s: %s #%d - %v\n", *sku, *name, *issue, magazine.Price())
+	return nil
+}
+
+func cmdAddEBook(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("add-ebook", out)
+	sku := fs.String("sku", "", "SKU to register the e-book under")
+	title := fs.String("title", "", "e-book title")
+	author := fs.String("author", "", "e-book author")
+	var price moneyValue
+	fs.Var(&price, "price", `price such as 6.99 or "6.99 EUR"`)
+	format := fs.String("format", "EPUB", "file format: EPUB, PDF or MOBI")
+	size := fs.Int64("size", 0, "file size in bytes")
+	drm := fs.Bool("drm", false, "the file is DRM-protected")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	f, err := ParseEBookFormat(*format)
+	if err != nil {
+		return err
+	}
+	ebook, err := NewEBook(*title, *author, price.m, f, *size)
+	if err != nil {
+		return err
+	}
+	ebook.DRM = *drm
+	if err := c.Add(*sku, ebook); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %s: %s\n", *sku, ebook.Summary())
+	return nil
+}
+
+func cmdAddAudioBook(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("add-audiobook", out)
+	sku := fs.String("sku", "", "SKU to register the audiobook under")
+	title := fs.String("title", "", "audiobook title")
+	author := fs.String("author", "", "author")
+	narrator := fs.String("narrator", "", "narrator")
+	length := fs.Duration("length", 0, "listening time, such as 8h24m")
+	var price, hourly moneyValue
+	fs.Var(&price, "price", `list price such as 19.99 or "19.99 EUR"`)
+	fs.Var(&hourly, "hourly", "price per hour of listening, instead of -price")
+	credit := fs.Bool("credit", false, "sold for a subscription credit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !hourly.m.IsZero() {
+		price.m = HourlyPrice(hourly.m, *length)
+	}
+	audio, err := NewAudioBook(*title, *author, *narrator, price.m, *length)
+	if err != nil {
+		return err
+	}
+	if *credit {
+		audio.Pricing = SubscriptionCredit
+	}
+	if err := c.Add(*sku, audio); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %s: %s\n", *sku, audio.Summary())
+	return nil
+}
+
+func cmdList(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("list", out)
+	var formats stringList
+	fs.Var(&formats, "format", "CATEGORY=template summary format (repeatable, see summary_template.go)")
+	lang := fs.String("lang", "", `preferred languages for titles, e.g. "fr-CH, fr;q=0.9"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	overrides, err := ParseSummaryOverrides(formats)
+	if err != nil {
+		return err
+	}
+	templates, err := NewSummaryTemplates(overrides)
+	if err != nil {
+		return err
+	}
+	templates.Languages = ParseLanguagePreferences(*lang)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SKU\tCATEGORY\tSUMMARY")
+	for _, e := range c.Entries() {
+		summary, err := templates.Render(e.Item)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.SKU, err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.SKU, categoryOf(e.Item), summary)
+	}
+	return tw.Flush()
+}
+
+func cmdPrice(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("price", out)
+	sku := fs.String("sku", "", "SKU of the item")
+	currency := fs.String("currency", "", "also show the price in this currency")
+	rates := fs.String("rates", "", "exchange rates: a JSON file or an http(s) URL (see currency.go)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	item, err := c.Get(*sku)
+	if err != nil {
+		return err
+	}
+	if *currency == "" {
+		fmt.Fprintf(out, "%s: %v\n", itemTitle(item), item.Price())
+		return nil
+	}
+	if *rates == "" {
+		return fmt.Errorf("price: -currency needs -rates")
+	}
+	var provider RateProvider = &HTTPRates{URL: *rates}
+	if !strings.HasPrefix(*rates, "http://") && !strings.HasPrefix(*rates, "https://") {
+		if provider, err = LoadRatesFile(*rates); err != nil {
+			return err
+		}
+	}
+	converted, err := NewCurrencyConverter(provider).Convert(item.Price(), *currency)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s: %v (%v)\n", itemTitle(item), item.Price(), converted)
+	return nil
+}
+
+func cmdDiscount(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("discount", out)
+	sku := fs.String("sku", "", "SKU of the item")
+	percent := fs.Float64("percent", 0, "discount percentage, 0-100")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	item, err := c.Get(*sku)
+	if err != nil {
+		return err
+	}
+	p, err := NewPercent(*percent)
+	if err != nil {
+		return err
+	}
+	discounted, err := item.CalculateDiscount(p)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s: %v with %v off (was %v)\n", itemTitle(item), discounted, p, item.Price())
+	return nil
+}
+
+// cmdCatalogCSV imports books and magazines from CSV, or exports them
+func cmdCatalogCSV(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	if len(args) == 0 || (args[0] != "import" && args[0] != "export") {
+		return fmt.Errorf("csv: the subcommands are import and export")
+	}
+	fs := newFlagSet("csv "+args[0], out)
+	path := fs.String("file", "", "CSV file to import")
+	var opts CSVExportOptions
+	if args[0] == "export" {
+		path = fs.String("out", "", "CSV file to write; standard output if empty")
+		fs.BoolVar(&opts.Notes, "notes", false, "add a column with the staff notes; keep such files inside the store")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "import" {
+		f, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		result, err := c.ImportCSV(f)
+		if err != nil {
+			return err
+		}
+		for _, issue := range result.Issues {
+			fmt.Fprintf(out, "line %d skipped: %s\n", issue.Line, issue.Message)
+		}
+		fmt.Fprintf(out, "Added %d items, skipped %d lines\n", result.Added, len(result.Issues))
+		return nil
+	}
+
+	if *path == "" {
+		_, err := c.ExportCSV(out, opts)
+		return err
+	}
+	f, err := os.Create(*path)
+	if err != nil {
+		return err
+	}
+	n, err := c.ExportCSV(f, opts)
+	// Close can be the first to report a failed write, e.g. a full disk
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Exported %d items to %s\n", n, *path)
+	return nil
+}
+
+// cmdNotes lists the staff notes on an item, after adding or editing
+// one in the name of the session's user
+func cmdNotes(s *cliSession, args []string, out io.Writer) error {
+	fs := newFlagSet("notes", out)
+	sku := fs.String("sku", "", "SKU of the item")
+	add := fs.String("add", "", "text of a new note")
+	edit := fs.Int("edit", 0, "ID of the note to replace with -text")
+	text := fs.String("text", "", "new text of the note given by -edit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *add != "" && *edit != 0 {
+		return fmt.Errorf("notes: give -add or -edit, not both")
+	}
+	if (*edit != 0) != (*text != "") {
+		return fmt.Errorf("notes: -edit and -text go together")
+	}
+	var notes []Note
+	err := s.catalog.Annotate(*sku, func(a Annotated) error {
+		if *add != "" {
+			if _, err := a.AddNote(s.user.Name, *add); err != nil {
+				return err
+			}
+		}
+		if *edit != 0 {
+			if err := a.EditNote(*edit, s.user.Name, *text); err != nil {
+				return err
+			}
+		}
+		notes = a.Notes()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		fmt.Fprintf(out, "No notes on %s\n", *sku)
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAUTHOR\tWRITTEN\tVERSIONS\tNOTE")
+	for _, n := range notes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", n.ID, n.Author, n.At.Format(time.DateTime), len(n.History)+1, n.Text)
+	}
+	return tw.Flush()
+}
+
+// printUsage lists every command, sorted by name
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: bookstore [-pause 2s] [-store FILE] [-deterministic] [-events] [demo | shell | COMMAND [flags]]")
+	fmt.Fprintln(w, "commands:")
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+}
@@ -0,0 +1,193 @@
+// genassert writes compile-time interface assertions for a package.
+//
+// For every interface declared in the package it finds the concrete types
+// that have at least one of the interface's methods (same name and
+// signature) and emits
+//
+//	var _ PricedItem = (*Book)(nil)
+//
+// into assertions_gen.go. A type that has only SOME of the methods still
+// gets an assertion, so the build fails with a "missing method" error
+// instead of the type silently not being a PricedItem. Types that are
+// embedded in other structs are only asserted when they are complete,
+// since they usually supply part of a method set by design. Types
+// declared in the -exclude files (the demo's stand-ins, by default) are
+// not asserted at all.
+//
+// Usage (from the package directory, normally via go generate):
+//
+//	go run ./cmd/genassert [-out assertions_gen.go] [-exclude demo.go]
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+func main() {
+	out := flag.String("out", "assertions_gen.go", "file to write assertions to")
+	exclude := flag.String("exclude", "demo.go", "comma-separated files whose types are not asserted")
+	flag.Parse()
+
+	src, err := generate(".", *out, strings.Split(*exclude, ","))
+	if err != nil {
+		log.Fatalf("genassert: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("genassert: %v", err)
+	}
+}
+
+// generate type-checks the package in dir (ignoring the previous output
+// file, which may be stale) and returns the formatted assertions file.
+func generate(dir, out string, exclude []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	// out may be given as a path, e.g. ./assertions_gen.go
+	out = filepath.Base(out)
+	skip := func(fi os.FileInfo) bool {
+		name := fi.Name()
+		return name != out && !strings.HasSuffix(name, "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, skip, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkgName string
+	var files []*ast.File
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+	}
+
+	// Collect type errors instead of stopping: a type that is missing a
+	// method is exactly the case we want to report through the assertion.
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(pkgName, fset, files, nil)
+
+	var ifaces, concrete []*types.TypeName
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		if slices.Contains(exclude, filepath.Base(fset.Position(tn.Pos()).Filename)) {
+			continue
+		}
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			// (*T)(nil) is not valid for generic types without instantiation
+			continue
+		}
+		if _, isIface := tn.Type().Underlying().(*types.Interface); isIface {
+			ifaces = append(ifaces, tn)
+		} else {
+			concrete = append(concrete, tn)
+		}
+	}
+
+	embedded := embeddedTypes(concrete)
+
+	var lines []string
+	for _, it := range ifaces {
+		iface := it.Type().Underlying().(*types.Interface)
+		if iface.NumMethods() == 0 {
+			continue
+		}
+		for _, ct := range concrete {
+			if !sharesMethod(ct.Type(), iface) {
+				continue
+			}
+			// Embedded helpers contribute part of a method set on purpose;
+			// only assert them when they are complete on their own
+			ptr := types.NewPointer(ct.Type())
+			if embedded[ct] && !types.Implements(ptr, iface) {
+				continue
+			}
+			lines = append(lines, assertion(it.Name(), ct))
+		}
+	}
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genassert; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "// Compile-time checks that each type implements its interfaces.\n")
+	fmt.Fprintf(&buf, "// Regenerate with: go generate ./...\n")
+	fmt.Fprintf(&buf, "var (\n")
+	for _, l := range lines {
+		fmt.Fprintf(&buf, "\t%s\n", l)
+	}
+	fmt.Fprintf(&buf, ")\n")
+	return format.Source(buf.Bytes())
+}
+
+// embeddedTypes returns the types that appear as embedded struct fields
+func embeddedTypes(concrete []*types.TypeName) map[*types.TypeName]bool {
+	embedded := make(map[*types.TypeName]bool)
+	for _, ct := range concrete {
+		st, ok := ct.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if !f.Embedded() {
+				continue
+			}
+			t := f.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				embedded[named.Obj()] = true
+			}
+		}
+	}
+	return embedded
+}
+
+// sharesMethod reports whether T or *T declares any method of iface
+// with the same name AND signature. Same-named methods with different
+// parameters (a service's SetPrice(item, price), say) are unrelated.
+func sharesMethod(t types.Type, iface *types.Interface) bool {
+	mset := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < iface.NumMethods(); i++ {
+		want := iface.Method(i)
+		sel := mset.Lookup(want.Pkg(), want.Name())
+		if sel == nil {
+			continue
+		}
+		if types.Identical(sel.Type(), want.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// assertion uses the pointer form because *T has the methods of both
+// value and pointer receivers, and it works for non-struct types too
+func assertion(iface string, ct *types.TypeName) string {
+	return fmt.Sprintf("_ %s = (*%s)(nil)", iface, ct.Name())
+}
@@ -0,0 +1,106 @@
+package main
+
+// ------------------- COLLECTION HELPERS ----------------------
+// Since Go 1.21 the standard library has "slices" and "maps" packages
+// (sorting, searching, cloning, iterating keys). Python's list
+// comprehensions have no direct equivalent there, so the two missing
+// pieces live here as small generic functions.
+//
+//   Python: [f(x) for x in xs]          Go: Map(xs, f)
+//   Python: [x for x in xs if keep(x)]  Go: Filter(xs, keep)
+//   Python: functools.reduce(f, xs, a)  Go: Reduce(xs, a, f)
+//
+// Collection[T] is a slice of priced items with the same helpers as
+// methods, plus aggregates such as TotalValue and Cheapest.
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Map returns f applied to every element of s
+// [T, U any] are type parameters: Map works for any element types
+func Map[T, U any](s []T, f func(T) U) []U {
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// Filter returns the elements of s for which keep returns true
+func Filter[T any](s []T, keep func(T) bool) []T {
+	var out []T
+	for _, v := range s {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Reduce folds s into one value, starting from init
+func Reduce[T, A any](s []T, init A, f func(A, T) A) A {
+	acc := init
+	for _, v := range s {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Collection holds items of one type T, which can be an interface
+// (Collection[PricedItem]) or a concrete type (Collection[*Book]). With
+// a concrete T, Filter returns *Book values, so no type assertions are
+// needed to reach Book-only fields.
+//
+// The constraint T PricedItem lets the methods call Price on any T.
+//
+// Methods cannot declare type parameters of their own, so Map and
+// Reduce to another type stay functions: Map(c, f) works because a
+// Collection[T] is a []T.
+type Collection[T PricedItem] []T
+
+// Filter returns the items for which keep returns true
+func (c Collection[T]) Filter(keep func(T) bool) Collection[T] {
+	return Filter(c, keep)
+}
+
+// SortBy returns a sorted copy, ordered by cmp like slices.SortFunc
+func (c Collection[T]) SortBy(cmp func(a, b T) int) Collection[T] {
+	sorted := slices.Clone(c)
+	// Stable keeps items that compare equal in their original order
+	slices.SortStableFunc(sorted, cmp)
+	return sorted
+}
+
+// TotalValue adds up the prices; all items must share a currency
+func (c Collection[T]) TotalValue() (Money, error) {
+	var err error
+	sum := Reduce(c, lineSum{}, func(sum lineSum, item T) lineSum {
+		if err == nil {
+			err = sum.add(item.Price(), 1)
+		}
+		return sum
+	})
+	if err != nil {
+		return Money{}, fmt.Errorf("total value: %w", err)
+	}
+	return sum.total, nil
+}
+
+// Cheapest returns the lowest-priced item; ok is false if c is empty
+func (c Collection[T]) Cheapest() (cheapest T, ok bool) {
+	for i, item := range c {
+		if i == 0 || item.Price().Less(cheapest.Price()) {
+			cheapest, ok = item, true
+		}
+	}
+	return cheapest, ok
+}
+
+// ByPrice orders items from cheapest to most expensive, for SortBy.
+// Prices in different currencies count as equal.
+func ByPrice[T PricedItem](a, b T) int {
+	c, _ := a.Price().Cmp(b.Price())
+	return c
+}
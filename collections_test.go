@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestMap(t *testing.T) {
+	assert.Equal(t, Map([]int{1, 2, 3}, strconv.Itoa), []string{"1", "2", "3"})
+	// Map keeps the length: an empty input gives an empty, non-nil output
+	assert.Equal(t, Map([]int{}, strconv.Itoa), []string{})
+	assert.Equal(t, Map(nil, func(s string) int { return len(s) }), []int{})
+}
+
+func TestFilter(t *testing.T) {
+	even := func(n int) bool { return n%2 == 0 }
+	assert.Equal(t, Filter([]int{1, 2, 3, 4}, even), []int{2, 4})
+	assert.Equal(t, Filter([]int{1, 3}, even), []int(nil))
+
+	// Filter must not write into the input's backing array
+	in := []int{2, 1, 4}
+	Filter(in, even)
+	assert.Equal(t, in, []int{2, 1, 4})
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(acc, n int) int { return acc + n }
+	assert.Equal(t, Reduce([]int{1, 2, 3}, 10, sum), 16)
+	assert.Equal(t, Reduce(nil, 10, sum), 10)
+	// The accumulator can have another type than the elements
+	joined := Reduce([]int{1, 2}, "", func(acc string, n int) string { return acc + strconv.Itoa(n) })
+	assert.Equal(t, joined, "12")
+}
+
+func TestCollection(t *testing.T) {
+	dune := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	emma := Must(NewBook("Emma", "Jane Austen", Dollars(7.99), ""))
+	go1 := Must(NewBook("Go", "Alan Donovan", Dollars(7.99), ""))
+	books := Collection[*Book]{dune, emma, go1}
+
+	sorted := books.SortBy(ByPrice)
+	// Stable: Emma and Go cost the same and keep their order
+	assert.Equal(t, sorted, Collection[*Book]{emma, go1, dune})
+	assert.Equal(t, books, Collection[*Book]{dune, emma, go1})
+
+	cheapest, ok := books.Cheapest()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, cheapest, emma)
+	_, ok = Collection[*Book]{}.Cheapest()
+	assert.Equal(t, ok, false)
+
+	total, err := books.TotalValue()
+	if assert.NoError(t, err) {
+		assert.Equal(t, total, Dollars(25.97))
+	}
+	assert.Equal(t, books.Filter(func(b *Book) bool { return b.author == "Jane Austen" }), Collection[*Book]{emma})
+
+	euros := Must(NewBook("Faust", "Goethe", Must(NewMoney(1500, "EUR")), ""))
+	if _, err := (Collection[PricedItem]{dune, euros}).TotalValue(); err == nil {
+		t.Error("added dollars to euros")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+// ------------------- MARKETPLACE COMMISSION ------------------
+// The store takes a commission on every sale made by a seller.
+// The rate depends on the seller's tier and the item's category,
+// and rate changes take effect on a given date (old orders keep
+// the rate that applied when they were placed).
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// SellerTier ranks marketplace sellers
+// Go has no enum keyword; a named int with iota constants is the idiom
+type SellerTier int
+
+const (
+	TierStandard SellerTier = iota // iota counts up from 0 in a const block
+	TierSilver
+	TierGold
+)
+
+// String makes tiers print nicely with fmt (like Python's __str__)
+func (t SellerTier) String() string {
+	switch t {
+	case TierStandard:
+		return "standard"
+	case TierSilver:
+		return "silver"
+	case TierGold:
+		return "gold"
+	default:
+		return fmt.Sprintf("SellerTier(%d)", int(t))
+	}
+}
+
+// Categorized is implemented by items that know their category code
+type Categorized interface {
+	Category() string
+}
+
+// CommissionRule is one row of a rate card
+type CommissionRule struct {
+	Tier          SellerTier
+	Category      string // e.g. CategoryCode; "" matches every category
+	Rate          Percent
+	EffectiveFrom time.Time
+}
+
+// RateCard holds all commission rules, old and current
+type RateCard struct {
+	rules []CommissionRule
+}
+
+// DefaultRateCard: 15% on books, 8% on magazines and 12% on anything
+// else (e-books, bundles...), less for better tiers
+func DefaultRateCard(effectiveFrom time.Time) *RateCard {
+	rc := &RateCard{}
+	rates := map[SellerTier][3]Percent{
+		TierStandard: {MustPercent(15), MustPercent(8), MustPercent(12)},
+		TierSilver:   {MustPercent(13), MustPercent(7), MustPercent(10)},
+		TierGold:     {MustPercent(11), MustPercent(6), MustPercent(8)},
+	}
+	for tier, r := range rates {
+		rc.Add(CommissionRule{Tier: tier, Category: CategoryCode, Rate: r[0], EffectiveFrom: effectiveFrom})
+		rc.Add(CommissionRule{Tier: tier, Category: MagazineCategoryCode, Rate: r[1], EffectiveFrom: effectiveFrom})
+		// The catch-all keeps new item types from failing with "no rate"
+		rc.Add(CommissionRule{Tier: tier, Rate: r[2], EffectiveFrom: effectiveFrom})
+	}
+	return rc
+}
+
+// Add records a rule; it applies from its EffectiveFrom date onwards
+func (rc *RateCard) Add(rule CommissionRule) {
+	rc.rules = append(rc.rules, rule)
+}
+
+// Rate finds the commission percentage for a sale at time "at".
+// Among the rules already in effect, a category-specific rule beats a
+// catch-all one, and a newer rule beats an older one.
+func (rc *RateCard) Rate(tier SellerTier, category string, at time.Time) (Percent, error) {
+	candidates := Filter(rc.rules, func(r CommissionRule) bool {
+		return r.Tier == tier && !r.EffectiveFrom.After(at) &&
+			(r.Category == category || r.Category == "")
+	})
+	if len(candidates) == 0 {
+		return Percent{}, fmt.Errorf("no commission rate for %s sellers in category %q", tier, category)
+	}
+	// slices.MinFunc takes a compare function returning <0, 0 or >0,
+	// like the cmp= functions of Python 2's sorted()
+	best := slices.MinFunc(candidates, func(a, b CommissionRule) int {
+		if (a.Category == "") != (b.Category == "") {
+			if a.Category != "" {
+				return -1
+			}
+			return 1
+		}
+		// Newest first: compare b to a
+		return b.EffectiveFrom.Compare(a.EffectiveFrom)
+	})
+	return best.Rate, nil
+}
+
+// Commission returns the store's cut of selling item at its current price
+func (rc *RateCard) Commission(tier SellerTier, item PricedItem, at time.Time) (Money, error) {
+	rate, err := rc.Rate(tier, categoryOf(item), at)
+	if err != nil {
+		return Money{}, err
+	}
+	return item.Price().Portion(rate), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestDefaultRateCardCoversEveryItemType(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	card := DefaultRateCard(start)
+	bundle := Must(NewBundle("Starter pack", MustPercent(10)))
+	assert.NoError(t, bundle.Add(Must(NewBook("Dune", "Frank Herbert", Dollars(10), ""))))
+
+	tests := []struct {
+		item PricedItem
+		tier SellerTier
+		want Money
+	}{
+		{Must(NewBook("Dune", "Frank Herbert", Dollars(100), "")), TierStandard, Dollars(15)},
+		{Must(NewMagazine("Vogue", Dollars(100), 1)), TierGold, Dollars(6)},
+		{Must(NewEBook("Dune", "Frank Herbert", Dollars(100), FormatEPUB, 1<<20)), TierStandard, Dollars(12)},
+		{Must(NewAudioBook("Dune", "Frank Herbert", "Scott Brick", Dollars(100), 21*time.Hour)), TierSilver, Dollars(10)},
+		{bundle, TierGold, Dollars(0.72)}, // 8% of $9.00
+	}
+	for _, tt := range tests {
+		got, err := card.Commission(tt.tier, tt.item, start)
+		if assert.NoError(t, err) {
+			assert.Equal(t, got, tt.want)
+		}
+	}
+
+	// Nothing applies before the card takes effect
+	if _, err := card.Commission(TierStandard, bundle, start.Add(-time.Hour)); err == nil {
+		t.Error("got a rate before the rate card took effect")
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+// ------------------- CURRENCY CONVERSION ---------------------
+// A CurrencyConverter shows a price in another currency. Where the
+// exchange rates come from is up to a RateProvider, an interface with a
+// single method, so a fixed table, a JSON file and a web service are
+// interchangeable (like passing any object with a .rate() method in
+// Python's duck typing, but checked by the compiler).
+//
+// Files and web services use the common "rates against a base" layout:
+//
+//	{"base": "USD", "rates": {"EUR": 0.92, "GBP": 0.79}}
+//
+// meaning 1 USD buys 0.92 EUR. Rates are floats because that is how
+// they are published; the converted amount is rounded to whole cents
+// once, by Money.Scale.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateProvider knows how many units of to one unit of from buys
+type RateProvider interface {
+	Rate(from, to string) (float64, error)
+}
+
+// StaticRates is a fixed table: Rates[c] is how much of currency c one
+// unit of Base buys
+type StaticRates struct {
+	Base  string
+	Rates map[string]float64
+}
+
+func (s StaticRates) Rate(from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+	fromRate, err := s.perBase(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := s.perBase(to)
+	if err != nil {
+		return 0, err
+	}
+	// from -> base -> to
+	return toRate / fromRate, nil
+}
+
+func (s StaticRates) perBase(currency string) (float64, error) {
+	if currency == strings.ToUpper(s.Base) {
+		return 1, nil
+	}
+	rate, ok := s.Rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", currency)
+	}
+	return rate, nil
+}
+
+// ParseRates reads a {"base": ..., "rates": {...}} document
+func ParseRates(r io.Reader) (StaticRates, error) {
+	var doc struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return StaticRates{}, fmt.Errorf("reading exchange rates: %w", err)
+	}
+	if len(doc.Base) != 3 {
+		return StaticRates{}, fmt.Errorf("exchange rates need a 3-letter base currency, got %q", doc.Base)
+	}
+	rates := make(map[string]float64, len(doc.Rates))
+	for currency, rate := range doc.Rates {
+		// A zero rate would turn every price into 0 (or divide by it)
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return StaticRates{}, fmt.Errorf("exchange rate for %s must be positive, got %v", currency, rate)
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return StaticRates{Base: strings.ToUpper(doc.Base), Rates: rates}, nil
+}
+
+// LoadRatesFile reads exchange rates from a JSON file
+func LoadRatesFile(path string) (StaticRates, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return StaticRates{}, err
+	}
+	defer f.Close()
+	return ParseRates(f)
+}
+
+// HTTPRates fetches rates from a URL serving the JSON layout above and
+// reuses them for MaxAge, so converting a whole catalog is one request
+type HTTPRates struct {
+	URL    string
+	MaxAge time.Duration // 0 means an hour
+	Client *http.Client  // nil means a client with a 10s timeout
+
+	mu        sync.Mutex
+	cached    StaticRates
+	fetchedAt time.Time
+	// now is currentTime, replaceable so callers can control the clock
+	now func() time.Time
+}
+
+func (h *HTTPRates) Rate(from, to string) (float64, error) {
+	rates, err := h.rates()
+	if err != nil {
+		return 0, err
+	}
+	return rates.Rate(from, to)
+}
+
+// rates returns the cached table, fetching it when missing or stale
+func (h *HTTPRates) rates() (StaticRates, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := currentTime
+	if h.now != nil {
+		now = h.now
+	}
+	maxAge := h.MaxAge
+	if maxAge == 0 {
+		maxAge = time.Hour
+	}
+	if h.cached.Rates != nil && now().Sub(h.fetchedAt) < maxAge {
+		return h.cached, nil
+	}
+
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(h.URL)
+	if err != nil {
+		return StaticRates{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return StaticRates{}, fmt.Errorf("exchange rate source returned %s", resp.Status)
+	}
+	rates, err := ParseRates(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return StaticRates{}, err
+	}
+	h.cached, h.fetchedAt = rates, now()
+	return rates, nil
+}
+
+// CurrencyConverter converts Money with rates from a provider
+type CurrencyConverter struct {
+	rates RateProvider
+}
+
+func NewCurrencyConverter(rates RateProvider) *CurrencyConverter {
+	return &CurrencyConverter{rates: rates}
+}
+
+// Convert returns m in currency to, rounded to the nearest cent
+func (c *CurrencyConverter) Convert(m Money, to string) (Money, error) {
+	to = strings.ToUpper(to)
+	if m.Currency() == to {
+		return m, nil
+	}
+	rate, err := c.rates.Rate(m.Currency(), to)
+	if err != nil {
+		return Money{}, err
+	}
+	converted := m.Scale(rate)
+	return NewMoney(converted.Minor(), to)
+}
+
+// Compare orders two amounts in any currencies by converting both to
+// currency in; it returns -1, 0 or +1 like Money.Cmp
+func (c *CurrencyConverter) Compare(a, b Money, in string) (int, error) {
+	a, err := c.Convert(a, in)
+	if err != nil {
+		return 0, err
+	}
+	b, err = c.Convert(b, in)
+	if err != nil {
+		return 0, err
+	}
+	return a.Cmp(b)
+}
@@ -0,0 +1,140 @@
+package main
+
+// ------------------- SIGNED PAGE CURSORS ---------------------
+// Long lists are returned a page at a time. The client gets an opaque
+// "cursor" token pointing after the last item it saw and sends it back
+// for the next page.
+//
+// If the token were plain text, a client could edit it to skip around
+// or probe for items. So the token is signed with an HMAC (a keyed
+// hash, Python's hmac module): only the server knows the key, and any
+// change to the token makes the signature check fail.
+//
+//	token = base64(payload) + "." + base64(HMAC-SHA256(key, payload))
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor means the token was forged, corrupted, expired or
+// used for a different listing; an HTTP API answers it with 400
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPayload is what a token carries
+type cursorPayload struct {
+	SortKey string `json:"s"`
+	LastID  string `json:"l"`
+	Expires int64  `json:"e"` // Unix seconds
+}
+
+// CursorSigner creates and checks cursor tokens
+type CursorSigner struct {
+	key []byte
+	// TTL is how long a token stays valid
+	TTL time.Duration
+	now func() time.Time
+}
+
+// NewCursorSigner uses key to sign tokens; keep it secret
+func NewCursorSigner(key []byte, ttl time.Duration) (*CursorSigner, error) {
+	if len(key) < 16 {
+		return nil, fmt.Errorf("cursor key must be at least 16 bytes")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("cursor TTL must be positive")
+	}
+	return &CursorSigner{key: key, TTL: ttl, now: currentTime}, nil
+}
+
+// Encode returns a token for "the page after lastID, sorted by sortKey"
+func (s *CursorSigner) Encode(sortKey, lastID string) string {
+	payload, _ := json.Marshal(cursorPayload{
+		SortKey: sortKey,
+		LastID:  lastID,
+		Expires: s.now().Add(s.TTL).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload))
+}
+
+// Decode checks the token and returns the last ID it points after.
+// sortKey must match the one the token was created for.
+func (s *CursorSigner) Decode(token, sortKey string) (string, error) {
+	enc := base64.RawURLEncoding
+	payloadPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+	// hmac.Equal takes the same time however many bytes match, so the
+	// signature can't be guessed byte by byte from response times
+	if !hmac.Equal(sig, s.sign(payload)) {
+		return "", fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+	if p.SortKey != sortKey {
+		return "", fmt.Errorf("%w: token is for sorting by %q", ErrInvalidCursor, p.SortKey)
+	}
+	if s.now().Unix() > p.Expires {
+		return "", fmt.Errorf("%w: token expired", ErrInvalidCursor)
+	}
+	return p.LastID, nil
+}
+
+func (s *CursorSigner) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// CatalogPage is one page of a catalog listing
+type CatalogPage struct {
+	SKUs []string
+	// Next is the cursor for the following page, "" on the last page
+	Next string
+}
+
+// ListPage returns up to limit SKUs after the cursor ("" for the first page)
+func (c *Catalog) ListPage(signer *CursorSigner, cursor string, limit int) (CatalogPage, error) {
+	if limit <= 0 {
+		return CatalogPage{}, fmt.Errorf("page size must be positive")
+	}
+	skus := c.SKUs()
+	start := 0
+	if cursor != "" {
+		last, err := signer.Decode(cursor, "sku")
+		if err != nil {
+			return CatalogPage{}, err
+		}
+		// The first SKU sorting after the last one seen; this still works
+		// if that item was removed in the meantime
+		start, _ = slices.BinarySearch(skus, last)
+		if start < len(skus) && skus[start] == last {
+			start++
+		}
+	}
+	end := min(start+limit, len(skus))
+	page := CatalogPage{SKUs: skus[start:end]}
+	if end < len(skus) {
+		page.Next = signer.Encode("sku", skus[end-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+// ------------------- DEAL OF THE DAY -------------------------
+// Picks one featured item per calendar day, with better candidates
+// (higher weight) picked more often. Two things make it interesting:
+//
+// 1. Walker's alias method: after O(n) setup every pick is O(1),
+//    unlike Python's random.choices(weights=...) which searches
+//    cumulative weights on every call.
+// 2. The random source is seeded from the date, so every server
+//    running this code agrees on the same deal without talking.
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// AliasTable samples indexes 0..n-1 in proportion to their weights
+type AliasTable struct {
+	prob  []float64
+	alias []int
+}
+
+// NewAliasTable builds the table (Vose's variant of the alias method).
+// Weights must be non-negative and at least one must be positive.
+func NewAliasTable(weights []float64) (*AliasTable, error) {
+	n := len(weights)
+	if n == 0 {
+		return nil, fmt.Errorf("no weights given")
+	}
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 || w != w { // w != w is only true for NaN
+			return nil, fmt.Errorf("weight %d is invalid: %v", i, w)
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("all weights are zero")
+	}
+
+	t := &AliasTable{prob: make([]float64, n), alias: make([]int, n)}
+
+	// Scale weights so the average is 1, then split into
+	// columns that are too short (<1) and too tall (>=1)
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	// Fill each short column with the excess of a tall one
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small, large = small[:len(small)-1], large[:len(large)-1]
+
+		t.prob[s] = scaled[s]
+		t.alias[s] = l
+		scaled[l] -= 1 - scaled[s]
+		if scaled[l] < 1 {
+			small = append(small, l)
+		} else {
+			large = append(large, l)
+		}
+	}
+	// Whatever is left is full height (up to rounding error)
+	for _, i := range append(small, large...) {
+		t.prob[i] = 1
+	}
+	return t, nil
+}
+
+// Pick returns a random index: roll a column, then flip a biased coin
+func (t *AliasTable) Pick(rng *rand.Rand) int {
+	i := rng.Intn(len(t.prob))
+	if rng.Float64() < t.prob[i] {
+		return i
+	}
+	return t.alias[i]
+}
+
+// DealWeight scores how much an item deserves to be featured.
+// Margin and stock level are not tracked yet, so callers supply the
+// scoring; anything returning 0 is never picked.
+type DealWeight func(item PricedItem) float64
+
+// DealOfTheDay picks the featured item for the UTC calendar day of
+// "day". The same items in the same order always give the same result
+// for the same day, whatever time zone a server runs in: 23:30 in New
+// York and 05:30 the next morning in Berlin are the same UTC day.
+func DealOfTheDay(items []PricedItem, day time.Time, weight DealWeight) (PricedItem, error) {
+	weights := make([]float64, len(items))
+	for i, item := range items {
+		weights[i] = weight(item)
+	}
+	table, err := NewAliasTable(weights)
+	if err != nil {
+		return nil, fmt.Errorf("cannot pick a deal: %w", err)
+	}
+	// A private generator, so the global one used by randomPageCount
+	// is not affected (and cannot affect us)
+	rng := rand.New(rand.NewSource(daySeed(day)))
+	return items[table.Pick(rng)], nil
+}
+
+// daySeed turns 2024-03-15 (UTC) into 20240315
+func daySeed(day time.Time) int64 {
+	// Date reports the day in day's own location; UTC makes it the
+	// same moment-for-moment everywhere
+	y, m, d := day.UTC().Date()
+	return int64(y)*10000 + int64(m)*100 + int64(d)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestDaySeedIgnoresTimeZone(t *testing.T) {
+	newYork := time.FixedZone("EST", -5*60*60)
+	berlin := time.FixedZone("CET", 1*60*60)
+	// The same moment: 23:30 on the 14th in New York is 04:30 UTC on
+	// the 15th and 05:30 in Berlin
+	moment := time.Date(2024, time.March, 14, 23, 30, 0, 0, newYork)
+	assert.Equal(t, daySeed(moment), int64(20240315))
+	assert.Equal(t, daySeed(moment.In(berlin)), int64(20240315))
+	assert.Equal(t, daySeed(moment.UTC()), int64(20240315))
+}
+
+func TestDealOfTheDayIsStable(t *testing.T) {
+	items := []PricedItem{
+		Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), "")),
+		Must(NewBook("Emma", "Jane Austen", Dollars(7.99), "")),
+		Must(NewMagazine("Vogue", Dollars(12.99), 1)),
+	}
+	same := func(PricedItem) float64 { return 1 }
+	moment := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	want := Must(DealOfTheDay(items, moment, same))
+	for _, zone := range []*time.Location{time.FixedZone("A", -11*60*60), time.FixedZone("B", 11*60*60)} {
+		assert.Equal(t, Must(DealOfTheDay(items, moment.In(zone), same)), want)
+	}
+
+	// Items with weight 0 are never picked
+	onlyVogue := func(item PricedItem) float64 {
+		if _, ok := item.(*Magazine); ok {
+			return 1
+		}
+		return 0
+	}
+	for day := range 30 {
+		deal := Must(DealOfTheDay(items, moment.AddDate(0, 0, day), onlyVogue))
+		assert.Equal(t, deal, items[2])
+	}
+}
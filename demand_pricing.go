@@ -0,0 +1,209 @@
+package main
+
+// ------------------- DEMAND PRICING --------------------------
+// The demand pricer nudges prices by how fast an item sells compared to
+// how much of it is left. "Days of cover" is stock divided by units
+// sold per day: little cover means the item is running out, so it may
+// cost more; lots of cover means it isn't moving, so it gets cheaper.
+//
+//	cover < RaiseBelow               -> raise by Step
+//	RaiseBelow <= cover <= LowerAbove -> hold
+//	cover > LowerAbove               -> lower by Step
+//
+// Two things stop prices from bouncing up and down (hysteresis): the
+// band in the middle where nothing happens, and Confirm, the number of
+// runs in a row that must agree before the price moves. Every item has
+// its own min and max, and every change lands in the price history with
+// the cover that caused it.
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// DemandPricer moves opted-in item prices with their days of cover
+type DemandPricer struct {
+	Inventory *Inventory
+	// Window is how far back sales count (14 days if zero)
+	Window time.Duration
+	// Step is how much one run raises or lowers a price
+	Step Percent
+	// RaiseBelow and LowerAbove are days of cover
+	RaiseBelow, LowerAbove float64
+	// Confirm is how many runs in a row must agree before a price moves
+	// (1 if zero)
+	Confirm int
+
+	items []demandItem
+}
+
+type demandItem struct {
+	item     PricedItem
+	min, max Money
+	// signal is the direction of the last run, streak how many runs
+	// in a row gave it
+	signal int
+	streak int
+}
+
+// DefaultDemandWindow is the sales window used when Window is zero
+const DefaultDemandWindow = 14 * 24 * time.Hour
+
+// NewDemandPricer raises prices below raiseBelow days of cover and
+// lowers them above lowerAbove
+func NewDemandPricer(inv *Inventory, step Percent, raiseBelow, lowerAbove float64) (*DemandPricer, error) {
+	if raiseBelow < 0 || raiseBelow >= lowerAbove {
+		return nil, fmt.Errorf("need 0 <= raiseBelow < lowerAbove, got %v and %v", raiseBelow, lowerAbove)
+	}
+	return &DemandPricer{Inventory: inv, Step: step, RaiseBelow: raiseBelow, LowerAbove: lowerAbove}, nil
+}
+
+// OptIn lets the pricer manage item between min and max.
+// Opting in again updates the bounds and starts counting afresh.
+func (p *DemandPricer) OptIn(item PricedItem, min, max Money) error {
+	if max.Less(min) {
+		return fmt.Errorf("max %v is below min %v", max, min)
+	}
+	di := demandItem{item: item, min: min, max: max}
+	if i := slices.IndexFunc(p.items, func(d demandItem) bool { return d.item == item }); i >= 0 {
+		p.items[i] = di
+		return nil
+	}
+	p.items = append(p.items, di)
+	return nil
+}
+
+// OptOut hands the item's price back to manual control
+func (p *DemandPricer) OptOut(item PricedItem) {
+	p.items = slices.DeleteFunc(p.items, func(d demandItem) bool { return d.item == item })
+}
+
+// DemandPricingChange is one line of a demand pricing report
+type DemandPricingChange struct {
+	Item      PricedItem
+	OldPrice  Money
+	NewPrice  Money
+	Velocity  float64 // units sold per day
+	Available int
+	Cover     float64 // days; +Inf when nothing sold
+	// Note says why NewPrice was chosen
+	Note string
+	Err  error
+}
+
+// DemandPricingReport lists what a run did (or would do, for a dry run)
+type DemandPricingReport struct {
+	DryRun  bool
+	Changes []DemandPricingChange
+}
+
+// Run looks at every opted-in item once. A dry run neither changes
+// prices nor counts towards Confirm.
+func (p *DemandPricer) Run(dryRun bool) DemandPricingReport {
+	report := DemandPricingReport{DryRun: dryRun}
+	window := p.Window
+	if window == 0 {
+		window = DefaultDemandWindow
+	}
+	confirm := max(p.Confirm, 1)
+
+	for i := range p.items {
+		// A pointer, so the streak below is saved in the slice
+		d := &p.items[i]
+		change := DemandPricingChange{
+			Item:      d.item,
+			OldPrice:  d.item.Price(),
+			NewPrice:  d.item.Price(),
+			Velocity:  p.Inventory.SalesVelocity(d.item, window),
+			Available: p.Inventory.AvailableQuantity(d.item),
+		}
+		change.Cover = math.Inf(1)
+		if change.Velocity > 0 {
+			change.Cover = float64(change.Available) / change.Velocity
+		}
+
+		signal := 0
+		switch {
+		case change.Cover < p.RaiseBelow:
+			signal = 1
+		case change.Cover > p.LowerAbove && change.Available > 0:
+			signal = -1
+		}
+		streak := 1
+		if signal == d.signal {
+			streak = d.streak + 1
+		}
+		if !dryRun {
+			d.signal, d.streak = signal, streak
+		}
+
+		// target stays the old price unless the signal is confirmed
+		target := change.OldPrice
+		switch {
+		case signal == 0:
+			change.Note = "hold"
+		case streak < confirm:
+			change.Note = fmt.Sprintf("waiting (%d of %d runs)", streak, confirm)
+		case signal > 0:
+			target, change.Note = change.OldPrice.Scale(1+p.Step.Fraction()), "raise"
+			if d.max.Less(target) {
+				target, change.Note = d.max, "held at max"
+			}
+		default:
+			target, change.Note = change.OldPrice.Off(p.Step), "lower"
+			if target.Less(d.min) {
+				target, change.Note = d.min, "held at min"
+			}
+		}
+		if target == change.OldPrice {
+			report.Changes = append(report.Changes, change)
+			continue
+		}
+
+		change.NewPrice = target
+		if !dryRun {
+			reason := fmt.Sprintf("demand pricing: %.1f days of cover", change.Cover)
+			if math.IsInf(change.Cover, 1) {
+				reason = "demand pricing: no sales"
+			}
+			change.Err = setPriceBecause(d.item, target, reason)
+			// Start counting afresh, so the next move needs Confirm runs too
+			d.streak = 0
+		}
+		report.Changes = append(report.Changes, change)
+	}
+	return report
+}
+
+// Schedule runs the pricer every interval until stop is called.
+// In deterministic mode nothing is scheduled.
+func (p *DemandPricer) Schedule(interval time.Duration, dryRun bool, done func(DemandPricingReport)) (stop func()) {
+	return every(interval, func() { done(p.Run(dryRun)) })
+}
+
+// Print writes the report as an aligned table.
+// label names an item in the table, e.g. by its localized title.
+func (rep DemandPricingReport) Print(w io.Writer, label func(PricedItem) string) error {
+	if rep.DryRun {
+		fmt.Fprintln(w, "DRY RUN - no prices were changed")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tOLD\tNEW\tSOLD/DAY\tSTOCK\tCOVER\tNOTE")
+	for _, c := range rep.Changes {
+		note := c.Note
+		if c.Err != nil {
+			note = "error: " + c.Err.Error()
+		}
+		cover := "-"
+		if !math.IsInf(c.Cover, 1) {
+			cover = fmt.Sprintf("%.1f days", c.Cover)
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%.2f\t%d\t%s\t%s\n",
+			label(c.Item), c.OldPrice, c.NewPrice, c.Velocity, c.Available, cover, note)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,1439 @@
+package main
+
+// ------------------- GUIDED DEMO -----------------------------
+// The demo walks through every feature as a list of narrated steps.
+// Each step is a struct holding a function value, much like a Python
+// list of (title, callable) tuples. Adding a feature to the tour means
+// appending one more step.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// demoState is shared by all steps, so later steps can reuse the
+// items created by earlier ones
+type demoState struct {
+	catalog     *Catalog
+	harryPotter *Book
+	vogue       *Magazine
+	// A fixed "now" keeps most of the output reproducible
+	orderTime time.Time
+	// Features report events here instead of printing directly
+	notifications *NotificationHub
+}
+
+// demoStep is one narrated stage of the tour
+type demoStep struct {
+	title string
+	// explanation is printed before the step runs
+	explanation string
+	run         func(s *demoState)
+}
+
+// demoSteps returns the scripted tour in order
+func demoSteps() []demoStep {
+	return []demoStep{
+		{
+			title:       "Creating items and a catalog",
+			explanation: "NewBook is a factory function; the catalog keeps items under unique SKUs.",
+			run:         demoCreateItems,
+		},
+		{
+			title:       "Interfaces and discounts",
+			explanation: "Book, Magazine and AudioBook all satisfy PricedItem, so the same code prices each of them.",
+			run:         demoCatalogPricing,
+		},
+		{
+			title:       "Generic collections",
+			explanation: "Collection[T] works for any PricedItem type; with T = *Book no type assertions are needed.",
+			run:         demoCollections,
+		},
+		{
+			title:       "E-books",
+			explanation: "EBook is a third PricedItem; the cart prices it without knowing what it is.",
+			run:         demoEBooks,
+		},
+		{
+			title:       "Bundles",
+			explanation: "A Bundle is a PricedItem made of PricedItems, so bundles can hold bundles.",
+			run:         demoBundles,
+		},
+		{
+			title:       "Discount policies",
+			explanation: "A PricingEngine combines policies; its stacking rule settles conflicts between them.",
+			run:         demoPricingEngine,
+		},
+		{
+			title:       "Catalog drift detection",
+			explanation: "One hash per catalog tells whether two copies match; item hashes tell where.",
+			run:         demoCatalogDrift,
+		},
+		{
+			title:       "Concurrent catalog",
+			explanation: "Goroutines change prices while others read them; an RWMutex keeps the catalog consistent.",
+			run:         demoConcurrentCatalog,
+		},
+		{
+			title:       "Bulk repricing",
+			explanation: "A pool of worker goroutines reprices the whole catalog; each item's error is kept.",
+			run:         demoBulkRepricing,
+		},
+		{
+			title:       "Cancellation and timeouts",
+			explanation: "A context.Context carries a deadline; slow work checks ctx.Done() and gives up.",
+			run:         demoCancellation,
+		},
+		{
+			title:       "Signed page cursors",
+			explanation: "Page tokens carry an HMAC signature, so clients cannot forge them.",
+			run:         demoCursors,
+		},
+		{
+			title:       "HTTP API",
+			explanation: "Handlers map catalog errors to status codes; try \"go run . serve\".",
+			run:         demoHTTP,
+		},
+		{
+			title:       "Book previews",
+			explanation: "Excerpts are stored gzip-compressed and served on their own, with Range support.",
+			run:         demoPreviews,
+		},
+		{
+			title:       "Shopping cart",
+			explanation: "Checkout freezes the cart's prices, plus tax per rate, into an Order with a fixed lifecycle.",
+			run:         demoCart,
+		},
+		{
+			title:       "Gapless order numbers",
+			explanation: "Each store numbers its orders 1, 2, 3... with no gaps or repeats, even across restarts.",
+			run:         demoOrderNumbers,
+		},
+		{
+			title:       "Member prices",
+			explanation: "Member prices and member-only promotions are discount policies that check the customer.",
+			run:         demoMembership,
+		},
+		{
+			title:       "Quotes for business customers",
+			explanation: "A quote locks today's prices for N days; converting it later ignores price changes.",
+			run:         demoQuote,
+		},
+		{
+			title:       "JSON round trip",
+			explanation: "MarshalJSON exposes private fields through a DTO; notes stay internal.",
+			run:         demoJSON,
+		},
+		{
+			title:       "Catalog CSV",
+			explanation: "Books and magazines go out and come in as CSV; bad rows are reported and skipped.",
+			run:         demoCatalogCSV,
+		},
+		{
+			title:       "Inventory and selling out",
+			explanation: "Reserve takes units from stock; asking for more than is left is an error.",
+			run:         demoInventory,
+		},
+		{
+			title:       "Expiring reservations",
+			explanation: "A hold on stock lives in a TTLStore; unless paid in time, it expires and the copies go back.",
+			run:         demoReservationHolds,
+		},
+		{
+			title:       "Packs and single copies",
+			explanation: "Sealed packs are counted in units too; breaking one is just bookkeeping.",
+			run:         demoPacks,
+		},
+		{
+			title:       "Reorder points",
+			explanation: "Reorder when stock <= lead time x daily sales + safety stock.",
+			run:         demoReorder,
+		},
+		{
+			title:       "Purchase orders",
+			explanation: "Deliveries arrive in parts; stock is valued oldest lot first (FIFO).",
+			run:         demoPurchaseOrder,
+		},
+		{
+			title:       "Values vs pointers",
+			explanation: "Assigning a struct copies it; only pointers share (see value_semantics.go).",
+			run: func(s *demoState) {
+				printValueSemantics(ExploreValueSemantics())
+			},
+		},
+		{
+			title:       "Localization",
+			explanation: "Translations fall back to the base language, then to the original title.",
+			run:         demoLocalization,
+		},
+		{
+			title:       "Deal of the day",
+			explanation: "A date-seeded weighted pick: every server agrees on today's deal.",
+			run:         demoDealOfTheDay,
+		},
+		{
+			title:       "Order cutoff and shipping",
+			explanation: "Orders after 17:00 or on closed days ship on the next business day.",
+			run:         demoShipping,
+		},
+		{
+			title:       "Internal notes",
+			explanation: "Staff notes keep their edit history and never show up in Summary().",
+			run:         demoNotes,
+		},
+		{
+			title:       "Overflow-safe arithmetic",
+			explanation: "int64 math wraps around silently; checked_math.go catches it.",
+			run:         demoOverflow,
+		},
+		{
+			title:       "Price change throttling",
+			explanation: "At most 2 price changes per item per hour; errors.As reveals the wait.",
+			run:         demoPriceThrottle,
+		},
+		{
+			title:       "Price history",
+			explanation: "Every SetPrice is logged with its reason; LowestPrice looks back N days.",
+			run:         demoPriceHistory,
+		},
+		{
+			title:       "Domain events",
+			explanation: "Price changes, restocks and orders are published on an EventBus; listeners subscribe.",
+			run:         demoEvents,
+		},
+		{
+			title:       "Transactional outbox",
+			explanation: "A price and its event are saved together; a relay publishes the event at least once.",
+			run:         demoOutbox,
+		},
+		{
+			title:       "Read-through cache",
+			explanation: "An LRU cache wraps the repository; writes and sync events evict entries.",
+			run:         demoCachedRepository,
+		},
+		{
+			title:       "Store-wide sale",
+			explanation: "25% off everything except blacked-out items, never below an item's floor.",
+			run:         demoStoreSale,
+		},
+		{
+			title:       "Price source aggregation",
+			explanation: "Quotes far from the median (in MAD units) are rejected before pricing.",
+			run:         demoPriceSources,
+		},
+		{
+			title:       "Automatic repricing",
+			explanation: "Opted-in items undercut the cheapest trusted competitor, down to a floor.",
+			run:         demoRepricing,
+		},
+		{
+			title:       "Demand pricing",
+			explanation: "Prices follow days of cover, moving only when two runs in a row agree.",
+			run:         demoDemandPricing,
+		},
+		{
+			title:       "Roles and impersonation",
+			explanation: "One Authorizer checks every action and audits the decision.",
+			run:         demoAuthorization,
+		},
+		{
+			title:       "Marketplace commission",
+			explanation: "Commission depends on seller tier, category and date.",
+			run:         demoCommission,
+		},
+	}
+}
+
+// runDemo plays every step, waiting "pause" between them so a reader
+// can follow along. A zero pause prints everything at once.
+func runDemo(pause time.Duration) {
+	state := &demoState{
+		catalog:       NewCatalog(),
+		orderTime:     time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC),
+		notifications: NewNotificationHub(),
+	}
+	// Only the terminal is configured for the demo; a real store would
+	// also register a WebhookNotifier or EmailNotifier here
+	state.notifications.Register("terminal", &TerminalNotifier{Out: os.Stdout}, nil)
+	steps := demoSteps()
+	for i, step := range steps {
+		if i > 0 {
+			fmt.Println()
+			time.Sleep(pause)
+		}
+		fmt.Printf("=== Step %d/%d: %s ===\n", i+1, len(steps), step.title)
+		fmt.Println(step.explanation)
+		fmt.Println(strings.Repeat("-", len(step.explanation)))
+		step.run(state)
+	}
+}
+
+// itemTitle labels an item in tables by its original title
+func itemTitle(item PricedItem) string {
+	if t, ok := item.(Translatable); ok {
+		return t.LocalizedTitle(DefaultLanguage)
+	}
+	return "?"
+}
+
+func demoCreateItems(s *demoState) {
+	// := is a shorthand declaration operator
+	// It declares and initializes variables in one step
+	s.harryPotter = Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(10.99), "Flourish & Blotts"))
+
+	// Calling methods uses dot notation like Python
+	fmt.Println(s.harryPotter.Summary())
+
+	// Public fields can be accessed directly
+	fmt.Println("Original Seller:", s.harryPotter.Seller)
+	s.harryPotter.Seller = "Obscurus Books"
+	fmt.Println("New Seller:", s.harryPotter.Seller)
+
+	// Error handling pattern in Go:
+	// 1. Call function that returns error
+	// 2. Check if error is nil
+	// 3. Handle error if present
+	if err := s.harryPotter.SetPrice(Dollars(12.99)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	// Constructors check their arguments too, and report every problem
+	// at once. Must, above, is for arguments known to be good.
+	if _, err := NewBook("", "J.K. Rowling", Dollars(-5), ""); err != nil {
+		fmt.Println("Error:", err)
+		// errors.Is finds a sentinel error however deeply it is wrapped
+		fmt.Println("Negative price?", errors.Is(err, ErrNegativePrice))
+	}
+
+	fmt.Println(s.harryPotter.Summary())
+	fmt.Println("Price:", s.harryPotter.Price())
+	fmt.Println("Category Code:", GetCategoryCode())
+
+	// Creating a magazine instance
+	s.vogue = Must(NewMagazine("Vogue", Dollars(12.99), 123))
+
+	// Register both in the catalog under their SKUs
+	for sku, item := range map[string]PricedItem{"BK-001": s.harryPotter, "MG-001": s.vogue} {
+		if err := s.catalog.Add(sku, item); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+	// A duplicate SKU is rejected instead of overwriting the first item
+	if err := s.catalog.Add("BK-001", s.vogue); err != nil {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println("Catalog SKUs:", s.catalog.SKUs())
+	// errors.As pulls out a typed error, with its fields
+	var notFound *ItemNotFoundError
+	if _, err := s.catalog.Get("BK-404"); errors.As(err, &notFound) {
+		fmt.Println("No item with SKU", notFound.SKU)
+	}
+}
+
+func demoCatalogPricing(s *demoState) {
+	// A fixed table keeps the demo offline; LoadRatesFile and HTTPRates
+	// provide the same rates from a file or a web service
+	converter := NewCurrencyConverter(StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.92}})
+	for i, sku := range s.catalog.SKUs() {
+		if i > 0 {
+			fmt.Println()
+		}
+		item, err := s.catalog.Get(sku)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Printf("%s pricing:\n", sku)
+		printItemPriceInfo(item, converter, "EUR")
+	}
+
+	// Audiobooks aren't in the catalog, but any PricedItem will do.
+	// This one is priced at $2.50 per hour of listening.
+	length := 8*time.Hour + 24*time.Minute
+	audio := Must(NewAudioBook("Harry Potter", "J.K. Rowling", "Stephen Fry", HourlyPrice(Dollars(2.50), length), length))
+	fmt.Printf("\n%s\n", audio.Summary())
+	printItemPriceInfo(audio, converter, "EUR")
+	audio.Pricing = SubscriptionCredit
+	fmt.Printf("\n%s\n", audio.Summary())
+	printItemPriceInfo(audio, converter, "EUR")
+}
+
+func demoCollections(s *demoState) {
+	books := Collection[*Book]{
+		s.harryPotter,
+		Must(NewBook("The Go Programming Language", "Alan Donovan", Dollars(34.99), "")),
+		Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), "")),
+	}
+	for _, b := range books.SortBy(ByPrice[*Book]) {
+		// b is a *Book, so Book-only fields are right there
+		fmt.Printf("  %-8v %s\n", b.Price(), b.author)
+	}
+	under20 := books.Filter(func(b *Book) bool { return b.Price().Less(Dollars(20)) })
+	fmt.Println("Under $20:", Map(under20, func(b *Book) string { return b.title }))
+
+	all := Collection[PricedItem](s.catalog.List())
+	total, err := all.TotalValue()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	cheapest, _ := all.Cheapest()
+	fmt.Printf("Catalog: %d items worth %v, cheapest %s\n", len(all), total, itemTitle(cheapest))
+}
+
+func demoEBooks(s *demoState) {
+	ebook := Must(NewEBook("Harry Potter", "J.K. Rowling", Dollars(7.99), FormatEPUB, 2_516_582))
+	ebook.PaperEdition = s.harryPotter
+	fmt.Println(ebook.Summary())
+	fmt.Println("In stock without any inventory:", ebook.InStock(NewInventory()))
+
+	// The e-book is cheaper only when the paper edition is in the same cart
+	engine := NewPricingEngine(StackAll, EditionBundleDiscount{Percent: MustPercent(50)})
+	for _, withPaper := range []bool{false, true} {
+		var cart Cart
+		cart.AddItem(ebook, 1)
+		if withPaper {
+			cart.AddItem(s.harryPotter, 1)
+		}
+		total, err := cart.TotalWithDiscounts(engine, s.orderTime)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Printf("Cart with paper edition: %-5v total %v\n", withPaper, total)
+	}
+}
+
+func demoBundles(s *demoState) {
+	ebook := Must(NewEBook("Harry Potter", "J.K. Rowling", Dollars(7.99), FormatEPUB, 2_516_582))
+	paperAndEBook := Must(NewBundle("Paper + e-book", MustPercent(25)))
+	paperAndEBook.Add(s.harryPotter)
+	paperAndEBook.Add(ebook)
+
+	boxSet := Must(NewBundle("Collector's box", MustPercent(10)))
+	boxSet.Add(paperAndEBook)
+	boxSet.Add(s.vogue)
+	if err := paperAndEBook.Add(boxSet); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	fmt.Println(paperAndEBook.Summary())
+	fmt.Println(boxSet.Summary())
+	contents, _ := boxSet.ContentsTotal()
+	saved, _ := boxSet.Savings()
+	fmt.Printf("Box set: %v instead of %v, saving %v\n", boxSet.Price(), contents, saved)
+	// 20% off every item, then each bundle's own discount on top
+	discounted, err := boxSet.CalculateDiscount(MustPercent(20))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Box set with 20% off:", discounted)
+	if err := boxSet.SetPrice(Dollars(20)); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func demoPricingEngine(s *demoState) {
+	springSale := SeasonalDiscount{
+		Label:   "Spring sale",
+		Start:   time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		Percent: MustPercent(20),
+	}
+	engine := NewPricingEngine(StackAll,
+		springSale,
+		// What Magazine.CalculateDiscount used to do on its own
+		PercentageDiscount{Percent: MustPercent(10), Category: MagazineCategoryCode, MinPrice: Dollars(10)},
+		BulkDiscount{MinQuantity: 10, Percent: MustPercent(5)},
+	)
+	for _, sku := range s.catalog.SKUs() {
+		item, _ := s.catalog.Get(sku)
+		for _, qty := range []int{1, 10} {
+			r := engine.Price(DiscountContext{Item: item, Quantity: qty, At: s.orderTime})
+			fmt.Printf("%s x%d: %v -> %v (%s)\n", sku, qty, r.Base, r.Final, strings.Join(r.Applied, " + "))
+		}
+	}
+
+	// A coupon, a store sale, a member price and a bulk tier all apply
+	// here; the stacking rule decides how they combine
+	sale := &StoreSale{}
+	sale.Activate(SaleConfig{Discount: MustPercent(15)})
+	members := NewMemberPricing()
+	if err := members.SetMemberPrice(s.harryPotter, Dollars(11.49)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	ctx := DiscountContext{Item: s.harryPotter, Quantity: 10, At: s.orderTime, Member: true, Coupons: []string{"spring10"}}
+	fmt.Println("\nBK-001 x10 for a member with coupon SPRING10, during a 15% sale:")
+	for _, rule := range []StackingRule{StackAll, BestOnly, SmallestOnly, StackCapped} {
+		engine := NewPricingEngine(rule,
+			Coupon{Code: "SPRING10", Percent: MustPercent(10)},
+			sale,
+			members,
+			BulkDiscount{MinQuantity: 10, Percent: MustPercent(5)},
+		)
+		engine.MaxDiscount = MustPercent(25)
+		r := engine.Price(ctx)
+		capped := ""
+		if r.Capped {
+			capped = fmt.Sprintf(", capped at %v", engine.MaxDiscount)
+		}
+		fmt.Printf("%-17v %v (%s%s)\n", rule, r.Final, strings.Join(r.Applied, " + "), capped)
+	}
+}
+
+func demoCatalogDrift(s *demoState) {
+	// A replica with its own copies of the items, one of them outdated
+	replica := NewCatalog()
+	oldBook := Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(10.99), "Obscurus Books"))
+	replica.Add("BK-001", oldBook)
+	replica.Add("BK-999", Must(NewBook("Discontinued", "Nobody", Dollars(1), "")))
+
+	primaryDigest, _ := s.catalog.Digest()
+	replicaDigest, _ := replica.Digest()
+	fmt.Printf("Roots match: %v\n", primaryDigest.Root == replicaDigest.Root)
+	drift := CompareDigests(replicaDigest, primaryDigest)
+	for _, d := range drift {
+		fmt.Printf("  %s: %v\n", d.SKU, d.Kind)
+	}
+
+	if err := replica.Repair(s.catalog, drift); err != nil {
+		fmt.Println("Error:", err)
+	}
+	replicaDigest, _ = replica.Digest()
+	fmt.Printf("After repair, roots match: %v\n", primaryDigest.Root == replicaDigest.Root)
+}
+
+func demoConcurrentCatalog(s *demoState) {
+	const writers, readers, rounds = 3, 5, 20
+	catalog := NewCatalog()
+	for i := range writers {
+		catalog.Add(fmt.Sprintf("BK-%03d", i), Must(NewBook(fmt.Sprintf("Book %d", i), "Author", Dollars(10), "")))
+	}
+	low, high := Dollars(9), Dollars(11)
+
+	// A WaitGroup waits for a set of goroutines, like asyncio.gather
+	var wg sync.WaitGroup
+	// Each writer flips the price of its own book between low and high
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sku := fmt.Sprintf("BK-%03d", i)
+			for round := range rounds {
+				price := low
+				if round%2 == 1 {
+					price = high
+				}
+				if err := catalog.SetPrice(sku, price, "stress test"); err != nil {
+					fmt.Println("Error:", err)
+				}
+			}
+		}()
+	}
+	// Readers only ever see a whole price, never half of a write
+	var badReads atomic.Int64
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				for _, sku := range catalog.SKUs() {
+					price, err := catalog.Price(sku)
+					if err != nil || (price != low && price != high && price != Dollars(10)) {
+						badReads.Add(1)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("%d writers made %d price changes while %d readers made %d reads, %d of them bad\n",
+		writers, writers*rounds, readers, readers*rounds*writers, badReads.Load())
+	for _, sku := range catalog.SKUs() {
+		price, _ := catalog.Price(sku)
+		fmt.Printf("  %s ends at %v\n", sku, price)
+	}
+}
+
+func demoBulkRepricing(s *demoState) {
+	catalog := NewCatalog()
+	catalog.Add("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), "")))
+	catalog.Add("BK-002", Must(NewBook("Emma", "Jane Austen", Dollars(7.99), "")))
+	catalog.Add("EB-001", Must(NewEBook("Dune", "Frank Herbert", Dollars(4.99), FormatEPUB, 2<<20)))
+	catalog.Add("MG-001", Must(NewMagazine("Vogue", Dollars(12.99), 124)))
+
+	// Printed books go up 10%; magazine prices are the publisher's
+	raise := func(ctx context.Context, item PricedItem) (Money, error) {
+		switch item.(type) {
+		case *Book:
+			return item.Price().Scale(1.10), nil
+		case *Magazine:
+			return Money{}, errors.New("magazine prices are set by the publisher")
+		}
+		return item.Price(), nil
+	}
+	result := BulkRepricer{Workers: 3, Reason: "annual price review"}.RepriceAll(context.Background(), catalog, raise)
+	fmt.Printf("Changed %d, unchanged %d, failed %d\n", result.Changed, result.Unchanged, len(result.Errors))
+	if err := result.Err(); err != nil {
+		fmt.Println("Errors:", err)
+	}
+	for _, e := range catalog.Entries() {
+		fmt.Printf("  %s %s: %v\n", e.SKU, itemTitle(e.Item), e.Item.Price())
+	}
+}
+
+func demoCancellation(s *demoState) {
+	catalog := NewCatalog()
+	catalog.Add("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), "")))
+	catalog.Add("BK-002", Must(NewBook("Emma", "Jane Austen", Dollars(7.99), "")))
+	catalog.Add("EB-001", Must(NewEBook("Dune", "Frank Herbert", Dollars(4.99), FormatEPUB, 2<<20)))
+	catalog.Add("EB-002", Must(NewEBook("Emma", "Jane Austen", Dollars(3.99), FormatEPUB, 1<<20)))
+
+	// E-book discounts need the publisher's approval, which is slow
+	flashSale := func(ctx context.Context, item PricedItem) (Money, error) {
+		if _, ok := item.(*EBook); ok {
+			select {
+			case <-time.After(time.Hour):
+			case <-ctx.Done():
+				return Money{}, ctx.Err()
+			}
+		}
+		return item.CalculateDiscount(MustPercent(20))
+	}
+	// Like asyncio.wait_for: the whole run gets 50ms
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result := BulkRepricer{Workers: 1, Reason: "flash sale"}.RepriceAll(ctx, catalog, flashSale)
+	fmt.Printf("Changed %d, skipped %d\n", result.Changed, result.Skipped)
+	if err := result.Err(); err != nil {
+		fmt.Println("Stopped:", err)
+	}
+	if errors.Is(result.Stopped, context.DeadlineExceeded) {
+		fmt.Println("The deadline passed; the rest of the catalog was left alone")
+	}
+}
+
+func demoCursors(s *demoState) {
+	signer, err := NewCursorSigner([]byte("demo-key-not-for-production"), 10*time.Minute)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	signer.now = func() time.Time { return s.orderTime }
+
+	// One item per page to walk the whole catalog
+	page, _ := s.catalog.ListPage(signer, "", 1)
+	fmt.Println("Page 1:", page.SKUs)
+	next := page.Next
+	page, _ = s.catalog.ListPage(signer, next, 1)
+	fmt.Println("Page 2:", page.SKUs, "last page:", page.Next == "")
+
+	// Changing a single character breaks the signature
+	tampered := "x" + next[1:]
+	if _, err := s.catalog.ListPage(signer, tampered, 1); errors.Is(err, ErrInvalidCursor) {
+		fmt.Println("Tampered:", err)
+	}
+	signer.now = func() time.Time { return s.orderTime.Add(time.Hour) }
+	if _, err := s.catalog.ListPage(signer, next, 1); err != nil {
+		fmt.Println("An hour later:", err)
+	}
+}
+
+func demoHTTP(s *demoState) {
+	server, err := NewCatalogServer(s.catalog)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	// Writes need a staff member's API key
+	const key = "demo-key-for-kim-the-manager"
+	if err := server.Authz.AddKey(key, Actor{Name: "kim", Role: RoleManager}); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	// httptest.NewRecorder captures a response without opening a port
+	requests := []struct{ method, path, body string }{
+		{"GET", "/items/MG-001", ""},
+		{"PUT", "/items/BK-001/price", `{"price": -1}`},
+		{"POST", "/items/BK-001/discount", `{"percent": 50}`},
+		{"GET", "/items/XX-404", ""},
+		// The price update fails, so the stock adjustment is rolled back
+		{"POST", "/batch", `{"transactional": true, "operations": [
+			{"op": "adjust_stock", "sku": "MG-001", "delta": 10},
+			{"op": "update_price", "sku": "MG-001", "price": -5}]}`},
+	}
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		r.Header.Set("Authorization", "Bearer "+key)
+		server.ServeHTTP(rec, r)
+		fmt.Printf("%s %s -> %d %s", req.method, req.path, rec.Code, rec.Body.String())
+	}
+}
+
+func demoPreviews(s *demoState) {
+	chapter := strings.Repeat("Mr. and Mrs. Dursley, of number four, Privet Drive, were proud to say "+
+		"that they were perfectly normal, thank you very much. ", 40)
+	if err := s.harryPotter.SetExcerpt(chapter); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Excerpt: %d bytes, stored in %d\n", len(chapter), s.harryPotter.ExcerptStoredSize())
+	// Keep the rest of the tour's output as it was
+	defer s.harryPotter.SetExcerpt("")
+
+	server, err := NewCatalogServer(s.catalog)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/items/BK-001", nil))
+	fmt.Printf("GET /items/BK-001 -> %d %s", rec.Code, rec.Body.String())
+
+	// The first 60 bytes only, as a reader app would ask for them
+	req := httptest.NewRequest("GET", "/items/BK-001/preview", nil)
+	req.Header.Set("Range", "bytes=0-59")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	fmt.Printf("GET /items/BK-001/preview (Range: bytes=0-59) -> %d %s\n%q\n",
+		rec.Code, rec.Header().Get("Content-Range"), rec.Body.String())
+}
+
+func demoCart(s *demoState) {
+	engine := NewPricingEngine(StackAll, BulkDiscount{MinQuantity: 10, Percent: MustPercent(5)})
+	var cart Cart
+	cart.AddItem(s.harryPotter, 1)
+	cart.AddItem(s.vogue, 4)
+	cart.AddItem(s.vogue, 6) // same item again: quantity becomes 10
+	// German VAT: books have a reduced rate
+	cart.Tax = RegionalTax{Rules: []TaxRule{
+		{Region: "DE", Category: CategoryCode, TaxRate: TaxRate{"VAT 7%", MustPercent(7)}},
+		{Region: "DE", TaxRate: TaxRate{"VAT 19%", MustPercent(19)}},
+	}}
+	cart.Region = "DE"
+	subtotal, _ := cart.Subtotal()
+	total, _ := cart.TotalWithDiscounts(engine, s.orderTime)
+	fmt.Printf("Subtotal %v, with discounts %v\n", subtotal, total)
+
+	order, err := cart.Checkout(engine, s.orderTime)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, l := range order.Lines {
+		fmt.Printf("  %-12s x%-2d %v each %v\n", itemTitle(l.Item), l.Quantity, l.Paid, l.Discounts)
+	}
+	for _, t := range order.Tax {
+		fmt.Printf("  %-16s %v on %v\n", t.Label, t.Amount, t.Taxable)
+	}
+	fmt.Printf("Order total %v; cart now has %d lines\n", order.Total, len(cart.Lines()))
+	if _, err := cart.Checkout(engine, s.orderTime); err != nil {
+		fmt.Println("Checkout again:", err)
+	}
+
+	// The order's lifecycle: invalid moves are refused
+	order.Pay(s.orderTime.Add(5 * time.Minute))
+	order.Ship(s.orderTime.Add(6 * time.Hour))
+	if err := order.Cancel(s.orderTime.Add(7 * time.Hour)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	order.Deliver(s.orderTime.AddDate(0, 0, 2))
+	for _, t := range order.History() {
+		fmt.Printf("  %s  %v -> %v\n", t.At.Format("Jan 2 15:04"), t.From, t.To)
+	}
+}
+
+func demoOrderNumbers(s *demoState) {
+	dir, err := os.MkdirTemp("", "bookstore")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "order-numbers.json")
+
+	// 30 checkouts at once, 20 at the main store and 10 at the airport
+	engine := NewPricingEngine(StackAll)
+	numbers := NewFileSequence(path)
+	var (
+		mu     sync.Mutex
+		placed = make(map[string][]string)
+		wg     sync.WaitGroup
+	)
+	for i := range 30 {
+		store := "MAIN"
+		if i%3 == 0 {
+			store = "AIRPORT"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cart := Cart{Numbers: numbers, Store: store}
+			cart.AddItem(s.vogue, 1)
+			order, err := cart.Checkout(engine, s.orderTime)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			mu.Lock()
+			placed[store] = append(placed[store], order.Number)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Gapless means the sorted numbers are exactly 1..count
+	for _, store := range []string{"AIRPORT", "MAIN"} {
+		got := slices.Sorted(slices.Values(placed[store]))
+		gapless := true
+		for i, number := range got {
+			gapless = gapless && number == FormatOrderNumber(store, int64(i+1))
+		}
+		fmt.Printf("%-7s %d orders, %s to %s, gapless: %v\n", store, len(got), got[0], got[len(got)-1], gapless)
+	}
+
+	// A new FileSequence on the same file stands in for a restart
+	cart := Cart{Numbers: NewFileSequence(path), Store: "MAIN"}
+	cart.AddItem(s.harryPotter, 1)
+	order, err := cart.Checkout(engine, s.orderTime)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("After a restart:")
+	order.PrintReceipt(os.Stdout, itemTitle)
+}
+
+func demoCatalogCSV(s *demoState) {
+	if _, err := s.catalog.ExportCSV(os.Stdout, CSVExportOptions{}); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// A distributor's file: quoted commas, a bad price, an unknown type
+	file := `Type,SKU,Title,Author,Price,Issue
+book,BK-100,"Dune, Part One",Frank Herbert,9.99,
+book,BK-101,Emma,Jane Austen,cheap,
+magazine,MG-100,"The ""New"" Yorker",,8.99,42
+comic,CM-001,Watchmen,Alan Moore,19.99,
+`
+	catalog := NewCatalog()
+	result, err := catalog.ImportCSV(strings.NewReader(file))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, issue := range result.Issues {
+		fmt.Printf("line %d skipped: %s\n", issue.Line, issue.Message)
+	}
+	fmt.Printf("Imported %d items: %s\n", result.Added, strings.Join(Map(catalog.List(), itemTitle), "; "))
+}
+
+func demoJSON(s *demoState) {
+	data, err := json.Marshal(s.vogue)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println(string(data))
+
+	// Unmarshal into a fresh Magazine and check nothing was lost
+	var restored Magazine
+	if err := json.Unmarshal(data, &restored); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Restored: %s, %v\n", restored.LocalizedTitle(DefaultLanguage), restored.Price())
+
+	// Invalid data is rejected just like SetPrice would reject it
+	if err := json.Unmarshal([]byte(`{"title":"Bad","price":-5}`), &Book{}); err != nil {
+		fmt.Println("Rejected:", err)
+	}
+}
+
+func demoInventory(s *demoState) {
+	inventory := NewInventory()
+	if err := inventory.Restock(s.vogue, 3); err != nil {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println("Vogue in stock:", s.vogue.InStock(inventory), "- available:", inventory.AvailableQuantity(s.vogue))
+
+	// Two customers buy all three copies between them
+	for _, qty := range []int{2, 1} {
+		if err := inventory.Reserve(s.vogue, qty); err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		if err := inventory.Commit(s.vogue, qty); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+	fmt.Println("Vogue in stock:", s.vogue.InStock(inventory), "- available:", inventory.AvailableQuantity(s.vogue))
+
+	// A third customer is turned away
+	if err := inventory.Reserve(s.vogue, 1); err != nil {
+		fmt.Println("Sold out:", err)
+	}
+}
+
+func demoReservationHolds(s *demoState) {
+	inventory := NewInventory()
+	if err := inventory.Restock(s.harryPotter, 5); err != nil {
+		fmt.Println("Error:", err)
+	}
+	holds := NewHolds(inventory, func(cart string, h Hold, err error) {
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("%s's hold expired, %d back on the shelf\n", cart, h.Qty)
+	})
+	defer holds.Close()
+	// Replace the store's clock so the output is reproducible
+	clock := s.orderTime
+	holds.deadlines.now = func() time.Time { return clock }
+
+	for _, cart := range []struct {
+		id  string
+		qty int
+	}{{"cart-1", 2}, {"cart-2", 1}} {
+		if err := holds.Place(cart.id, s.harryPotter, cart.qty, 15*time.Minute); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+	fmt.Println("Held for 15 minutes, available:", holds.Available(s.harryPotter))
+
+	// cart-1 pays after 10 minutes: the hold turns into a sale
+	clock = clock.Add(10 * time.Minute)
+	if err := holds.Confirm("cart-1"); err != nil {
+		fmt.Println("Error:", err)
+	} else {
+		left, _ := holds.TTL("cart-2")
+		fmt.Printf("cart-1 paid; cart-2 has %v left\n", left)
+	}
+
+	// cart-2 never pays. The store's timer would sweep it up; with a
+	// fake clock the demo sweeps by hand.
+	clock = clock.Add(10 * time.Minute)
+	holds.Sweep()
+	fmt.Println("Available:", holds.Available(s.harryPotter))
+}
+
+func demoPacks(s *demoState) {
+	inventory := NewInventory()
+	inventory.now = func() time.Time { return s.orderTime }
+	inventory.SetPackSize(s.vogue, 10)
+	inventory.RestockIn(s.vogue, 3, UnitPack)
+	inventory.RestockIn(s.vogue, 4, UnitEach)
+	show := func(label string) {
+		fmt.Printf("%-22s %2d available = %d sealed packs + %d loose\n", label,
+			inventory.AvailableQuantity(s.vogue), inventory.SealedPacks(s.vogue), inventory.LooseUnits(s.vogue))
+	}
+	show("Received:")
+
+	// A newsagent takes a whole pack; a reader takes 6 single copies
+	inventory.ReserveIn(s.vogue, 1, UnitPack)
+	show("After 1 pack:")
+	inventory.ReserveIn(s.vogue, 6, UnitEach)
+	show("After 6 copies:")
+	for _, b := range inventory.PackBreaks(s.vogue) {
+		fmt.Printf("Opened %d pack(s) into %d copies at %s\n", b.Packs, b.Units, b.At.Format("15:04"))
+	}
+}
+
+func demoReorder(s *demoState) {
+	inventory := NewInventory()
+	clock := s.orderTime.AddDate(0, 0, -10)
+	inventory.now = func() time.Time { return clock }
+
+	// Ten days of sales: 2 books a day, 1 magazine every other day
+	inventory.Restock(s.harryPotter, 40)
+	inventory.Restock(s.vogue, 20)
+	for day := 0; day < 10; day++ {
+		clock = clock.AddDate(0, 0, 1)
+		inventory.Reserve(s.harryPotter, 2)
+		inventory.Commit(s.harryPotter, 2)
+		if day%2 == 0 {
+			inventory.Reserve(s.vogue, 1)
+			inventory.Commit(s.vogue, 1)
+		}
+	}
+
+	// The book supplier took 6 and 8 days on past orders
+	books := &Supplier{Name: "Bloomsbury", DefaultLeadTime: 5 * 24 * time.Hour}
+	books.RecordDelivery(s.orderTime.AddDate(0, -2, 0), s.orderTime.AddDate(0, -2, 6))
+	books.RecordDelivery(s.orderTime.AddDate(0, -1, 0), s.orderTime.AddDate(0, -1, 8))
+	magazines := &Supplier{Name: "Condé Nast", DefaultLeadTime: 3 * 24 * time.Hour}
+
+	planner := NewReorderPlanner(inventory)
+	planner.VelocityWindow = 10 * 24 * time.Hour
+	planner.Track(s.harryPotter, ReorderPolicy{Supplier: books, SafetyStock: 5})
+	planner.Track(s.vogue, ReorderPolicy{Supplier: magazines, SafetyStock: 2})
+	if err := PrintLowStockReport(os.Stdout, planner.LowStockReport(), itemTitle); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func demoMembership(s *demoState) {
+	members := NewMemberPricing()
+	members.SetMemberPrice(s.harryPotter, Dollars(9.99))
+	engine := NewPricingEngine(StackAll, members,
+		MembersOnly{PercentageDiscount{Percent: MustPercent(10), Category: MagazineCategoryCode}})
+
+	for _, member := range []bool{false, true} {
+		cart := Cart{Member: member}
+		cart.AddItem(s.harryPotter, 1)
+		cart.AddItem(s.vogue, 2)
+		order, err := cart.Checkout(engine, s.orderTime)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("Member: %v\n", member)
+		order.PrintReceipt(os.Stdout, itemTitle)
+	}
+}
+
+func demoQuote(s *demoState) {
+	economist := Must(NewMagazine("The Economist", Dollars(8.99), 42))
+	engine := NewPricingEngine(StackAll, BulkDiscount{MinQuantity: 10, Percent: MustPercent(5)})
+	var cart Cart
+	cart.AddItem(economist, 50)
+	quote, err := cart.Quote("Q-7", "Acme Corp", engine, s.orderTime, 30)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	quote.Print(os.Stdout, itemTitle)
+
+	// The list price goes up a week later, but the quote holds
+	economist.SetPrice(Dollars(9.99))
+	order, err := quote.ToOrder(nil, "", s.orderTime.AddDate(0, 0, 7))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Ordered at %v each, total %v (list price now %v)\n",
+		order.Lines[0].Paid, order.Total, economist.Price())
+
+	late, _ := cart.Quote("Q-8", "Acme Corp", engine, s.orderTime, 30)
+	if _, err := late.ToOrder(nil, "", s.orderTime.AddDate(0, 2, 0)); err != nil {
+		fmt.Println("Two months later:", err)
+	}
+}
+
+func demoPurchaseOrder(s *demoState) {
+	inventory := NewInventory()
+	inventory.ReceiveLot(s.harryPotter, 10, Dollars(8.00))
+
+	supplier := &Supplier{Name: "Bloomsbury", DefaultLeadTime: 5 * 24 * time.Hour}
+	po := supplier.NewPurchaseOrder("PO-1001", s.orderTime)
+	po.AddLine(s.harryPotter, 30, Dollars(9.50))
+	po.AddLine(s.vogue, 20, Dollars(2.25))
+
+	// First truck: all the magazines, half the books
+	po.Receive(inventory, s.vogue, 20, s.orderTime.AddDate(0, 0, 4))
+	po.Receive(inventory, s.harryPotter, 15, s.orderTime.AddDate(0, 0, 4))
+	po.Print(os.Stdout, itemTitle)
+	if err := po.Receive(inventory, s.harryPotter, 20, s.orderTime.AddDate(0, 0, 6)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	po.Receive(inventory, s.harryPotter, 15, s.orderTime.AddDate(0, 0, 6))
+	fmt.Printf("Status: %v, supplier lead time now %v\n", po.Status(), supplier.LeadTime())
+
+	// Selling 12 books uses up the $8.00 lot and 2 of the $9.50 ones
+	value, _ := inventory.StockValue(s.harryPotter)
+	fmt.Printf("Harry Potter stock: %d units worth %v\n", inventory.AvailableQuantity(s.harryPotter), value)
+	inventory.Reserve(s.harryPotter, 12)
+	inventory.Commit(s.harryPotter, 12)
+	value, _ = inventory.StockValue(s.harryPotter)
+	fmt.Printf("After selling 12:   %d units worth %v\n", inventory.AvailableQuantity(s.harryPotter), value)
+}
+
+func demoLocalization(s *demoState) {
+	frenchTitle := Translation{Title: "Harry Potter à l'école des sorciers"}
+	if err := s.harryPotter.SetTranslation("fr", frenchTitle); err != nil {
+		fmt.Println("Error:", err)
+	}
+	for _, lang := range ParseLanguagePreferences("fr-CH, de;q=0.9") {
+		fmt.Printf("%s: %s\n", lang, s.harryPotter.LocalizedTitle(lang))
+	}
+}
+
+func demoDealOfTheDay(s *demoState) {
+	// A function can be passed around like any other value
+	// Here pricier items get a proportionally higher chance
+	byPrice := func(item PricedItem) float64 { return item.Price().Float64() }
+	deal, err := DealOfTheDay(s.catalog.List(), currentTime(), byPrice)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	// A type assertion asks "does this value also have these methods?"
+	if named, ok := deal.(Translatable); ok {
+		fmt.Printf("Today's deal: %s (%v)\n", named.LocalizedTitle(DefaultLanguage), deal.Price())
+	}
+}
+
+func demoShipping(s *demoState) {
+	hours := DefaultStoreHours()
+	hours.AddHoliday(time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC))
+	saturdayEvening := time.Date(2024, time.March, 16, 17, 30, 0, 0, time.UTC)
+	fmt.Println("Friday 10:00:", hours.ShippingMessage(s.orderTime))
+	fmt.Println("Saturday 17:30:", hours.ShippingMessage(saturdayEvening))
+}
+
+func demoNotes(s *demoState) {
+	noteID, err := s.vogue.AddNote("sam", "damaged batch received")
+	if err == nil {
+		err = s.vogue.EditNote(noteID, "alex", "damaged batch received, 3 copies returned")
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+	for _, note := range s.vogue.Notes() {
+		fmt.Printf("#%d by %s: %s (%d earlier version)\n", note.ID, note.Author, note.Text, len(note.History))
+	}
+}
+
+func demoOverflow(s *demoState) {
+	if _, err := LineTotal(1299, math.MaxInt64/100); err != nil {
+		fmt.Println("Huge order rejected:", err)
+	}
+	if err := s.harryPotter.SetPageCount(10_000_000); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func demoPriceThrottle(s *demoState) {
+	limiter := Must(NewPriceChangeLimiter(2, time.Hour))
+	// Replace the limiter's clock so the output is reproducible
+	clock := s.orderTime
+	limiter.now = func() time.Time { return clock }
+
+	for _, price := range []Money{Dollars(11.99), Dollars(10.99), Dollars(9.99)} {
+		err := limiter.SetPrice(s.harryPotter, price)
+		// errors.As finds an error of the given type in the chain
+		// and stores it in throttled, like Python's "except X as e"
+		var throttled *PriceChangeThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			fmt.Printf("%v rejected, retry in %v\n", price, throttled.RetryAfter)
+		case err != nil:
+			fmt.Println("Error:", err)
+		default:
+			fmt.Printf("%v accepted\n", price)
+		}
+		clock = clock.Add(10 * time.Minute)
+	}
+	// Put the price back for the following steps
+	s.harryPotter.SetPrice(Dollars(12.99))
+}
+
+func demoPriceHistory(s *demoState) {
+	if err := s.harryPotter.SetPriceBecause(Dollars(9.99), "weekend promotion"); err != nil {
+		fmt.Println("Error:", err)
+	}
+	if err := s.harryPotter.SetPriceBecause(Dollars(12.99), "promotion over"); err != nil {
+		fmt.Println("Error:", err)
+	}
+	for _, c := range s.harryPotter.PriceHistory() {
+		reason := c.Reason
+		if reason == "" {
+			reason = "(no reason given)"
+		}
+		fmt.Printf("%v -> %v: %s\n", c.Old, c.New, reason)
+	}
+	// A "was" price must not be higher than this (EU Omnibus rule)
+	fmt.Println("Lowest price in the last 30 days:", LowestPrice(s.harryPotter, 30))
+}
+
+func demoEvents(s *demoState) {
+	// Three listeners: a console log, the notification hub and a counter
+	counter := &EventCounter{}
+	unsubscribe := []func(){
+		Events.SubscribeAll(LogEvents(os.Stdout)),
+		Events.SubscribeAll(NotifyEvents(s.notifications)),
+		Events.SubscribeAll(counter.Handle),
+		// Subscribe picks out one event type, with its fields
+		Subscribe(Events, func(e OrderPlaced) {
+			fmt.Printf("Thank-you email queued for an order of %v\n", e.Order.Total)
+		}),
+	}
+	// Stop listening at the end, so later steps run quietly
+	defer func() {
+		for _, fn := range unsubscribe {
+			fn()
+		}
+	}()
+
+	book := Must(NewBook("The Hobbit", "J.R.R. Tolkien", Dollars(14.99), ""))
+	book.SetPriceBecause(Dollars(11.99), "clearance")
+	inventory := NewInventory()
+	inventory.now = func() time.Time { return s.orderTime }
+	inventory.Restock(book, 2)
+	inventory.Reserve(book, 2)
+	cart := &Cart{}
+	cart.AddItem(book, 2)
+	if _, err := cart.Checkout(NewPricingEngine(StackAll), s.orderTime); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	counts := counter.Counts()
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Printf("%s: %d\n", name, counts[name])
+	}
+}
+
+// crashingOutbox fails to mark the first message it is asked to, like
+// a relay that dies right after publishing
+type crashingOutbox struct {
+	OutboxStore
+	crashed bool
+}
+
+func (o *crashingOutbox) MarkPublished(id int64) error {
+	if !o.crashed {
+		o.crashed = true
+		return errors.New("relay crashed")
+	}
+	return o.OutboxStore.MarkPublished(id)
+}
+
+func demoOutbox(s *demoState) {
+	repo := NewMemoryRepository()
+	repo.Create("BK-042", Must(NewBook("The Hobbit", "J.R.R. Tolkien", Dollars(14.99), "")))
+
+	deliveries := 0
+	unsubscribe := []func(){
+		Events.SubscribeAll(func(e Event) { deliveries++ }),
+		// The listener that matters only hears each change once
+		Events.SubscribeAll(DedupEvents(LogEvents(os.Stdout))),
+	}
+	defer func() {
+		for _, fn := range unsubscribe {
+			fn()
+		}
+	}()
+
+	for _, price := range []Money{Dollars(12.99), Dollars(11.99)} {
+		if err := repo.SetPrice("BK-042", price, "clearance"); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+	pending, _ := repo.Pending(DefaultOutboxBatch)
+	fmt.Println("Saved; events waiting in the outbox:", len(pending))
+
+	relay := &OutboxRelay{Store: &crashingOutbox{OutboxStore: repo}, Bus: Events, Lookup: repo.FindByID}
+	if _, err := relay.Flush(); err != nil {
+		fmt.Println("First run:", err)
+	}
+	// The restarted relay sends the first event again
+	n, err := relay.Flush()
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+	pending, _ = repo.Pending(DefaultOutboxBatch)
+	fmt.Printf("Second run published %d; %d deliveries in all, %d left in the outbox\n", n, deliveries, len(pending))
+}
+
+func demoCachedRepository(s *demoState) {
+	repo := NewMemoryRepository()
+	repo.Create("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), "")))
+	repo.Create("BK-002", Must(NewBook("Emma", "Jane Austen", Dollars(7.99), "")))
+	// A deployment sizes the cache from BOOKSTORE_CACHE_SIZE; the demo
+	// keeps room for one item at most, to show eviction
+	cfg, err := CacheConfigFromEnv()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	cfg.Size = min(cfg.Size, 1)
+	cached := NewCachedRepository(repo, cfg)
+	defer cached.Close()
+
+	read := func(sku string) {
+		before := cached.Stats().Hits
+		item, err := cached.FindByID(sku)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		source := "repository"
+		if cached.Stats().Hits > before {
+			source = "cache"
+		}
+		fmt.Printf("  %s: %s from the %s\n", sku, itemTitle(item), source)
+	}
+	read("BK-001")
+	read("BK-001")
+	read("BK-002") // pushes BK-001 out
+	read("BK-001")
+
+	// A write and a sync both make the cached copy stale
+	if err := cached.Update("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(8.99), ""))); err != nil {
+		fmt.Println("Error:", err)
+	}
+	read("BK-001")
+	read("BK-001")
+	Events.Publish(ItemsSynced{SKUs: []string{"BK-001"}})
+	read("BK-001")
+	fmt.Println("Stats:", cached.Stats())
+}
+
+func demoStoreSale(s *demoState) {
+	sale := &StoreSale{}
+	sale.OnChange(func(e SaleEvent) {
+		subject := "Sale over"
+		if e.Active {
+			subject = fmt.Sprintf("Sale on: %v off!", e.Discount)
+		}
+		if err := s.notifications.Send(KindSale, subject, ""); err != nil {
+			fmt.Println("Error:", err)
+		}
+	})
+
+	extra := Must(NewBook("Go Programming", "Alan Donovan", Dollars(40), ""))
+	sale.Activate(SaleConfig{
+		Discount: MustPercent(25),
+		Blackout: []PricedItem{s.harryPotter},
+		Floors:   map[PricedItem]Money{extra: Dollars(32)},
+	})
+	for _, item := range []PricedItem{s.harryPotter, s.vogue, extra} {
+		fmt.Printf("%v -> %v\n", item.Price(), sale.Price(item))
+	}
+	sale.Deactivate()
+}
+
+func demoPriceSources(s *demoState) {
+	quotes := []PriceQuote{
+		{"publisher", 12.99},
+		{"wholesaler", 12.49},
+		{"marketplace", 13.25},
+		{"broken-feed", 0.01},
+	}
+	agg, err := AggregatePrices(quotes, DefaultOutlierK)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Reference price: $%.2f from %d sources\n", agg.Reference, len(agg.Accepted))
+	for _, r := range agg.Rejected {
+		fmt.Printf("Rejected %s: %s\n", r.Source, r.Reason)
+	}
+}
+
+func demoRepricing(s *demoState) {
+	repricer := NewRepricer(Dollars(0.10))
+	repricer.OptIn(s.harryPotter, Dollars(11.00))
+	repricer.OptIn(s.vogue, Dollars(12.50))
+	quotes := map[PricedItem][]PriceQuote{
+		s.harryPotter: {{"bookshop", 12.49}, {"megastore", 12.79}, {"broken-feed", 0.99}},
+		s.vogue:       {{"newsstand", 11.99}, {"kiosk", 12.25}},
+	}
+	// A dry run reports the plan without touching any price
+	report := repricer.Run(quotes, true)
+	if err := report.Print(os.Stdout, itemTitle); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func demoDemandPricing(s *demoState) {
+	inventory := NewInventory()
+	inventory.now = func() time.Time { return s.orderTime }
+	bestseller := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	slowSeller := Must(NewBook("Moby Dick", "Herman Melville", Dollars(14.99), ""))
+	// 8 of 10 copies of Dune sold, but only 1 of 40 of Moby Dick
+	for _, stock := range []struct {
+		item           PricedItem
+		received, sold int
+	}{{bestseller, 10, 8}, {slowSeller, 40, 1}} {
+		if err := inventory.Restock(stock.item, stock.received); err != nil {
+			fmt.Println("Error:", err)
+		}
+		if err := inventory.Reserve(stock.item, stock.sold); err != nil {
+			fmt.Println("Error:", err)
+		}
+		if err := inventory.Commit(stock.item, stock.sold); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+
+	pricer := Must(NewDemandPricer(inventory, MustPercent(5), 7, 60))
+	pricer.Confirm = 2
+	if err := pricer.OptIn(bestseller, Dollars(8.99), Dollars(10.99)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	if err := pricer.OptIn(slowSeller, Dollars(12.99), Dollars(16.99)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	// The first run only notices the trend; the second one acts on it
+	for run := 1; run <= 2; run++ {
+		fmt.Printf("Run %d:\n", run)
+		if err := pricer.Run(false).Print(os.Stdout, itemTitle); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+	for _, c := range slowSeller.PriceHistory() {
+		fmt.Printf("Moby Dick history: %v -> %v (%s)\n", c.Old, c.New, c.Reason)
+	}
+}
+
+func demoAuthorization(s *demoState) {
+	authz := NewAuthorizer()
+	authz.now = func() time.Time { return s.orderTime }
+	clerk := Actor{Name: "sam", Role: RoleClerk}
+	admin := Actor{Name: "alex", Role: RoleAdmin}
+
+	if err := authz.Authorize(clerk, PermEditPrices, "change the price of BK-001"); err != nil {
+		fmt.Println("Denied:", err)
+	}
+	// The admin sees the store through the clerk's eyes
+	asClerk, err := authz.Impersonate(admin, clerk)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := authz.Authorize(asClerk, PermAdjustStock, "restock MG-001"); err == nil {
+		fmt.Println("Allowed:", asClerk, "may restock MG-001")
+	}
+
+	fmt.Println("Audit log:")
+	for _, e := range authz.AuditLog() {
+		verdict := "denied"
+		if e.Allowed {
+			verdict = "allowed"
+		}
+		fmt.Printf("  %s %s: %s -> %s\n", e.At.Format(time.Kitchen), e.Actor, e.Action, verdict)
+	}
+}
+
+func demoCommission(s *demoState) {
+	rateCard := DefaultRateCard(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	for _, tier := range []SellerTier{TierStandard, TierGold} {
+		bookCut, err := rateCard.Commission(tier, s.harryPotter, s.orderTime)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		magazineCut, err := rateCard.Commission(tier, s.vogue, s.orderTime)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Printf("%s seller: book %v, magazine %v\n", tier, bookCut, magazineCut)
+	}
+}
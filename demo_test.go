@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test -run TestDemoGolden -update rewrites the golden file after an
+// intended change to the demo's output; review it with git diff
+var update = flag.Bool("update", false, "rewrite testdata/*.golden from the current output")
+
+// demoChildEnv marks the copy of the test binary that plays the demo
+const demoChildEnv = "BOOKSTORE_DEMO_CHILD"
+
+// TestDemoGolden compares "go run . -deterministic" with
+// testdata/demo.golden. Deterministic mode changes package-level state
+// (the clock, the random source), so the demo runs in a fresh copy of
+// the test binary rather than next to the other tests.
+func TestDemoGolden(t *testing.T) {
+	if os.Getenv(demoChildEnv) != "" {
+		EnableDeterministicMode()
+		runDemo(0)
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDemoGolden$")
+	cmd.Env = append(os.Environ(), demoChildEnv+"=1")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the demo: %v", err)
+	}
+	// The child's own test framework adds a trailing PASS line
+	got := strings.TrimSuffix(string(out), "PASS\n")
+
+	golden := filepath.Join("testdata", "demo.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got == string(want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Fatalf("demo output differs from %s at line %d:\n got: %q\nwant: %q\n(run with -update if the change is intended)", golden, i+1, g, w)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+// ------------------- DETERMINISTIC MODE ----------------------
+// Random page counts, "today's" deal and timestamps make every run a
+// little different. That is realistic, but it gets in the way when
+// comparing output: the example output at the end of main.go, a diff
+// between two versions, the golden file TestDemoGolden checks against
+// (see demo_test.go).
+//
+//	go run . -deterministic
+//	BOOKSTORE_DETERMINISTIC=1 go run .
+//
+// In deterministic mode:
+//   - the random generator gets a fixed seed
+//   - the clock is frozen at FrozenTime
+//   - background jobs (Repricer.Schedule, DemandPricer.Schedule) don't start
+//
+// This works because nothing calls time.Now or the global math/rand
+// functions directly. Types with a clock default their now field to
+// currentTime, and random values come from randomIntn.
+
+import (
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeterministicEnv turns deterministic mode on when set to a non-empty
+// value, for runs where adding a flag is awkward (CI scripts)
+const DeterministicEnv = "BOOKSTORE_DETERMINISTIC"
+
+// DeterministicSeed seeds the random generator in deterministic mode
+const DeterministicSeed = 1
+
+// FrozenTime is the time in deterministic mode; it matches the demo's
+// order time
+var FrozenTime = time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+
+var (
+	// clock is what currentTime returns the time from
+	clock = time.Now
+	// backgroundJobs is false in deterministic mode
+	backgroundJobs = true
+
+	// A *rand.Rand is not safe for concurrent use, hence the mutex
+	randomMu sync.Mutex
+	random   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// EnableDeterministicMode freezes time and randomness. Call it before
+// anything random happens, i.e. first thing in main.
+func EnableDeterministicMode() {
+	clock = func() time.Time { return FrozenTime }
+	backgroundJobs = false
+	randomMu.Lock()
+	random = rand.New(rand.NewSource(DeterministicSeed))
+	randomMu.Unlock()
+}
+
+// deterministicRequested reports whether the environment asks for
+// deterministic mode
+func deterministicRequested() bool {
+	return os.Getenv(DeterministicEnv) != ""
+}
+
+// currentTime is the program's time.Now
+func currentTime() time.Time {
+	return clock()
+}
+
+// randomIntn returns a random int in [0, n), like rand.Intn
+func randomIntn(n int) int {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	return random.Intn(n)
+}
@@ -0,0 +1,269 @@
+package main
+
+// ------------------- DISCOUNT POLICIES -----------------------
+// Each kind of discount is a small type implementing DiscountPolicy.
+// A PricingEngine holds a list of policies and a stacking rule that
+// says how they combine. New discounts need no change to Book or
+// Magazine - this is the "strategy pattern", done with an interface.
+//
+// In Python you'd write an ABC with an abstract apply(); in Go any type
+// with the right methods qualifies, no registration needed.
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DiscountContext is everything a policy may look at
+type DiscountContext struct {
+	Item     PricedItem
+	Quantity int
+	At       time.Time
+	// Basket is every item in the same cart, Item included; nil when
+	// pricing a single item
+	Basket []PricedItem
+	// Member is true for members of the loyalty club (see membership.go)
+	Member bool
+	// Coupons are the codes the customer entered
+	Coupons []string
+}
+
+// DiscountPolicy computes a discounted unit price.
+// ok is false when the policy does not apply; price is then ignored.
+type DiscountPolicy interface {
+	Name() string
+	Apply(price Money, ctx DiscountContext) (discounted Money, ok bool)
+}
+
+// PercentageDiscount takes a percentage off, optionally only for one
+// category and only for items priced above MinPrice
+type PercentageDiscount struct {
+	Percent  Percent
+	Category string // "" means every category
+	MinPrice Money  // 0 means no threshold
+}
+
+func (d PercentageDiscount) Name() string {
+	name := fmt.Sprintf("%v off", d.Percent)
+	if d.Category != "" {
+		name += " " + d.Category
+	}
+	if !d.MinPrice.IsZero() {
+		name += fmt.Sprintf(" over %v", d.MinPrice)
+	}
+	return name
+}
+
+func (d PercentageDiscount) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	if d.Category != "" && categoryOf(ctx.Item) != d.Category {
+		return price, false
+	}
+	// Less is false across currencies, so a threshold in another
+	// currency keeps the discount off rather than guessing
+	if !d.MinPrice.IsZero() && !d.MinPrice.Less(price) {
+		return price, false
+	}
+	return price.Off(d.Percent), true
+}
+
+// BulkDiscount rewards buying at least MinQuantity units at once
+type BulkDiscount struct {
+	MinQuantity int
+	Percent     Percent
+}
+
+func (d BulkDiscount) Name() string {
+	return fmt.Sprintf("%v off %d+ units", d.Percent, d.MinQuantity)
+}
+
+func (d BulkDiscount) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	if ctx.Quantity < d.MinQuantity {
+		return price, false
+	}
+	return price.Off(d.Percent), true
+}
+
+// Coupon takes Percent off when the customer entered Code; codes are
+// not case-sensitive
+type Coupon struct {
+	Code    string
+	Percent Percent
+}
+
+func (c Coupon) Name() string {
+	return fmt.Sprintf("coupon %s (%v off)", c.Code, c.Percent)
+}
+
+func (c Coupon) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	entered := slices.ContainsFunc(ctx.Coupons, func(code string) bool {
+		return strings.EqualFold(code, c.Code)
+	})
+	if !entered {
+		return price, false
+	}
+	return price.Off(c.Percent), true
+}
+
+// SeasonalDiscount applies between Start (inclusive) and End (exclusive)
+type SeasonalDiscount struct {
+	Label      string
+	Start, End time.Time
+	Percent    Percent
+}
+
+func (d SeasonalDiscount) Name() string {
+	return fmt.Sprintf("%s (%v off)", d.Label, d.Percent)
+}
+
+func (d SeasonalDiscount) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	if ctx.At.Before(d.Start) || !ctx.At.Before(d.End) {
+		return price, false
+	}
+	return price.Off(d.Percent), true
+}
+
+// categoryOf returns the item's category, or "" if it has none
+func categoryOf(item PricedItem) string {
+	if c, ok := item.(Categorized); ok {
+		return c.Category()
+	}
+	return ""
+}
+
+// ------------------- PRICING ENGINE --------------------------
+
+// StackingRule says how several applicable policies combine when, say,
+// a coupon, a store sale, a member price and a bulk tier all apply
+type StackingRule int
+
+const (
+	// StackAll applies every policy in order, each to the previous result
+	StackAll StackingRule = iota
+	// BestOnly applies only the policy giving the lowest price: best
+	// for the customer
+	BestOnly
+	// SmallestOnly applies only the policy giving the highest price,
+	// i.e. the smallest discount: best for the store
+	SmallestOnly
+	// StackCapped adds up what each policy takes off the base price,
+	// but never more than the engine's MaxDiscount of it (if set)
+	StackCapped
+)
+
+// stackingRuleNames are the names used in configuration
+var stackingRuleNames = map[StackingRule]string{
+	StackAll:     "stack-all",
+	BestOnly:     "best-for-customer",
+	SmallestOnly: "best-for-store",
+	StackCapped:  "additive-with-cap",
+}
+
+func (r StackingRule) String() string {
+	if name, ok := stackingRuleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("StackingRule(%d)", int(r))
+}
+
+// ParseStackingRule reads a rule name as printed by String
+func ParseStackingRule(s string) (StackingRule, error) {
+	for rule, name := range stackingRuleNames {
+		if s == name {
+			return rule, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown stacking rule %q", s)
+}
+
+// PricingResult explains how a unit price was reached
+type PricingResult struct {
+	Base    Money
+	Final   Money
+	Applied []string // names of the policies that were used
+	// Capped is true when StackCapped cut the discount short, at
+	// MaxDiscount or at the price itself
+	Capped bool
+}
+
+// PricingEngine combines discount policies
+type PricingEngine struct {
+	Stacking StackingRule
+	// MaxDiscount caps the total discount under StackCapped; zero means
+	// no cap, since a 0% cap would just switch the discounts off. The
+	// other rules ignore it.
+	MaxDiscount Percent
+	policies    []DiscountPolicy
+}
+
+// NewPricingEngine creates an engine with the given rule and policies
+// (the ...DiscountPolicy parameter is variadic, like Python's *args)
+func NewPricingEngine(stacking StackingRule, policies ...DiscountPolicy) *PricingEngine {
+	return &PricingEngine{Stacking: stacking, policies: policies}
+}
+
+// AddPolicy appends a policy; with StackAll, order matters
+func (e *PricingEngine) AddPolicy(p DiscountPolicy) {
+	e.policies = append(e.policies, p)
+}
+
+// Price computes the discounted unit price for ctx.Item. Every rule is
+// resolved here, so policies never need to know about each other.
+func (e *PricingEngine) Price(ctx DiscountContext) PricingResult {
+	base := ctx.Item.Price()
+	result := PricingResult{Base: base, Final: base}
+	switch e.Stacking {
+	case BestOnly:
+		for _, p := range e.policies {
+			if price, ok := p.Apply(base, ctx); ok && price.Less(result.Final) {
+				result.Final = price
+				result.Applied = []string{p.Name()}
+			}
+		}
+	case SmallestOnly:
+		for _, p := range e.policies {
+			// The first policy that applies sets the price; later ones
+			// only replace it with a higher one
+			if price, ok := p.Apply(base, ctx); ok && (result.Applied == nil || result.Final.Less(price)) {
+				result.Final = price
+				result.Applied = []string{p.Name()}
+			}
+		}
+	case StackCapped:
+		var sum lineSum
+		for _, p := range e.policies {
+			price, ok := p.Apply(base, ctx)
+			if !ok {
+				continue
+			}
+			saved, err := base.Sub(price)
+			if err != nil {
+				continue // a policy in another currency can't be added up
+			}
+			if err := sum.add(saved, 1); err != nil {
+				continue
+			}
+			result.Applied = append(result.Applied, p.Name())
+		}
+		// Never more than the price itself, however the policies add up
+		off, limit := sum.total, base
+		if e.MaxDiscount.Value() > 0 {
+			limit = base.Portion(e.MaxDiscount)
+		}
+		if limit.Less(off) {
+			off, result.Capped = limit, true
+		}
+		if final, err := base.Sub(off); err == nil && !off.IsZero() {
+			result.Final = final
+		}
+	default:
+		for _, p := range e.policies {
+			if price, ok := p.Apply(result.Final, ctx); ok {
+				result.Final = price
+				result.Applied = append(result.Applied, p.Name())
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestPercentageDiscountThreshold(t *testing.T) {
+	euros := func(minor int64) Money { return Must(NewMoney(minor, "EUR")) }
+	tests := []struct {
+		name     string
+		minPrice Money
+		price    Money
+		want     Money
+		applied  bool
+	}{
+		{"no threshold, dollars", Money{}, Dollars(10), Dollars(8), true},
+		// A zero threshold is in no currency: it must not block euros
+		{"no threshold, euros", Money{}, euros(1000), euros(800), true},
+		{"above the threshold", Dollars(5), Dollars(10), Dollars(8), true},
+		{"at the threshold", Dollars(10), Dollars(10), Dollars(10), false},
+		{"below the threshold", Dollars(15), Dollars(10), Dollars(10), false},
+		{"euro threshold, euro price", euros(500), euros(1000), euros(800), true},
+		// Can't tell whether €10 is over $5, so no discount
+		{"dollar threshold, euro price", Dollars(5), euros(1000), euros(1000), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := PercentageDiscount{Percent: MustPercent(20), MinPrice: tt.minPrice}
+			item := Must(NewBook("Faust", "Goethe", tt.price, ""))
+			got, ok := d.Apply(tt.price, DiscountContext{Item: item, Quantity: 1})
+			assert.Equal(t, got, tt.want)
+			assert.Equal(t, ok, tt.applied)
+		})
+	}
+}
+
+func TestPricingEngineStacking(t *testing.T) {
+	coupon := Coupon{Code: "SPRING10", Percent: MustPercent(10)}
+	sale := PercentageDiscount{Percent: MustPercent(20)}
+	bulk := BulkDiscount{MinQuantity: 10, Percent: MustPercent(5)}
+	sixty := PercentageDiscount{Percent: MustPercent(60)}
+	all := []string{coupon.Name(), sale.Name(), bulk.Name()}
+	// Every policy applies to 10 units with the coupon; only the sale to 1
+	every := DiscountContext{Quantity: 10, Coupons: []string{"spring10"}}
+	one := DiscountContext{Quantity: 1}
+
+	tests := []struct {
+		name        string
+		rule        StackingRule
+		maxDiscount float64
+		policies    []DiscountPolicy
+		ctx         DiscountContext
+		want        Money
+		applied     []string
+		capped      bool
+	}{
+		{"stack all", StackAll, 0, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(68.40), all, false},
+		{"stack all, one applies", StackAll, 0, []DiscountPolicy{coupon, sale, bulk}, one, Dollars(80), []string{sale.Name()}, false},
+		{"stack all, none", StackAll, 0, nil, every, Dollars(100), nil, false},
+
+		{"best only", BestOnly, 0, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(80), []string{sale.Name()}, false},
+		{"best only, one applies", BestOnly, 0, []DiscountPolicy{coupon, sale, bulk}, one, Dollars(80), []string{sale.Name()}, false},
+		{"best only, none", BestOnly, 0, nil, every, Dollars(100), nil, false},
+
+		{"smallest only", SmallestOnly, 0, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(95), []string{bulk.Name()}, false},
+		{"smallest only, one applies", SmallestOnly, 0, []DiscountPolicy{coupon, sale, bulk}, one, Dollars(80), []string{sale.Name()}, false},
+		{"smallest only, none", SmallestOnly, 0, nil, every, Dollars(100), nil, false},
+
+		// 10% + 20% + 5% of the base price is 35% off
+		{"capped, no cap set", StackCapped, 0, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(65), all, false},
+		{"capped at 25%", StackCapped, 25, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(75), all, true},
+		{"capped at exactly the total", StackCapped, 35, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(65), all, false},
+		{"capped at 100%", StackCapped, 100, []DiscountPolicy{coupon, sale, bulk}, every, Dollars(65), all, false},
+		{"capped, under the cap", StackCapped, 25, []DiscountPolicy{coupon, sale, bulk}, one, Dollars(80), []string{sale.Name()}, false},
+		{"capped, none", StackCapped, 25, nil, every, Dollars(100), nil, false},
+		// 60% + 60% is more than the price: free, not a refund
+		{"capped at the price, no cap set", StackCapped, 0, []DiscountPolicy{sixty, sixty}, one, Dollars(0), []string{sixty.Name(), sixty.Name()}, true},
+		{"capped at the price", StackCapped, 100, []DiscountPolicy{sixty, sixty}, one, Dollars(0), []string{sixty.Name(), sixty.Name()}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewPricingEngine(tt.rule, tt.policies...)
+			engine.MaxDiscount = MustPercent(tt.maxDiscount)
+			ctx := tt.ctx
+			ctx.Item = Must(NewBook("Dune", "Frank Herbert", Dollars(100), ""))
+			got := engine.Price(ctx)
+			assert.Equal(t, got.Base, Dollars(100))
+			assert.Equal(t, got.Final, tt.want)
+			assert.Equal(t, got.Applied, tt.applied)
+			assert.Equal(t, got.Capped, tt.capped)
+		})
+	}
+}
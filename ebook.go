@@ -0,0 +1,171 @@
+package main
+
+// ------------------- E-BOOKS ---------------------------------
+// EBook is a third PricedItem. Nothing that works with PricedItem had
+// to change to accept it: the catalog, cart, JSON store and HTTP API
+// just work, which is the point of interfaces.
+//
+// What is different about e-books:
+//   - they are files: a format (EPUB, PDF, MOBI), a size and maybe DRM
+//   - they never sell out (see InStock in inventory.go)
+//   - bought together with their paper edition, the e-book is cheaper
+//     (EditionBundleDiscount below)
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EBookCategoryCode is the category of every e-book
+const EBookCategoryCode = "EBOOK"
+
+// EBookFormat is the file format an e-book is sold in
+type EBookFormat int
+
+const (
+	FormatEPUB EBookFormat = iota
+	FormatPDF
+	FormatMOBI
+)
+
+func (f EBookFormat) String() string {
+	switch f {
+	case FormatEPUB:
+		return "EPUB"
+	case FormatPDF:
+		return "PDF"
+	case FormatMOBI:
+		return "MOBI"
+	default:
+		return fmt.Sprintf("EBookFormat(%d)", int(f))
+	}
+}
+
+// ParseEBookFormat reads "epub", "PDF", ... in any case
+func ParseEBookFormat(s string) (EBookFormat, error) {
+	for _, f := range []EBookFormat{FormatEPUB, FormatPDF, FormatMOBI} {
+		if strings.EqualFold(s, f.String()) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("e-book format must be EPUB, PDF or MOBI, not %q", s)
+}
+
+// MaxEBookSize rejects sizes that are surely a unit mix-up (bytes vs MB)
+const MaxEBookSize = 2 << 30 // 2 GiB
+
+type EBook struct {
+	title    string
+	author   string
+	price    Money
+	format   EBookFormat
+	fileSize int64 // bytes
+	// DRM-protected files only open in approved reader apps
+	DRM         bool
+	Description string
+	// PaperEdition is the printed version, if the store sells one
+	PaperEdition *Book
+	translations
+	annotations
+	priceLog
+}
+
+// NewEBook returns an e-book without DRM; fileSize is in bytes
+func NewEBook(title, author string, price Money, format EBookFormat, fileSize int64) (*EBook, error) {
+	e := &EBook{
+		title:    title,
+		author:   author,
+		price:    price,
+		format:   format,
+		fileSize: fileSize,
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *EBook) Summary() string {
+	return fmt.Sprintf("%s by %s (%v, %s) - %v", e.title, e.author, e.format, formatFileSize(e.fileSize), e.price)
+}
+
+func (e *EBook) Category() string {
+	return EBookCategoryCode
+}
+
+func (e *EBook) Price() Money {
+	return e.price
+}
+
+func (e *EBook) SetPrice(price Money) error {
+	return e.SetPriceBecause(price, "")
+}
+
+func (e *EBook) SetPriceBecause(price Money, reason string) error {
+	if price.IsNegative() {
+		return ErrNegativePrice
+	}
+	e.recordPrice(e, e.price, price, reason)
+	e.price = price
+	return nil
+}
+
+func (e *EBook) CalculateDiscount(percentage Percent) (Money, error) {
+	discounted, _ := PercentageDiscount{Percent: percentage}.Apply(e.price, DiscountContext{Item: e})
+	return discounted, nil
+}
+
+func (e *EBook) Format() EBookFormat {
+	return e.format
+}
+
+// FileSize is the download size in bytes
+func (e *EBook) FileSize() int64 {
+	return e.fileSize
+}
+
+func (e *EBook) SetFileSize(size int64) error {
+	if size <= 0 || size > MaxEBookSize {
+		return fmt.Errorf("file size must be between 1 byte and %s", formatFileSize(MaxEBookSize))
+	}
+	e.fileSize = size
+	return nil
+}
+
+// formatFileSize prints a byte count the way download pages do: "2.4 MB"
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, prefix := float64(size)/unit, 0
+	for value >= unit && prefix < 2 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMG"[prefix])
+}
+
+// EditionBundleDiscount takes Percent off an e-book when its paper
+// edition is bought in the same order
+type EditionBundleDiscount struct {
+	Percent Percent
+}
+
+func (d EditionBundleDiscount) Name() string {
+	return fmt.Sprintf("%v off with the paper edition", d.Percent)
+}
+
+func (d EditionBundleDiscount) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	ebook, ok := ctx.Item.(*EBook)
+	if !ok || ebook.PaperEdition == nil {
+		return price, false
+	}
+	for _, other := range ctx.Basket {
+		// Pointer comparison: the very same Book, not an equal copy
+		if other == PricedItem(ebook.PaperEdition) {
+			return price.Off(d.Percent), true
+		}
+	}
+	return price, false
+}
@@ -0,0 +1,33 @@
+package main
+
+// ------------------- ERROR TYPES -----------------------------
+// Errors built with fmt.Errorf("...") can only be told apart by their
+// text, which breaks as soon as a message is reworded. Errors callers
+// need to react to get a name instead:
+//
+//   - a sentinel value, like ErrNegativePrice, for errors with no
+//     details; test with errors.Is(err, ErrNegativePrice)
+//   - a type, like ItemNotFoundError, for errors that carry data; get
+//     at it with errors.As, like "except ItemNotFound as e" in Python
+//
+// Both still work after the error is wrapped with %w along the way.
+// Sentinels that belong to one feature live next to it, e.g.
+// ErrOverflow in checked_math.go and ErrInvalidPercentage in percent.go.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNegativePrice is returned for a price below zero, by SetPrice,
+// the constructors and the JSON decoders
+var ErrNegativePrice = errors.New("price cannot be negative")
+
+// ItemNotFoundError means no item is registered under SKU
+type ItemNotFoundError struct {
+	SKU string
+}
+
+func (e *ItemNotFoundError) Error() string {
+	return fmt.Sprintf("item %q not found", e.SKU)
+}
@@ -0,0 +1,209 @@
+package main
+
+// ------------------- DOMAIN EVENTS ---------------------------
+// Things that happen in the store - a price changes, stock runs out, an
+// order is placed - are published as typed events on an EventBus.
+// Whoever cares subscribes: a logger, the notification hub, a metrics
+// counter. The code publishing an event knows none of them, the same
+// decoupling as Django signals or blinker in Python.
+//
+//	unsubscribe := Subscribe(Events, func(e PriceChanged) { ... })
+//	defer unsubscribe()
+//
+// Subscribe is generic, so a listener gets the concrete event type and
+// needs no type assertion. SubscribeAll receives every event.
+//
+// Items can't be handed a bus (JSON decoding creates them, for one), so
+// the store publishes on the package-level Events bus, in the same way
+// net/http has DefaultServeMux. Listeners run synchronously, in
+// subscription order, on the publishing goroutine.
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is something that happened; EventName is e.g. "price-changed".
+// The event types below also have a String method for logging.
+type Event interface {
+	EventName() string
+}
+
+// PriceChanged is published by SetPrice and SetPriceBecause
+type PriceChanged struct {
+	Item     PricedItem
+	Old, New Money
+	Reason   string
+	At       time.Time
+	// Key is set on events relayed from an outbox; a redelivered event
+	// has the same Key as the first delivery
+	Key string
+}
+
+func (e PriceChanged) EventName() string { return "price-changed" }
+
+func (e PriceChanged) String() string {
+	s := fmt.Sprintf("%s: %v -> %v", itemTitle(e.Item), e.Old, e.New)
+	if e.Reason != "" {
+		s += " (" + e.Reason + ")"
+	}
+	return s
+}
+
+// Restocked is published when units arrive in an Inventory
+type Restocked struct {
+	Item      PricedItem
+	Quantity  int
+	Available int // after the delivery
+	At        time.Time
+}
+
+func (e Restocked) EventName() string { return "restocked" }
+
+func (e Restocked) String() string {
+	return fmt.Sprintf("%s: +%d, %d available", itemTitle(e.Item), e.Quantity, e.Available)
+}
+
+// StockDepleted is published when the last available unit of an item
+// is reserved or written off
+type StockDepleted struct {
+	Item     PricedItem
+	Reserved int // units still set aside for orders
+	At       time.Time
+}
+
+func (e StockDepleted) EventName() string { return "stock-depleted" }
+
+func (e StockDepleted) String() string {
+	return fmt.Sprintf("%s: none available, %d reserved", itemTitle(e.Item), e.Reserved)
+}
+
+// OrderPlaced is published by Cart.Checkout and Quote.ToOrder
+type OrderPlaced struct {
+	Order *Order
+}
+
+func (e OrderPlaced) EventName() string { return "order-placed" }
+
+func (e OrderPlaced) String() string {
+	return fmt.Sprintf("%d lines, total %v", len(e.Order.Lines), e.Order.Total)
+}
+
+// ItemsSynced is published when Catalog.Repair replaces or removes
+// items to match another catalog
+type ItemsSynced struct {
+	SKUs []string
+}
+
+func (e ItemsSynced) EventName() string { return "items-synced" }
+
+func (e ItemsSynced) String() string {
+	return strings.Join(e.SKUs, ", ")
+}
+
+// EventBus delivers published events to subscribers. It is safe for
+// concurrent use; the zero value is not, use NewEventBus.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Event)
+	nextID   int
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[int]func(Event))}
+}
+
+// Events is the bus the store's own code publishes on
+var Events = NewEventBus()
+
+// SubscribeAll calls fn for every event until unsubscribe is called
+func (b *EventBus) SubscribeAll(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.handlers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Subscribe calls fn for every event of type E. It's a function, not
+// a method, because Go methods can't have type parameters.
+func Subscribe[E Event](b *EventBus, fn func(E)) (unsubscribe func()) {
+	return b.SubscribeAll(func(e Event) {
+		if typed, ok := e.(E); ok {
+			fn(typed)
+		}
+	})
+}
+
+// Publish calls every subscriber with e. The handlers are copied first,
+// so a handler may subscribe, unsubscribe or publish without deadlock.
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	ids := slices.Sorted(maps.Keys(b.handlers))
+	handlers := make([]func(Event), len(ids))
+	for i, id := range ids {
+		handlers[i] = b.handlers[id]
+	}
+	b.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
+
+// ------------------- LISTENERS -------------------------------
+
+// LogEvents returns a handler printing each event as one line
+func LogEvents(w io.Writer) func(Event) {
+	return func(e Event) {
+		fmt.Fprintf(w, "[event] %s: %v\n", e.EventName(), e)
+	}
+}
+
+// NotifyEvents returns a handler turning price drops and sold-out
+// items into notifications
+func NotifyEvents(hub *NotificationHub) func(Event) {
+	return func(e Event) {
+		// A handler can't return an error, and the hub has already
+		// retried, so a failed delivery is dropped
+		switch e := e.(type) {
+		case PriceChanged:
+			if e.New.Less(e.Old) {
+				hub.Send(KindPriceDrop, fmt.Sprintf("%s now %v", itemTitle(e.Item), e.New), "")
+			}
+		case StockDepleted:
+			hub.Send(KindLowStock, itemTitle(e.Item)+" is sold out", "")
+		}
+	}
+}
+
+// EventCounter counts events by name, for metrics
+type EventCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *EventCounter) Handle(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[e.EventName()]++
+}
+
+// Counts returns a copy of the counts so far
+func (c *EventCounter) Counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.counts)
+}
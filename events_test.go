@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+func TestEventBusSubscribe(t *testing.T) {
+	bus := NewEventBus()
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	var all []string
+	var prices []PriceChanged
+	unsubscribeAll := bus.SubscribeAll(func(e Event) { all = append(all, e.EventName()) })
+	unsubscribe := Subscribe(bus, func(e PriceChanged) { prices = append(prices, e) })
+
+	bus.Publish(PriceChanged{Item: book, Old: Dollars(9.99), New: Dollars(8.99)})
+	bus.Publish(StockDepleted{Item: book})
+	assert.Equal(t, all, []string{"price-changed", "stock-depleted"})
+	if assert.Equal(t, len(prices), 1) {
+		assert.Equal(t, prices[0].New, Dollars(8.99))
+	}
+
+	unsubscribe()
+	unsubscribeAll()
+	bus.Publish(PriceChanged{Item: book, Old: Dollars(8.99), New: Dollars(7.99)})
+	assert.Equal(t, len(all), 2)
+	assert.Equal(t, len(prices), 1)
+}
+
+func TestEventBusHandlerMayUseBus(t *testing.T) {
+	bus := NewEventBus()
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	var log bytes.Buffer
+	var unsubscribe func()
+	// A handler that publishes and unsubscribes itself doesn't deadlock
+	unsubscribe = Subscribe(bus, func(e StockDepleted) {
+		unsubscribe()
+		bus.Publish(Restocked{Item: e.Item, Quantity: 5, Available: 5})
+	})
+	bus.SubscribeAll(LogEvents(&log))
+	bus.Publish(StockDepleted{Item: book})
+	bus.Publish(StockDepleted{Item: book})
+	assert.Equal(t, log.String(), "[event] restocked: Dune: +5, 5 available\n"+
+		"[event] stock-depleted: Dune: none available, 0 reserved\n"+
+		"[event] stock-depleted: Dune: none available, 0 reserved\n")
+}
+
+func TestEventBusConcurrent(t *testing.T) {
+	leaktest.Check(t)
+	bus := NewEventBus()
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	var counter EventCounter
+	bus.SubscribeAll(counter.Handle)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				bus.Publish(Restocked{Item: book, Quantity: 1})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			Subscribe(bus, func(Restocked) {})()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, counter.Counts(), map[string]int{"restocked": 1000})
+}
+
+func TestNotifyEvents(t *testing.T) {
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	var sent recordingNotifier
+	hub := NewNotificationHub()
+	hub.Register("test", &sent, nil)
+	handle := NotifyEvents(hub)
+
+	handle(PriceChanged{Item: book, Old: Dollars(9.99), New: Dollars(8.99)})
+	// A price rise is not news
+	handle(PriceChanged{Item: book, Old: Dollars(8.99), New: Dollars(10.99)})
+	handle(StockDepleted{Item: book})
+	handle(Restocked{Item: book, Quantity: 3})
+	assert.Equal(t, sent.subjects(), []string{"Dune now $8.99", "Dune is sold out"})
+}
@@ -0,0 +1,102 @@
+package main
+
+// ------------------- BOOK PREVIEWS ---------------------------
+// A book can carry an excerpt, typically its first chapter, for
+// customers to read before buying. Excerpts are much bigger than the
+// rest of a book's data, so:
+//
+//   - they are capped at MaxExcerptSize
+//   - they are kept gzip-compressed, in memory and wherever the book is
+//     stored (JSON files, the SQL data column)
+//   - the HTTP API leaves them out of item and list responses; clients
+//     fetch them separately from GET /items/{id}/preview, which
+//     supports Range requests for reading a page at a time
+//
+// compress/gzip is the Go counterpart of Python's gzip module.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// MaxExcerptSize is the largest excerpt a book may have, uncompressed:
+// plenty for a first chapter
+const MaxExcerptSize = 256 << 10 // 256 KiB
+
+// ErrExcerptTooLarge is returned for excerpts over MaxExcerptSize
+var ErrExcerptTooLarge = fmt.Errorf("excerpt is larger than %d bytes", MaxExcerptSize)
+
+// SetExcerpt attaches text to the book; "" removes the excerpt
+func (b *Book) SetExcerpt(text string) error {
+	if len(text) > MaxExcerptSize {
+		return ErrExcerptTooLarge
+	}
+	if !utf8.ValidString(text) {
+		return errors.New("excerpt must be UTF-8 text")
+	}
+	if text == "" {
+		b.excerpt = nil
+		return nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, text); err != nil {
+		return err
+	}
+	// Close flushes the last compressed block; the data is incomplete
+	// without it
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	b.excerpt = buf.Bytes()
+	return nil
+}
+
+// Excerpt returns the book's excerpt, or "" if it has none
+func (b *Book) Excerpt() (string, error) {
+	if b.excerpt == nil {
+		return "", nil
+	}
+	return decompressExcerpt(b.excerpt)
+}
+
+// HasExcerpt reports whether the book has an excerpt
+func (b *Book) HasExcerpt() bool {
+	return b.excerpt != nil
+}
+
+// ExcerptStoredSize is how many bytes the compressed excerpt takes
+func (b *Book) ExcerptStoredSize() int {
+	return len(b.excerpt)
+}
+
+// decompressExcerpt unzips data, refusing to produce more than
+// MaxExcerptSize bytes: a few KB of crafted gzip can expand to gigabytes
+// (a "zip bomb")
+func decompressExcerpt(data []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("reading excerpt: %w", err)
+	}
+	defer zr.Close()
+	text, err := io.ReadAll(io.LimitReader(zr, MaxExcerptSize+1))
+	if err != nil {
+		return "", fmt.Errorf("reading excerpt: %w", err)
+	}
+	if len(text) > MaxExcerptSize {
+		return "", ErrExcerptTooLarge
+	}
+	return string(text), nil
+}
+
+// withoutExcerpt returns a copy of the book without its excerpt, for
+// responses that should stay small
+func (b *Book) withoutExcerpt() *Book {
+	c := *b
+	c.excerpt = nil
+	return &c
+}
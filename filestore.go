@@ -0,0 +1,195 @@
+package main
+
+// ------------------- FILE STORE ------------------------------
+// FileStore keeps the catalog in a JSON file between runs:
+//
+//	{"items": [{"sku": "BK-001", "type": "book", "item": {...}, "notes": [...]}, ...]}
+//
+// Staff notes (see notes.go) sit next to the item, not inside it.
+//
+// Saving writes a temporary file and renames it over the old one. A
+// rename is atomic, so a crash mid-save leaves the previous file intact
+// instead of a half-written one.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type storedItem struct {
+	SKU  string          `json:"sku"`
+	Type string          `json:"type"`
+	Item json.RawMessage `json:"item"`
+	// Notes are kept out of Item, whose JSON the API also sends
+	Notes []Note `json:"notes,omitempty"`
+}
+
+type storedCatalog struct {
+	Items []storedItem `json:"items"`
+}
+
+// FileStore saves and loads a catalog at Path
+type FileStore struct {
+	Path string
+}
+
+// Save writes every item of c to the file
+func (s FileStore) Save(c *Catalog) error {
+	// An empty catalog is "items": [], not null
+	doc := storedCatalog{Items: []storedItem{}}
+	for _, e := range c.Entries() {
+		typ, err := itemType(e.Item)
+		if err != nil {
+			return fmt.Errorf("saving %q: %w", e.SKU, err)
+		}
+		data, err := json.Marshal(e.Item)
+		if err != nil {
+			return fmt.Errorf("saving %q: %w", e.SKU, err)
+		}
+		doc.Items = append(doc.Items, storedItem{SKU: e.SKU, Type: typ, Item: data, Notes: itemNotes(e.Item)})
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, data)
+}
+
+// writeFileAtomic replaces the file at path with data: readers see the
+// old file or the new one, never a mix
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// If anything below fails, don't leave the temporary file behind
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Sync gets the data onto the disk before the rename makes it the
+	// real file; otherwise a power cut could leave an empty one
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load reads the file back into a new catalog. A missing file returns
+// an error for which errors.Is(err, os.ErrNotExist) is true.
+func (s FileStore) Load() (*Catalog, error) {
+	c := NewCatalog()
+	err := s.Stream(c.Add)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadInto copies every stored item into repo and returns how many
+// were created
+func (s FileStore) LoadInto(repo Repository) (int, error) {
+	n := 0
+	err := s.Stream(func(sku string, item PricedItem) error {
+		if err := repo.Create(sku, item); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// Stream calls fn for each stored item, in file order, stopping at the
+// first error.
+//
+// json.Unmarshal would need the whole file in memory, plus every item
+// at once. A json.Decoder instead reads the file piece by piece: Token
+// returns the next delimiter or key ({ [ ] } "items"), and Decode reads
+// just the next value. Only one item is held at a time, so memory stays
+// flat however large the catalog file is; like iterating over ijson
+// events in Python rather than calling json.load.
+func (s FileStore) Stream(fn func(sku string, item PricedItem) error) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	if err := streamItems(dec, fn); err != nil {
+		return fmt.Errorf("%s: %w", s.Path, err)
+	}
+	return nil
+}
+
+// streamItems walks {"items": [...]}, skipping any other keys
+func streamItems(dec *json.Decoder, fn func(sku string, item PricedItem) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "items" {
+			// Decoding into a RawMessage skips a value of any shape
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		// Files saved before empty catalogs were written as [] say null
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == nil {
+			continue
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("invalid catalog file: expected [ at offset %d, got %v", dec.InputOffset(), tok)
+		}
+		for dec.More() {
+			var stored storedItem
+			if err := dec.Decode(&stored); err != nil {
+				return err
+			}
+			item, err := decodeItem(stored.Type, stored.Item)
+			if err == nil {
+				err = setItemNotes(item, stored.Notes)
+			}
+			if err != nil {
+				return fmt.Errorf("item %q: %w", stored.SKU, err)
+			}
+			if err := fn(stored.SKU, item); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// expectDelim reads the next token and checks that it is want
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("invalid catalog file: expected %v at offset %d, got %v", want, dec.InputOffset(), tok)
+	}
+	return nil
+}
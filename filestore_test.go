@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	bundle := Must(NewBundle("Sci-fi starter", MustPercent(10)))
+	assert.NoError(t, bundle.Add(Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))))
+	assert.NoError(t, bundle.Add(Must(NewMagazine("Analog", Dollars(7.99), 1))))
+	every := map[string]PricedItem{
+		"BK-001": Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(12.99), "Obscurus Books")),
+		"MG-001": Must(NewMagazine("Vogue", Must(NewMoney(1250, "EUR")), 123)),
+		"EB-001": Must(NewEBook("Dune", "Frank Herbert", Dollars(6.99), FormatEPUB, 1<<20)),
+		"AB-001": Must(NewAudioBook("Dune", "Frank Herbert", "Scott Brick", Dollars(24.99), 21*time.Hour)),
+		"BN-001": bundle,
+	}
+
+	tests := []struct {
+		name  string
+		items map[string]PricedItem
+	}{
+		{"empty", nil},
+		{"one item", map[string]PricedItem{"BK-001": every["BK-001"]}},
+		{"every item type", every},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCatalog()
+			for sku, item := range tt.items {
+				assert.NoError(t, c.Add(sku, item))
+			}
+			store := FileStore{Path: filepath.Join(t.TempDir(), "catalog.json")}
+			assert.NoError(t, store.Save(c))
+			loaded, err := store.Load()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, loaded.SKUs(), c.SKUs())
+			assert.Equal(t, Must(loaded.Digest()).Root, Must(c.Digest()).Root)
+		})
+	}
+}
+
+func TestFileStoreLoadsNullItems(t *testing.T) {
+	// Empty catalogs used to be saved like this
+	store := FileStore{Path: filepath.Join(t.TempDir(), "catalog.json")}
+	assert.NoError(t, os.WriteFile(store.Path, []byte(`{"items": null}`), 0o644))
+	loaded, err := store.Load()
+	if assert.NoError(t, err) {
+		assert.Equal(t, loaded.Len(), 0)
+	}
+	assert.NoError(t, os.WriteFile(store.Path, []byte(`{"items": 3}`), 0o644))
+	if _, err := store.Load(); err == nil {
+		t.Error("loaded a file whose items is a number")
+	}
+}
+
+// liveHeap is the heap in use after a garbage collection
+func liveHeap() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// TestFileStoreStreamMemory checks that Stream holds one item at a time,
+// on a catalog of 50,000 books (about 10 MB of JSON). The file is far
+// smaller than the 500 MB catalogs Stream is for, which would make the
+// test too slow to run every time, but Load's peak already grows with
+// it while Stream's stays put.
+func TestFileStoreStreamMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("generates a large catalog file")
+	}
+	const n = 50_000
+	store := catalogFixture(t, n)
+	peak := func(load func(check func())) uint64 {
+		base := liveHeap()
+		var most uint64
+		load(func() { most = max(most, liveHeap()) })
+		return most - min(most, base)
+	}
+
+	streamed := peak(func(check func()) {
+		i := 0
+		assert.NoError(t, store.Stream(func(string, PricedItem) error {
+			if i++; i%(n/10) == 0 {
+				check()
+			}
+			return nil
+		}))
+	})
+	loaded := peak(func(check func()) {
+		c, err := store.Load()
+		assert.NoError(t, err)
+		check()
+		runtime.KeepAlive(c)
+	})
+	t.Logf("live heap growth: Stream %d KiB, Load %d KiB", streamed>>10, loaded>>10)
+	if streamed > 1<<20 || streamed > loaded/10 {
+		t.Errorf("Stream kept %d KiB live, Load %d KiB: Stream is holding on to items", streamed>>10, loaded>>10)
+	}
+}
+
+// catalogFixture saves a generated catalog of n books and returns its store
+func catalogFixture(tb testing.TB, n int) FileStore {
+	tb.Helper()
+	c := NewCatalog()
+	for i := range n {
+		book := Must(NewBook(fmt.Sprintf("Book %d", i), "Anon", Dollars(float64(i%50)+0.99), ""))
+		if err := c.Add(fmt.Sprintf("BK-%06d", i), book); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	store := FileStore{Path: filepath.Join(tb.TempDir(), "catalog.json")}
+	if err := store.Save(c); err != nil {
+		tb.Fatal(err)
+	}
+	return store
+}
+
+// Compare with BenchmarkFileStoreLoad, which holds every item at once
+func BenchmarkFileStoreStream(b *testing.B) {
+	store := catalogFixture(b, 10_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		n := 0
+		err := store.Stream(func(string, PricedItem) error {
+			n++
+			return nil
+		})
+		if err != nil || n != 10_000 {
+			b.Fatalf("streamed %d items: %v", n, err)
+		}
+	}
+}
+
+func BenchmarkFileStoreLoad(b *testing.B) {
+	store := catalogFixture(b, 10_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := store.Load(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
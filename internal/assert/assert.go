@@ -0,0 +1,138 @@
+// Package assert holds the few checks this module's tests repeat, so
+// they stay short without depending on testify:
+//
+//	assert.Equal(t, book.Price(), Must(NewMoney(899, "USD")))
+//	assert.ErrorIs(t, err, ErrNotFound)
+//	assert.Panics(t, func() { MustPercent(150) })
+//
+// Like a plain if in a test, each one reports with t.Errorf and lets
+// the test carry on. Each also returns whether it passed, for the cases
+// where carrying on makes no sense:
+//
+//	if !assert.NoError(t, err) {
+//		return
+//	}
+//
+// When two structs differ, Equal lists only the fields that differ,
+// with their paths, instead of printing both values whole.
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TB is the part of testing.TB that the assertions use
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Equal checks that got and want are deeply equal (reflect.DeepEqual)
+func Equal[T any](t TB, got, want T) bool {
+	t.Helper()
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+	var lines []string
+	diff(&lines, "", reflect.ValueOf(got), reflect.ValueOf(want))
+	if len(lines) == 0 {
+		// e.g. NaN, which is printed the same but never equal
+		lines = []string{fmt.Sprintf("got %#v, want %#v", got, want)}
+	}
+	if len(lines) == 1 && strings.HasPrefix(lines[0], "got ") {
+		// Nothing was walked into: "got X, want Y" says it all
+		t.Errorf("%s", lines[0])
+		return false
+	}
+	t.Errorf("values differ:\n\t%s", strings.Join(lines, "\n\t"))
+	return false
+}
+
+// NoError checks that err is nil
+func NoError(t TB, err error) bool {
+	t.Helper()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return false
+	}
+	return true
+}
+
+// ErrorIs checks that errors.Is(err, target)
+func ErrorIs(t TB, err, target error) bool {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("got error %v, want %v", err, target)
+		return false
+	}
+	return true
+}
+
+// Panics checks that fn panics
+func Panics(t TB, fn func()) (ok bool) {
+	t.Helper()
+	defer func() {
+		if recover() != nil {
+			ok = true
+		} else {
+			t.Errorf("did not panic")
+		}
+	}()
+	fn()
+	return false
+}
+
+// diff appends one line per difference between got and want, each
+// starting with the path to it, e.g. ".Price.minor: got 899, want 999"
+func diff(lines *[]string, path string, got, want reflect.Value) {
+	if got.IsValid() && want.IsValid() && got.Type() == want.Type() {
+		switch got.Kind() {
+		case reflect.Struct:
+			for i := range got.NumField() {
+				name := got.Type().Field(i).Name
+				diff(lines, path+"."+name, got.Field(i), want.Field(i))
+			}
+			return
+		case reflect.Pointer:
+			if !got.IsNil() && !want.IsNil() {
+				diff(lines, path, got.Elem(), want.Elem())
+				return
+			}
+		case reflect.Slice, reflect.Array:
+			if got.Len() == want.Len() {
+				for i := range got.Len() {
+					diff(lines, fmt.Sprintf("%s[%d]", path, i), got.Index(i), want.Index(i))
+				}
+				return
+			}
+		}
+		// reflect.Value, unlike Interface, can read unexported fields
+		if valuesEqual(got, want) {
+			return
+		}
+	}
+	line := fmt.Sprintf("got %s, want %s", show(got), show(want))
+	if path != "" {
+		line = path + ": " + line
+	}
+	*lines = append(*lines, line)
+}
+
+// valuesEqual compares two values of the same type, including
+// unexported ones that can't be turned back into an interface
+func valuesEqual(got, want reflect.Value) bool {
+	if got.CanInterface() {
+		return reflect.DeepEqual(got.Interface(), want.Interface())
+	}
+	return fmt.Sprintf("%#v", got) == fmt.Sprintf("%#v", want)
+}
+
+func show(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return fmt.Sprintf("%#v", v)
+}
@@ -0,0 +1,86 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// recorder is a TB that keeps the failures instead of failing the test
+type recorder struct {
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+type price struct {
+	minor    int64
+	currency string
+}
+
+type line struct {
+	Title string
+	Price price
+	Tags  []string
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name      string
+		check     func(TB) bool
+		wantLines []string // substrings of the failure; none means it passes
+	}{
+		{"equal ints", func(t TB) bool { return Equal(t, 3, 3) }, nil},
+		{"different ints", func(t TB) bool { return Equal(t, 3, 4) }, []string{"got 3, want 4"}},
+		{"equal structs", func(t TB) bool {
+			return Equal(t, line{"Emma", price{899, "USD"}, []string{"a"}}, line{"Emma", price{899, "USD"}, []string{"a"}})
+		}, nil},
+		{"struct fields", func(t TB) bool {
+			return Equal(t, &line{"Emma", price{899, "USD"}, []string{"a", "b"}}, &line{"Emma", price{999, "USD"}, []string{"a", "c"}})
+		}, []string{".Price.minor: got 899, want 999", `.Tags[1]: got "b", want "c"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			ok := tt.check(&r)
+			if ok != (tt.wantLines == nil) {
+				t.Fatalf("returned %v; failures: %q", ok, r.failures)
+			}
+			for _, want := range tt.wantLines {
+				if len(r.failures) != 1 || !strings.Contains(r.failures[0], want) {
+					t.Errorf("failures %q don't mention %q", r.failures, want)
+				}
+			}
+			if len(r.failures) == 1 && strings.Contains(r.failures[0], "Title") {
+				t.Errorf("equal field reported: %q", r.failures[0])
+			}
+		})
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	var r recorder
+	wrapped := fmt.Errorf("reading: %w", io.EOF)
+	if !ErrorIs(&r, wrapped, io.EOF) || !NoError(&r, nil) {
+		t.Errorf("failed: %q", r.failures)
+	}
+	if ErrorIs(&r, errors.New("other"), io.EOF) || NoError(&r, io.EOF) || len(r.failures) != 2 {
+		t.Errorf("passed: %q", r.failures)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	var r recorder
+	if !Panics(&r, func() { panic("boom") }) {
+		t.Errorf("a panic was missed: %q", r.failures)
+	}
+	if Panics(&r, func() {}) || len(r.failures) != 1 {
+		t.Errorf("no panic passed: %q", r.failures)
+	}
+}
@@ -0,0 +1,119 @@
+// Package leaktest fails a test that leaves goroutines running.
+//
+// A goroutine that never returns is a leak: a scheduler whose stop
+// function doesn't stop it, a subscriber nobody unsubscribes, a worker
+// waiting on a channel nobody closes. Call Check at the start of a
+// test:
+//
+//	func TestRepriceAll(t *testing.T) {
+//		leaktest.Check(t)
+//		...
+//	}
+//
+// Check notes the goroutines running now. When the test and all of its
+// other cleanups have finished, any goroutine that wasn't there before
+// fails the test, with its stack trace. Goroutines get a short grace
+// period to return, since stopping one is usually asynchronous.
+//
+// It lives under internal/, so only this module can import it.
+package leaktest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Timeout is how long new goroutines get to return after a test
+var Timeout = 5 * time.Second
+
+// TB is the part of testing.TB that Check uses; *testing.T and
+// *testing.B satisfy it
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...any)
+}
+
+// ignored are goroutines the runtime or the testing package start on
+// their own, which a test can't be blamed for
+var ignored = []string{
+	"testing.(*T).Run(",
+	"testing.tRunner(",
+	"testing.runTests(",
+	"runtime.ensureSigM(",
+	"os/signal.signal_recv(",
+}
+
+// Check fails t if goroutines started during the test are still running
+// once it is over
+func Check(t TB) {
+	t.Helper()
+	before := goroutines()
+	// Cleanups run last-registered first, so this one runs after every
+	// cleanup the test registers later, e.g. one stopping a scheduler
+	t.Cleanup(func() {
+		t.Helper()
+		deadline := time.Now().Add(Timeout)
+		for {
+			leaked := newGoroutines(before, goroutines())
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("%d goroutines still running after the test:\n\n%s",
+					len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// newGoroutines returns the stacks in after whose goroutine isn't in
+// before
+func newGoroutines(before, after map[string]string) []string {
+	var leaked []string
+	for id, stack := range after {
+		if _, ok := before[id]; !ok && !isIgnored(stack) {
+			leaked = append(leaked, stack)
+		}
+	}
+	return leaked
+}
+
+func isIgnored(stack string) bool {
+	for _, s := range ignored {
+		if strings.Contains(stack, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// goroutines returns the stack of every goroutine but the calling one,
+// keyed by goroutine ID
+func goroutines() map[string]string {
+	// runtime.Stack truncates to the buffer, so grow it until all fits
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	stacks := make(map[string]string)
+	// Stacks are separated by blank lines; the first one is ours
+	for i, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue
+		}
+		// Each starts with "goroutine 42 [chan receive]:"
+		header, _, _ := bytes.Cut(stack, []byte(" ["))
+		stacks[string(header)] = string(stack)
+	}
+	return stacks
+}
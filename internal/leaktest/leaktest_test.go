@@ -0,0 +1,86 @@
+package leaktest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder is a TB that keeps the failures instead of failing the test,
+// and runs its cleanups when told to
+type recorder struct {
+	failures []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper()           {}
+func (r *recorder) Cleanup(fn func()) { r.cleanups = append(r.cleanups, fn) }
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// finish runs the cleanups as the testing package does, last first
+func (r *recorder) finish() {
+	for _, fn := range slices.Backward(r.cleanups) {
+		fn()
+	}
+}
+
+// shortTimeout keeps the leaking cases from waiting the full Timeout
+func shortTimeout(t *testing.T) {
+	saved := Timeout
+	Timeout = 100 * time.Millisecond
+	t.Cleanup(func() { Timeout = saved })
+}
+
+func leakyWorker(stop chan struct{}) { <-stop }
+
+func TestCheckCatchesLeak(t *testing.T) {
+	shortTimeout(t)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	var r recorder
+	Check(&r)
+	go leakyWorker(stop)
+	r.finish()
+	if len(r.failures) != 1 {
+		t.Fatalf("got %d failures, want 1: %q", len(r.failures), r.failures)
+	}
+	// The report carries the leaked goroutine's stack
+	if !strings.Contains(r.failures[0], "1 goroutines still running") || !strings.Contains(r.failures[0], "leakyWorker") {
+		t.Errorf("failure doesn't show the leak:\n%s", r.failures[0])
+	}
+}
+
+func TestCheckPassesWhenStopped(t *testing.T) {
+	shortTimeout(t)
+	var r recorder
+	Check(&r)
+	stop := make(chan struct{})
+	go leakyWorker(stop)
+	// Registered after Check, so it runs first, like a test's own
+	// cleanup stopping a scheduler
+	r.Cleanup(func() { close(stop) })
+	r.finish()
+	if len(r.failures) != 0 {
+		t.Errorf("stopped goroutine reported: %q", r.failures)
+	}
+}
+
+func TestCheckIgnoresEarlierGoroutines(t *testing.T) {
+	shortTimeout(t)
+	stop := make(chan struct{})
+	defer close(stop)
+	go leakyWorker(stop)
+
+	var r recorder
+	Check(&r)
+	r.finish()
+	if len(r.failures) != 0 {
+		t.Errorf("goroutine started before Check reported: %q", r.failures)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+// ------------------- INVENTORY -------------------------------
+// The inventory counts how many units of each item the store has.
+// Units move between two buckets:
+//
+//	available --Reserve--> reserved --Commit--> (sold, gone)
+//	available <--Release-- reserved
+//
+// Restock adds new units to "available". Like the other per-item
+// features, items themselves are the map keys.
+//
+// Units arrive in lots, each with its own unit cost. Sales use up the
+// oldest lot first (FIFO), so the stock on hand is valued at the cost
+// of the most recent lots.
+
+import (
+	"fmt"
+	"time"
+)
+
+// stockLevel is the count for one item
+type stockLevel struct {
+	available int
+	reserved  int
+	// sales remembers every Commit, for sales velocity
+	sales []stockSale
+	// lots holds the units on hand (available + reserved), oldest first
+	lots []stockLot
+	// packs tracks sealed packs among the available units (see packs.go)
+	packs packStock
+}
+
+type stockLot struct {
+	qty      int
+	unitCost Money
+}
+
+type stockSale struct {
+	at  time.Time
+	qty int
+}
+
+// Inventory tracks stock for any number of items
+type Inventory struct {
+	levels map[PricedItem]*stockLevel
+	// now is currentTime, replaceable so callers can control the clock
+	now func() time.Time
+}
+
+// NewInventory returns an empty inventory: every item has zero stock
+func NewInventory() *Inventory {
+	return &Inventory{levels: make(map[PricedItem]*stockLevel), now: currentTime}
+}
+
+// level returns the item's counters, creating them on first use
+func (inv *Inventory) level(item PricedItem) *stockLevel {
+	l, ok := inv.levels[item]
+	if !ok {
+		l = &stockLevel{}
+		inv.levels[item] = l
+	}
+	return l
+}
+
+// Restock adds qty newly received units whose cost is not known
+func (inv *Inventory) Restock(item PricedItem, qty int) error {
+	return inv.ReceiveLot(item, qty, Money{})
+}
+
+// ReceiveLot adds qty units bought at unitCost each
+func (inv *Inventory) ReceiveLot(item PricedItem, qty int, unitCost Money) error {
+	if qty <= 0 {
+		return fmt.Errorf("restock quantity must be positive")
+	}
+	if unitCost.IsNegative() {
+		return fmt.Errorf("unit cost cannot be negative")
+	}
+	l := inv.level(item)
+	l.available += qty
+	l.lots = append(l.lots, stockLot{qty: qty, unitCost: unitCost})
+	Events.Publish(Restocked{Item: item, Quantity: qty, Available: l.available, At: inv.now()})
+	return nil
+}
+
+// Reserve sets qty units aside, e.g. for an order awaiting payment
+func (inv *Inventory) Reserve(item PricedItem, qty int) error {
+	if qty <= 0 {
+		return fmt.Errorf("reserve quantity must be positive")
+	}
+	l := inv.level(item)
+	if qty > l.available {
+		return fmt.Errorf("cannot reserve %d, only %d available", qty, l.available)
+	}
+	// Open sealed packs if there aren't enough loose units
+	inv.breakPacksFor(item, l, qty)
+	l.available -= qty
+	l.reserved += qty
+	inv.checkDepleted(item, l)
+	return nil
+}
+
+// Release returns qty reserved units to stock, e.g. for a cancelled order
+func (inv *Inventory) Release(item PricedItem, qty int) error {
+	if qty <= 0 {
+		return fmt.Errorf("release quantity must be positive")
+	}
+	l := inv.level(item)
+	if qty > l.reserved {
+		return fmt.Errorf("cannot release %d, only %d reserved", qty, l.reserved)
+	}
+	l.reserved -= qty
+	l.available += qty
+	return nil
+}
+
+// Commit removes qty reserved units for good, once they are sold
+func (inv *Inventory) Commit(item PricedItem, qty int) error {
+	if qty <= 0 {
+		return fmt.Errorf("commit quantity must be positive")
+	}
+	l := inv.level(item)
+	if qty > l.reserved {
+		return fmt.Errorf("cannot commit %d, only %d reserved", qty, l.reserved)
+	}
+	l.reserved -= qty
+	l.sales = append(l.sales, stockSale{at: inv.now(), qty: qty})
+	l.consumeLots(qty)
+	return nil
+}
+
+// Adjust corrects the available count after a stock take: a positive
+// delta adds units of unknown cost, a negative one writes units off
+// (damaged, lost) without counting them as sold
+func (inv *Inventory) Adjust(item PricedItem, delta int) error {
+	switch {
+	case delta > 0:
+		return inv.Restock(item, delta)
+	case delta == 0:
+		return fmt.Errorf("adjustment cannot be zero")
+	}
+	qty := -delta
+	l := inv.level(item)
+	if qty > l.available {
+		return fmt.Errorf("cannot write off %d, only %d available", qty, l.available)
+	}
+	inv.breakPacksFor(item, l, qty)
+	l.available -= qty
+	l.consumeLots(qty)
+	inv.checkDepleted(item, l)
+	return nil
+}
+
+// checkDepleted publishes StockDepleted once nothing is left available
+func (inv *Inventory) checkDepleted(item PricedItem, l *stockLevel) {
+	if l.available == 0 {
+		Events.Publish(StockDepleted{Item: item, Reserved: l.reserved, At: inv.now()})
+	}
+}
+
+// consumeLots removes qty units from the oldest lots first
+func (l *stockLevel) consumeLots(qty int) {
+	for qty > 0 && len(l.lots) > 0 {
+		used := min(qty, l.lots[0].qty)
+		l.lots[0].qty -= used
+		qty -= used
+		if l.lots[0].qty == 0 {
+			l.lots = l.lots[1:]
+		}
+	}
+}
+
+// StockValue is what the units on hand cost, valued FIFO
+func (inv *Inventory) StockValue(item PricedItem) (Money, error) {
+	var sum lineSum
+	l, ok := inv.levels[item]
+	if !ok {
+		return sum.total, nil
+	}
+	for _, lot := range l.lots {
+		if err := sum.add(lot.unitCost, lot.qty); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum.total, nil
+}
+
+// SalesVelocity is the average number of units sold per day over the
+// last "window" (committed units only)
+func (inv *Inventory) SalesVelocity(item PricedItem, window time.Duration) float64 {
+	l, ok := inv.levels[item]
+	if !ok || window <= 0 {
+		return 0
+	}
+	since := inv.now().Add(-window)
+	sold := 0
+	for _, sale := range l.sales {
+		if sale.at.After(since) {
+			sold += sale.qty
+		}
+	}
+	days := window.Hours() / 24
+	return float64(sold) / days
+}
+
+// AvailableQuantity is how many units can still be reserved
+func (inv *Inventory) AvailableQuantity(item PricedItem) int {
+	// Reading a missing key gives the zero value; no need to create it
+	if l, ok := inv.levels[item]; ok {
+		return l.available
+	}
+	return 0
+}
+
+// ReservedQuantity is how many units are set aside but not yet sold
+func (inv *Inventory) ReservedQuantity(item PricedItem) int {
+	if l, ok := inv.levels[item]; ok {
+		return l.reserved
+	}
+	return 0
+}
+
+// InStock reports whether at least one unit of the book is available
+func (b *Book) InStock(inv *Inventory) bool {
+	return inv.AvailableQuantity(b) > 0
+}
+
+// InStock reports whether at least one copy of the magazine is available
+func (m *Magazine) InStock(inv *Inventory) bool {
+	return inv.AvailableQuantity(m) > 0
+}
+
+// InStock is always true: every sale of an e-book is a fresh download
+func (e *EBook) InStock(inv *Inventory) bool {
+	return true
+}
+
+// InStock is always true for audiobooks too, they are streamed
+func (a *AudioBook) InStock(inv *Inventory) bool {
+	return true
+}
@@ -0,0 +1,375 @@
+package main
+
+// ------------------- JSON SERIALIZATION ----------------------
+// encoding/json only sees exported (capitalized) fields, so Book's
+// private title/author/price would silently disappear. The fix is a
+// DTO ("data transfer object"): a plain struct with exported fields and
+// json tags that mirrors the item. MarshalJSON/UnmarshalJSON convert
+// between the two; json.Marshal calls them automatically.
+//
+// Python equivalent: writing to_dict()/from_dict() for json.dumps.
+//
+// Internal notes are deliberately NOT serialized: JSON output leaves
+// the store, and notes are for staff only. The file and SQL stores save
+// them separately.
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type bookJSON struct {
+	Title        string                 `json:"title"`
+	Author       string                 `json:"author"`
+	Price        json.Number            `json:"price"`
+	Currency     string                 `json:"currency,omitempty"`
+	PageCount    int                    `json:"pageCount,omitempty"`
+	Seller       string                 `json:"seller,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+	// Excerpt is gzip-compressed; json encodes []byte as base64
+	Excerpt []byte `json:"excerpt,omitempty"`
+}
+
+type magazineJSON struct {
+	Name         string                 `json:"name"`
+	Price        json.Number            `json:"price"`
+	Currency     string                 `json:"currency,omitempty"`
+	IssueNumber  int                    `json:"issueNumber"`
+	Description  string                 `json:"description,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+type ebookJSON struct {
+	Title        string                 `json:"title"`
+	Author       string                 `json:"author"`
+	Price        json.Number            `json:"price"`
+	Currency     string                 `json:"currency,omitempty"`
+	Format       string                 `json:"format"`
+	FileSize     int64                  `json:"fileSize"`
+	DRM          bool                   `json:"drm,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+type audioBookJSON struct {
+	Title        string                 `json:"title"`
+	Author       string                 `json:"author"`
+	Narrator     string                 `json:"narrator"`
+	Price        json.Number            `json:"price"` // the list price
+	Currency     string                 `json:"currency,omitempty"`
+	Minutes      int                    `json:"minutes"`
+	Pricing      string                 `json:"pricing,omitempty"` // "purchase" or "credit"
+	Description  string                 `json:"description,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+// bundleJSON nests its contents, each with its type. They are copies:
+// a bundle read back holds new items, not the catalog's own entries.
+type bundleJSON struct {
+	Title        string                 `json:"title"`
+	Discount     float64                `json:"discount"` // percent
+	Items        []typedItemJSON        `json:"items"`
+	Description  string                 `json:"description,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+type typedItemJSON struct {
+	Type string          `json:"type"`
+	Item json.RawMessage `json:"item"`
+}
+
+// MarshalJSON makes Book satisfy json.Marshaler
+func (b *Book) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bookJSON{
+		Title:        b.title,
+		Author:       b.author,
+		Price:        json.Number(b.price.Decimal()),
+		Currency:     b.price.currency,
+		PageCount:    b.pageCount,
+		Seller:       b.Seller,
+		Description:  b.Description,
+		Translations: b.byLanguage,
+		Excerpt:      b.excerpt,
+	})
+}
+
+// UnmarshalJSON makes *Book satisfy json.Unmarshaler.
+// It validates like SetPrice/SetPageCount and the constructors, so bad
+// data can't sneak in through a file where they would have refused it.
+func (b *Book) UnmarshalJSON(data []byte) error {
+	var dto bookJSON
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+	price, err := decodePrice(dto.Price, dto.Currency)
+	if err != nil {
+		return fmt.Errorf("book %q: %w", dto.Title, err)
+	}
+	if dto.PageCount < 0 || dto.PageCount > MaxPageCount {
+		return fmt.Errorf("book %q: page count must be between 1 and %d", dto.Title, MaxPageCount)
+	}
+	*b = Book{
+		title:       dto.Title,
+		author:      dto.Author,
+		price:       price,
+		pageCount:   dto.PageCount,
+		Seller:      dto.Seller,
+		Description: dto.Description,
+		excerpt:     dto.Excerpt,
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	return b.restoreTranslations(dto.Translations)
+}
+
+// MarshalJSON makes Magazine satisfy json.Marshaler
+func (m *Magazine) MarshalJSON() ([]byte, error) {
+	return json.Marshal(magazineJSON{
+		Name:         m.name,
+		Price:        json.Number(m.price.Decimal()),
+		Currency:     m.price.currency,
+		IssueNumber:  m.issueNumber,
+		Description:  m.Description,
+		Translations: m.byLanguage,
+	})
+}
+
+// UnmarshalJSON makes *Magazine satisfy json.Unmarshaler
+func (m *Magazine) UnmarshalJSON(data []byte) error {
+	var dto magazineJSON
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+	price, err := decodePrice(dto.Price, dto.Currency)
+	if err != nil {
+		return fmt.Errorf("magazine %q: %w", dto.Name, err)
+	}
+	*m = Magazine{
+		name:        dto.Name,
+		price:       price,
+		issueNumber: dto.IssueNumber,
+		Description: dto.Description,
+	}
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	return m.restoreTranslations(dto.Translations)
+}
+
+// MarshalJSON makes EBook satisfy json.Marshaler. The paper edition is
+// a link to another catalog entry, so it isn't stored with the e-book.
+func (e *EBook) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ebookJSON{
+		Title:        e.title,
+		Author:       e.author,
+		Price:        json.Number(e.price.Decimal()),
+		Currency:     e.price.currency,
+		Format:       e.format.String(),
+		FileSize:     e.fileSize,
+		DRM:          e.DRM,
+		Description:  e.Description,
+		Translations: e.byLanguage,
+	})
+}
+
+// UnmarshalJSON makes *EBook satisfy json.Unmarshaler
+func (e *EBook) UnmarshalJSON(data []byte) error {
+	var dto ebookJSON
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+	price, err := decodePrice(dto.Price, dto.Currency)
+	if err != nil {
+		return fmt.Errorf("e-book %q: %w", dto.Title, err)
+	}
+	format, err := ParseEBookFormat(dto.Format)
+	if err != nil {
+		return fmt.Errorf("e-book %q: %w", dto.Title, err)
+	}
+	*e = EBook{
+		title:       dto.Title,
+		author:      dto.Author,
+		price:       price,
+		format:      format,
+		DRM:         dto.DRM,
+		Description: dto.Description,
+	}
+	if err := e.SetFileSize(dto.FileSize); err != nil {
+		return fmt.Errorf("e-book %q: %w", dto.Title, err)
+	}
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	return e.restoreTranslations(dto.Translations)
+}
+
+// MarshalJSON makes AudioBook satisfy json.Marshaler
+func (a *AudioBook) MarshalJSON() ([]byte, error) {
+	return json.Marshal(audioBookJSON{
+		Title:        a.title,
+		Author:       a.author,
+		Narrator:     a.narrator,
+		Price:        json.Number(a.listPrice.Decimal()),
+		Currency:     a.listPrice.currency,
+		Minutes:      int(a.duration.Round(time.Minute) / time.Minute),
+		Pricing:      a.Pricing.String(),
+		Description:  a.Description,
+		Translations: a.byLanguage,
+	})
+}
+
+// UnmarshalJSON makes *AudioBook satisfy json.Unmarshaler
+func (a *AudioBook) UnmarshalJSON(data []byte) error {
+	var dto audioBookJSON
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+	price, err := decodePrice(dto.Price, dto.Currency)
+	if err != nil {
+		return fmt.Errorf("audiobook %q: %w", dto.Title, err)
+	}
+	*a = AudioBook{
+		title:       dto.Title,
+		author:      dto.Author,
+		narrator:    dto.Narrator,
+		listPrice:   price,
+		Description: dto.Description,
+	}
+	switch dto.Pricing {
+	case "", PayPerTitle.String():
+		a.Pricing = PayPerTitle
+	case SubscriptionCredit.String():
+		a.Pricing = SubscriptionCredit
+	default:
+		return fmt.Errorf("audiobook %q: pricing must be %q or %q", dto.Title, PayPerTitle, SubscriptionCredit)
+	}
+	if err := a.SetDuration(time.Duration(dto.Minutes) * time.Minute); err != nil {
+		return fmt.Errorf("audiobook %q: %w", dto.Title, err)
+	}
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	return a.restoreTranslations(dto.Translations)
+}
+
+// MarshalJSON makes Bundle satisfy json.Marshaler
+func (b *Bundle) MarshalJSON() ([]byte, error) {
+	dto := bundleJSON{
+		Title:        b.title,
+		Discount:     b.Discount.Value(),
+		Items:        []typedItemJSON{},
+		Description:  b.Description,
+		Translations: b.byLanguage,
+	}
+	for _, item := range b.items {
+		typ, err := itemType(item)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		dto.Items = append(dto.Items, typedItemJSON{Type: typ, Item: data})
+	}
+	return json.Marshal(dto)
+}
+
+// UnmarshalJSON makes *Bundle satisfy json.Unmarshaler
+func (b *Bundle) UnmarshalJSON(data []byte) error {
+	var dto bundleJSON
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return err
+	}
+	discount, err := NewPercent(dto.Discount)
+	if err != nil {
+		return fmt.Errorf("bundle %q: discount: %w", dto.Title, err)
+	}
+	*b = Bundle{title: dto.Title, Discount: discount, Description: dto.Description}
+	for i, typed := range dto.Items {
+		item, err := decodeItem(typed.Type, typed.Item)
+		if err != nil {
+			return fmt.Errorf("bundle %q: item %d: %w", dto.Title, i+1, err)
+		}
+		if err := b.Add(item); err != nil {
+			return err
+		}
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	return b.restoreTranslations(dto.Translations)
+}
+
+// decodePrice reads the JSON number text exactly, without a float64
+// in between; a missing currency means DefaultCurrency
+func decodePrice(amount json.Number, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	price, err := ParseMoney(amount.String(), currency)
+	if err != nil {
+		return Money{}, fmt.Errorf("price: %w", err)
+	}
+	if price.IsNegative() {
+		return Money{}, ErrNegativePrice
+	}
+	return price, nil
+}
+
+// restoreTranslations goes through SetTranslation so the language tags
+// are normalized and empty titles rejected
+func (t *translations) restoreTranslations(byLanguage map[string]Translation) error {
+	for lang, tr := range byLanguage {
+		if err := t.SetTranslation(lang, tr); err != nil {
+			return fmt.Errorf("translation %q: %w", lang, err)
+		}
+	}
+	return nil
+}
+
+// itemType names the concrete type of item for JSON envelopes that can
+// hold either kind, such as the file store and the HTTP API
+func itemType(item PricedItem) (string, error) {
+	switch item.(type) {
+	case *Book:
+		return "book", nil
+	case *Magazine:
+		return "magazine", nil
+	case *EBook:
+		return "ebook", nil
+	case *AudioBook:
+		return "audiobook", nil
+	case *Bundle:
+		return "bundle", nil
+	default:
+		return "", fmt.Errorf("unsupported item type %T", item)
+	}
+}
+
+// decodeItem is the reverse of itemType: it unmarshals data into a new
+// item of the named type, validating it on the way
+func decodeItem(typ string, data json.RawMessage) (PricedItem, error) {
+	var item PricedItem
+	switch typ {
+	case "book":
+		item = &Book{}
+	case "magazine":
+		item = &Magazine{}
+	case "ebook":
+		item = &EBook{}
+	case "audiobook":
+		item = &AudioBook{}
+	case "bundle":
+		item = &Bundle{}
+	default:
+		return nil, fmt.Errorf(`type must be "book", "magazine", "ebook", "audiobook" or "bundle", not %q`, typ)
+	}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
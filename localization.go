@@ -0,0 +1,304 @@
+package main
+
+// ------------------- LOCALIZATION ----------------------------
+// Items can carry translated titles and descriptions per language.
+// Lookups fall back from "pt-BR" to "pt" and finally to the text the
+// item was created with, which is treated as DefaultLanguage.
+// Python would usually reach for gettext here; a map is enough for us.
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of the title passed to NewBook/NewMagazine
+const DefaultLanguage = "en"
+
+// Translation holds the translatable text of one item in one language
+type Translation struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// translations is embedded in Book and Magazine
+// Embedding "promotes" its methods, so book.SetTranslation(...) works
+// without Book declaring the method itself - Go's composition in action
+type translations struct {
+	byLanguage map[string]Translation
+}
+
+// SetTranslation stores the text for one language, replacing any previous one
+func (t *translations) SetTranslation(lang string, tr Translation) error {
+	lang = normalizeLanguage(lang)
+	if lang == "" {
+		return fmt.Errorf("language tag cannot be empty")
+	}
+	if tr.Title == "" {
+		return fmt.Errorf("translated title cannot be empty")
+	}
+	// The zero value of a map is nil; writing to a nil map panics,
+	// so create it on first use
+	if t.byLanguage == nil {
+		t.byLanguage = make(map[string]Translation)
+	}
+	t.byLanguage[lang] = tr
+	return nil
+}
+
+// Translation returns the stored text for lang, trying the base language
+// ("pt" for "pt-BR") when the regional variant is missing
+func (t *translations) Translation(lang string) (Translation, bool) {
+	for _, candidate := range languageFallbacks(lang) {
+		if tr, ok := t.byLanguage[candidate]; ok {
+			return tr, true
+		}
+	}
+	return Translation{}, false
+}
+
+// Languages lists the languages that have a translation, sorted
+func (t *translations) Languages() []string {
+	// maps.Keys yields the keys in random order; slices.Sorted collects
+	// and sorts them, like Python's sorted(d.keys())
+	return slices.Sorted(maps.Keys(t.byLanguage))
+}
+
+// localizedTitle returns the title in lang, or original without one
+func (t *translations) localizedTitle(lang, original string) string {
+	if tr, ok := t.Translation(lang); ok {
+		return tr.Title
+	}
+	return original
+}
+
+// localizedDescription returns the description in lang, or original
+// without one
+func (t *translations) localizedDescription(lang, original string) string {
+	if tr, ok := t.Translation(lang); ok && tr.Description != "" {
+		return tr.Description
+	}
+	return original
+}
+
+// Each item type only says which of its fields hold the original text
+
+func (b *Book) LocalizedTitle(lang string) string { return b.localizedTitle(lang, b.title) }
+func (b *Book) LocalizedDescription(lang string) string {
+	return b.localizedDescription(lang, b.Description)
+}
+
+func (m *Magazine) LocalizedTitle(lang string) string { return m.localizedTitle(lang, m.name) }
+func (m *Magazine) LocalizedDescription(lang string) string {
+	return m.localizedDescription(lang, m.Description)
+}
+
+func (e *EBook) LocalizedTitle(lang string) string { return e.localizedTitle(lang, e.title) }
+func (e *EBook) LocalizedDescription(lang string) string {
+	return e.localizedDescription(lang, e.Description)
+}
+
+func (a *AudioBook) LocalizedTitle(lang string) string { return a.localizedTitle(lang, a.title) }
+func (a *AudioBook) LocalizedDescription(lang string) string {
+	return a.localizedDescription(lang, a.Description)
+}
+
+func (b *Bundle) LocalizedTitle(lang string) string { return b.localizedTitle(lang, b.title) }
+func (b *Bundle) LocalizedDescription(lang string) string {
+	return b.localizedDescription(lang, b.Description)
+}
+
+// Translatable is satisfied by every item that supports translations
+type Translatable interface {
+	LocalizedTitle(lang string) string
+	LocalizedDescription(lang string) string
+	Translation(lang string) (Translation, bool)
+	SetTranslation(lang string, tr Translation) error
+}
+
+// ------------------- LANGUAGE SELECTION ----------------------
+
+// normalizeLanguage turns " PT_br " into "pt-br"
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// languageFallbacks returns "pt-br", "pt" for "pt-BR"
+func languageFallbacks(lang string) []string {
+	lang = normalizeLanguage(lang)
+	if lang == "" {
+		return nil
+	}
+	fallbacks := []string{lang}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		fallbacks = append(fallbacks, base)
+	}
+	return fallbacks
+}
+
+// localizeFor returns item's text in the first of langs (most preferred
+// first) it has a translation for, and that language. Without a match,
+// or for items that can't be translated, it returns the original text
+// and DefaultLanguage.
+func localizeFor(item PricedItem, langs []string) (Translation, string) {
+	t, ok := item.(Translatable)
+	if !ok {
+		return Translation{Title: itemTitle(item)}, DefaultLanguage
+	}
+	for _, lang := range langs {
+		if _, ok := t.Translation(lang); ok {
+			return Translation{Title: t.LocalizedTitle(lang), Description: t.LocalizedDescription(lang)}, lang
+		}
+	}
+	return Translation{
+		Title:       t.LocalizedTitle(DefaultLanguage),
+		Description: t.LocalizedDescription(DefaultLanguage),
+	}, DefaultLanguage
+}
+
+// ParseLanguagePreferences reads an Accept-Language style list such as
+// "fr-CH, fr;q=0.9, en;q=0.8" (or a plain "fr" from a command-line flag)
+// and returns the languages ordered from most to least preferred.
+// Entries with q=0 mean "not acceptable" and are dropped.
+func ParseLanguagePreferences(header string) []string {
+	type pref struct {
+		lang string
+		q    float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		lang, params, _ := strings.Cut(part, ";")
+		lang = normalizeLanguage(lang)
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		prefs = append(prefs, pref{lang, q})
+	}
+	// A stable sort keeps the header order for equal q values
+	slices.SortStableFunc(prefs, func(a, b pref) int { return cmp.Compare(b.q, a.q) })
+	return Map(prefs, func(p pref) string { return p.lang })
+}
+
+// ------------------- TRANSLATION FILES -----------------------
+// Translators get a CSV file with one row per text to translate:
+//
+//	key,source,translation
+//	hp.title,Harry Potter,Harry Potter à l'école des sorciers
+//
+// The key is "<item key>.title" or "<item key>.description", where the
+// item key is whatever the caller uses to identify items. Item keys may
+// contain dots themselves ("vol.2.title"): the field is after the last.
+
+var translationHeader = []string{"key", "source", "translation"}
+
+// ExportTranslations writes a translation file for lang. Existing
+// translations are filled in so translators can review them.
+func ExportTranslations(w io.Writer, lang string, items map[string]Translatable) error {
+	keys := slices.Sorted(maps.Keys(items))
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(translationHeader); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		item := items[key]
+		existing, _ := item.Translation(lang)
+		rows := [][]string{
+			{key + ".title", item.LocalizedTitle(DefaultLanguage), existing.Title},
+			{key + ".description", item.LocalizedDescription(DefaultLanguage), existing.Description},
+		}
+		for _, row := range rows {
+			// Nothing to translate when the source text is empty
+			if row[1] == "" {
+				continue
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	// csv.Writer buffers; Flush writes out and Error reports any failure
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportTranslations reads a translation file for lang and applies every
+// non-empty translation. Rows with unknown keys are reported together
+// in the returned error; the valid rows are still applied.
+func ImportTranslations(r io.Reader, lang string, items map[string]Translatable) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(translationHeader)
+	records, err := cr.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("translation file is empty")
+	}
+
+	// Gather both fields of an item first, then store them together
+	updates := make(map[string]Translation)
+	var errs []error
+	for i, rec := range records[1:] {
+		key, text := rec[0], rec[2]
+		if text == "" {
+			continue
+		}
+		dot := strings.LastIndexByte(key, '.')
+		if dot < 0 {
+			errs = append(errs, fmt.Errorf("line %d: key %q has no .title or .description", i+2, key))
+			continue
+		}
+		itemKey, field := key[:dot], key[dot+1:]
+		item, ok := items[itemKey]
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: unknown item %q", i+2, itemKey))
+			continue
+		}
+		tr, seen := updates[itemKey]
+		if !seen {
+			tr, _ = item.Translation(lang)
+		}
+		switch field {
+		case "title":
+			tr.Title = text
+		case "description":
+			tr.Description = text
+		default:
+			errs = append(errs, fmt.Errorf("line %d: unknown field %q", i+2, field))
+			continue
+		}
+		updates[itemKey] = tr
+	}
+
+	// Sorted keys make the order of reported errors predictable
+	for _, itemKey := range slices.Sorted(maps.Keys(updates)) {
+		tr := updates[itemKey]
+		// A description without a title keeps the original title
+		if tr.Title == "" {
+			tr.Title = items[itemKey].LocalizedTitle(DefaultLanguage)
+		}
+		if err := items[itemKey].SetTranslation(lang, tr); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", itemKey, err))
+		}
+	}
+	// errors.Join returns nil when there is nothing to report
+	return errors.Join(errs...)
+}
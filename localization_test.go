@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestParseLanguagePreferences(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8", []string{"fr-ch", "fr", "en"}},
+		{"en;q=0.5, de", []string{"de", "en"}},
+		{"de;q=0, *, pt_BR", []string{"pt-br"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, ParseLanguagePreferences(tt.header), tt.want)
+	}
+}
+
+func TestLocalizedFallbacks(t *testing.T) {
+	book := Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(12.99), ""))
+	book.Description = "A boy wizard"
+	assert.NoError(t, book.SetTranslation("pt", Translation{Title: "Harry Potter e a Pedra"}))
+	assert.Equal(t, book.LocalizedTitle("pt-BR"), "Harry Potter e a Pedra")
+	// No translated description: the original is kept
+	assert.Equal(t, book.LocalizedDescription("pt"), "A boy wizard")
+	assert.Equal(t, book.LocalizedTitle("de"), "Harry Potter")
+
+	text, lang := localizeFor(book, []string{"de", "pt-br"})
+	assert.Equal(t, lang, "pt-br")
+	assert.Equal(t, text, Translation{Title: "Harry Potter e a Pedra", Description: "A boy wizard"})
+	_, lang = localizeFor(book, []string{"de"})
+	assert.Equal(t, lang, DefaultLanguage)
+}
+
+func TestImportTranslationsDottedKeys(t *testing.T) {
+	items := map[string]Translatable{
+		"vol.2": Must(NewMagazine("Vogue", Dollars(12.99), 2)),
+		"hp":    Must(NewBook("Harry Potter", "J.K. Rowling", Dollars(12.99), "")),
+	}
+	file := `key,source,translation
+vol.2.title,Vogue,Vogue Paris
+hp.description,,Un garçon sorcier
+hp,Harry Potter,Oops
+`
+	err := ImportTranslations(strings.NewReader(file), "fr", items)
+	if err == nil || !strings.Contains(err.Error(), `line 4: key "hp"`) {
+		t.Errorf("got error %v, want one for line 4", err)
+	}
+	assert.Equal(t, items["vol.2"].LocalizedTitle("fr"), "Vogue Paris")
+	assert.Equal(t, items["hp"].LocalizedTitle("fr"), "Harry Potter")
+	assert.Equal(t, items["hp"].LocalizedDescription("fr"), "Un garçon sorcier")
+}
+
+func TestServerAcceptLanguage(t *testing.T) {
+	catalog := sampleCatalog()
+	book := Must(catalog.Get("BK-001")).(*Book)
+	assert.NoError(t, book.SetTranslation("fr", Translation{Title: "Harry Potter à l'école des sorciers"}))
+	server := Must(NewCatalogServer(catalog))
+
+	tests := []struct {
+		header, title, language string
+	}{
+		{"", "Harry Potter", "en"},
+		{"fr-CH, fr;q=0.9", "Harry Potter à l'école des sorciers", "fr-ch"},
+		{"de, fr;q=0.5", "Harry Potter à l'école des sorciers", "fr"},
+		{"de", "Harry Potter", "en"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/items/BK-001", nil)
+		req.Header.Set("Accept-Language", tt.header)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		var resp struct{ Title, Language string }
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, resp.Title, tt.title)
+		assert.Equal(t, resp.Language, tt.language)
+		assert.Equal(t, rec.Header().Get("Vary"), "Accept-Language")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+// ------------------- LRU CACHE -------------------------------
+// An LRU (least recently used) cache holds at most Capacity entries;
+// when it is full, adding one drops the entry nobody has used for the
+// longest time. Python has this built in as functools.lru_cache.
+//
+// Two structures work together: a map finds an entry in O(1), and a
+// doubly linked list (container/list) keeps entries in order of use,
+// most recent at the front. Using an entry moves it to the front, so
+// the one to drop is always at the back.
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRUCache maps keys to values, forgetting the least recently used
+// ones beyond its capacity. It is safe for concurrent use.
+type LRUCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[K]*list.Element
+	// order holds *lruEntry values, most recently used first
+	order *list.List
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewLRUCache holds up to capacity entries; 0 caches nothing
+func NewLRUCache[K comparable, V any](capacity int) *LRUCache[K, V] {
+	return &LRUCache[K, V]{
+		capacity: max(capacity, 0),
+		entries:  make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value under key and marks it as just used
+func (c *LRUCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry[K, V]).value, true
+}
+
+// Put stores value under key, dropping the least recently used entry
+// if the cache is full
+func (c *LRUCache[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity == 0 {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+}
+
+// Remove forgets key and reports whether it was cached
+func (c *LRUCache[K, V]) Remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	return ok
+}
+
+// Len returns the number of cached entries
+func (c *LRUCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -13,13 +13,21 @@ package main
 // Unlike Python, you can't use a package without importing it
 // The import syntax uses quotes, different from Python's plain imports
 import (
+	// flag parses command-line options, similar to Python's argparse
+	"flag"
+
 	// fmt is Go's standard package for formatted I/O operations
 	// similar to Python's print() and string formatting
 	"fmt"
 
-	// math/rand is for random number generation
-	// notice how sub-packages use "/" unlike Python's "."
-	"math/rand"
+	// os gives access to the process: arguments, exit codes, files
+	"os"
+
+	// strings has helpers like Python's str methods
+	"strings"
+
+	// time handles dates, durations and clocks
+	"time"
 )
 
 // ------------------- INTERFACES ---------------------------
@@ -28,14 +36,20 @@ import (
 // 2. They are implemented implicitly (no "implements" keyword needed)
 // 3. They are typically small, often just 1-2 methods
 // 4. They are satisfied by any type that implements all their methods
+//
+// Because satisfaction is implicit, a typo in a method name only shows up
+// where the type is used. assertions_gen.go pins every implementer down
+// at compile time; regenerate it after adding a new item type:
+//go:generate go run ./cmd/genassert
 type PricedItem interface {
     // Method declarations show:
     // - Name of method
     // - Return type(s) after the parentheses
     // - No function body (just declarations)
-    Price() float64
-    SetPrice(price float64) error
-    CalculateDiscount(percentage float64) (float64, error)
+    // Money is an exact amount in integer cents (see money.go)
+    Price() Money
+    SetPrice(price Money) error
+    CalculateDiscount(percentage Percent) (Money, error)
 }
 
 // ------------------- STRUCTS -----------------------------
@@ -51,9 +65,17 @@ type Book struct {
     // uppercase = public (exported)
     title      string  // private, like Python's _title
     author     string  // private, like Python's _author
-    price      float64 // private, like Python's _price
+    price      Money   // private, like Python's _price
     pageCount  int     // private, like Python's _page_count
     Seller     string  // public, like Python's seller (no underscore)
+    Description string // public, optional blurb shown in listings
+    excerpt    []byte  // gzip-compressed first chapter, see excerpt.go
+
+    // An embedded type has no field name; its methods become Book's
+    // methods (see localization.go). This is composition, not inheritance
+    translations
+    annotations // internal staff notes, see notes.go
+    priceLog    // every price change, see price_history.go
 }
 
 // ------------------- CONSTANTS ---------------------------
@@ -62,18 +84,21 @@ type Book struct {
 // Naming convention: Use MixedCaps or ALL_CAPS for constants
 const CategoryCode = "BOOK"
 
+// Each item type has its own category code
+const MagazineCategoryCode = "MAGAZINE"
+
 // ------------------- CONSTRUCTORS ------------------------
 // Go doesn't have built-in constructors like Python's __init__
 // Instead, we use factory functions, typically prefixed with "New"
 // This is a common Go pattern for object creation
-func NewBook(title, author string, price float64, seller string) *Book {
+func NewBook(title, author string, price Money, seller string) (*Book, error) {
     // The * before Book means this returns a pointer
     // Pointers are a core Go concept with no Python equivalent
     // They hold the memory address of values
     
-    // Return a new Book instance
+    // Create a new Book instance
     // The & operator creates a pointer to the struct
-    return &Book{
+    b := &Book{
         // Field initialization uses name: value syntax
         // Similar to Python's kwargs but with colons
         title:     title,
@@ -82,6 +107,12 @@ func NewBook(title, author string, price float64, seller string) *Book {
         pageCount: randomPageCount(),
         Seller:    seller,
     }
+    // Instead of raising ValueError from __init__, a Go constructor
+    // returns an error next to the value (see validation.go)
+    if err := b.Validate(); err != nil {
+        return nil, err
+    }
+    return b, nil
 }
 
 // ------------------- METHODS -----------------------------
@@ -91,14 +122,14 @@ func NewBook(title, author string, price float64, seller string) *Book {
 // or value (Book) receiver
 func (b *Book) Summary() string {
     // fmt.Sprintf is like Python's f-strings
-    // %.2f formats float with 2 decimal places
-    return fmt.Sprintf("%s by %s - $%.2f", b.title, b.author, b.price)
+    // %v uses the value's String method, like Python's __str__
+    return fmt.Sprintf("%s by %s - %v", b.title, b.author, b.price)
 }
 
 // Interface implementation for Book
 // Notice how we don't need to explicitly state that we're
 // implementing PricedItem - Go does this implicitly
-func (b *Book) Price() float64 {
+func (b *Book) Price() Money {
     return b.price
 }
 
@@ -107,24 +138,50 @@ func (b *Book) Price() float64 {
 // 1. No try/except blocks
 // 2. Errors are return values, not exceptions
 // 3. Multiple return values are common (value, error)
-func (b *Book) SetPrice(price float64) error {
+func (b *Book) SetPrice(price Money) error {
+    // Go has no default arguments; a second method fills one in instead
+    return b.SetPriceBecause(price, "")
+}
+
+// SetPriceBecause also records why the price changed (see price_history.go)
+func (b *Book) SetPriceBecause(price Money, reason string) error {
     // Error checking is explicit
-    if price < 0 {
-        // fmt.Errorf creates a new error with formatted text
-        return fmt.Errorf("price cannot be negative")
+    if price.IsNegative() {
+        // ErrNegativePrice is a sentinel error value (see errors.go);
+        // callers check for it with errors.Is
+        return ErrNegativePrice
     }
+    b.recordPrice(b, b.price, price, reason)
     b.price = price
     // nil is Go's equivalent of None
     return nil
 }
 
-func (b *Book) CalculateDiscount(percentage float64) (float64, error) {
+// Getter and setter for the page count
+// Go doesn't have Python's @property; plain methods do the job
+func (b *Book) PageCount() int {
+    return b.pageCount
+}
+
+// The longest books ever printed run to a few tens of thousands of pages,
+// so anything bigger is almost certainly a data entry error
+const MaxPageCount = 100000
+
+func (b *Book) SetPageCount(count int) error {
+    if count <= 0 || count > MaxPageCount {
+        return fmt.Errorf("page count must be between 1 and %d", MaxPageCount)
+    }
+    b.pageCount = count
+    return nil
+}
+
+func (b *Book) CalculateDiscount(percentage Percent) (Money, error) {
     // Multiple return values are idiomatic in Go
     // This is different from Python's single return value
-    if percentage < 0 || percentage > 100 {
-        return 0, fmt.Errorf("percentage must be between 0 and 100")
-    }
-    return b.price * (1 - percentage/100), nil
+    // No range check here: a Percent is validated when it is created
+    // The discount math itself lives in a DiscountPolicy (discounts.go)
+    discounted, _ := PercentageDiscount{Percent: percentage}.Apply(b.price, DiscountContext{Item: b})
+    return discounted, nil
 }
 
 // ------------------- HELPER FUNCTIONS --------------------
@@ -134,131 +191,554 @@ func GetCategoryCode() string {
     return CategoryCode
 }
 
+// Category reports which category the book belongs to
+func (b *Book) Category() string {
+    return CategoryCode
+}
+
 // Private helper function (lowercase name)
 func randomPageCount() int {
-    // rand.Intn(n) generates 0 to n-1
+    // randomIntn(n) generates 0 to n-1, like rand.Intn, but can be
+    // made repeatable (see deterministic.go)
     // Adding 100 gives us 100 to 1000
-    return rand.Intn(901) + 100
+    return randomIntn(901) + 100
 }
 
 // ------------------- MULTIPLE TYPES ---------------------
 // Go encourages small, focused types that satisfy interfaces
 type Magazine struct {
     name        string
-    price       float64
+    price       Money
     issueNumber int
+    Description string
+    translations
+    annotations
+    priceLog
 }
 
 // Constructor for Magazine
-func NewMagazine(name string, price float64, issueNumber int) *Magazine {
-    return &Magazine{
+func NewMagazine(name string, price Money, issueNumber int) (*Magazine, error) {
+    m := &Magazine{
         name:        name,
         price:       price,
         issueNumber: issueNumber,
     }
+    if err := m.Validate(); err != nil {
+        return nil, err
+    }
+    return m, nil
+}
+
+// Category reports which category the magazine belongs to
+func (m *Magazine) Category() string {
+    return MagazineCategoryCode
 }
 
 // Magazine methods implementing PricedItem interface
-func (m *Magazine) Price() float64 {
+func (m *Magazine) Price() Money {
     return m.price
 }
 
-func (m *Magazine) SetPrice(price float64) error {
-    if price < 0 {
-        return fmt.Errorf("price cannot be negative")
+func (m *Magazine) SetPrice(price Money) error {
+    return m.SetPriceBecause(price, "")
+}
+
+func (m *Magazine) SetPriceBecause(price Money, reason string) error {
+    if price.IsNegative() {
+        return ErrNegativePrice
     }
+    m.recordPrice(m, m.price, price, reason)
     m.price = price
     return nil
 }
 
-func (m *Magazine) CalculateDiscount(percentage float64) (float64, error) {
-    if percentage < 0 || percentage > 100 {
-        return 0, fmt.Errorf("percentage must be between 0 and 100")
-    }
-    baseDiscount := m.price * (1 - percentage/100)
-    if m.price > 10 {
-        return baseDiscount * 0.9, nil
-    }
-    return baseDiscount, nil
+// Extra rules such as "another 10% off magazines over $10" are no
+// longer hard-coded here; express them as policies in a PricingEngine
+func (m *Magazine) CalculateDiscount(percentage Percent) (Money, error) {
+    discounted, _ := PercentageDiscount{Percent: percentage}.Apply(m.price, DiscountContext{Item: m})
+    return discounted, nil
 }
 
 // ------------------- INTERFACE USAGE -------------------
 // This function demonstrates polymorphism in Go
 // It accepts any type that implements PricedItem
-func printItemPriceInfo(item PricedItem) {
+// Prices are shown in currency too when it isn't the item's own
+// (see currency.go); converter may be nil to skip that
+func printItemPriceInfo(item PricedItem, converter *CurrencyConverter, currency string) {
     // Direct price access through interface method
-    fmt.Printf("Original price: $%.2f\n", item.Price())
+    fmt.Printf("Original price: %s\n", inCurrency(item.Price(), converter, currency))
+    // A type assertion asks "does this item ALSO have PriceNote?"
+    // ok is false for items that don't, like Python's hasattr()
+    if explainer, ok := item.(PriceExplainer); ok && explainer.PriceNote() != "" {
+        fmt.Printf("Note: %s\n", explainer.PriceNote())
+    }
     
     // Error handling in Go is explicit and required
-    discounted, err := item.CalculateDiscount(20)
+    discounted, err := item.CalculateDiscount(MustPercent(20))
     // if err != nil is the most common error check in Go
     if err != nil {
         fmt.Printf("Error calculating discount: %v\n", err)
         return
     }
-    fmt.Printf("Price with 20%% discount: $%.2f\n", discounted)
+    fmt.Printf("Price with 20%% discount: %s\n", inCurrency(discounted, converter, currency))
+}
+
+// inCurrency formats m, followed by its value in currency if that differs
+func inCurrency(m Money, converter *CurrencyConverter, currency string) string {
+    if converter == nil || strings.EqualFold(m.Currency(), currency) {
+        return m.String()
+    }
+    converted, err := converter.Convert(m, currency)
+    if err != nil {
+        return fmt.Sprintf("%v (%s unavailable: %v)", m, currency, err)
+    }
+    return fmt.Sprintf("%v (%v)", m, converted)
 }
 
 // ------------------- MAIN FUNCTION ---------------------
 // main() is the entry point of a Go program
 // Like Python's if __name__ == "__main__":
 func main() {
-    // := is a shorthand declaration operator
-    // It declares and initializes variables in one step
-    harryPotter := NewBook("Harry Potter", "J.K. Rowling", 10.99, "Flourish & Blotts")
-
-    // Calling methods uses dot notation like Python
-    fmt.Println(harryPotter.Summary())
-
-    // Public fields can be accessed directly
-    fmt.Println("Original Seller:", harryPotter.Seller)
-    harryPotter.Seller = "Obscurus Books"
-    fmt.Println("New Seller:", harryPotter.Seller)
-
-    // Error handling pattern in Go:
-    // 1. Call function that returns error
-    // 2. Check if error is nil
-    // 3. Handle error if present
-    if err := harryPotter.SetPrice(12.99); err != nil {
-        fmt.Println("Error:", err)
+    // The flag package parses command-line options, like Python's argparse
+    // flag.Duration understands values such as "2s" or "500ms"
+    pause := flag.Duration("pause", 2*time.Second, "delay between steps of the demo command")
+    store := flag.String("store", "", "JSON file the commands load the catalog from and save it to")
+    deterministic := flag.Bool("deterministic", false, "freeze the clock and randomness, for reproducible output")
+    events := flag.Bool("events", false, "print every domain event (price changes, restocks...) to stderr")
+    flag.Parse()
+    if *deterministic || deterministicRequested() {
+        EnableDeterministicMode()
+    }
+    // A console subscriber sees everything published on the event bus
+    // (see events.go); stderr keeps it apart from command output
+    if *events {
+        Events.SubscribeAll(LogEvents(os.Stderr))
     }
 
-    fmt.Println(harryPotter.Summary())
-    fmt.Println("Price:", harryPotter.Price())
-    fmt.Println("Category Code:", GetCategoryCode())
-
-    // Creating a magazine instance
-    vogue := NewMagazine("Vogue", 12.99, 123)
-
-    fmt.Println("\n=== Demonstrating interface behavior ===")
-    fmt.Println("Book pricing:")
-    printItemPriceInfo(harryPotter)
-
-    fmt.Println("\nMagazine pricing:")
-    printItemPriceInfo(vogue)
+    // flag.Arg(0) is the first argument after the options ("" if none)
+    switch flag.Arg(0) {
+    case "demo":
+        // Narrated tour with pauses, for reading along
+        runDemo(*pause)
+    case "":
+        // Plain run: the same tour without pauses
+        runDemo(0)
+    default:
+        if _, ok := commands[flag.Arg(0)]; !ok && flag.Arg(0) != "shell" {
+            fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+            printUsage(os.Stderr)
+            // A non-zero exit code tells the shell the program failed
+            os.Exit(2)
+        }
+        // flag.Args() is everything after the options, command name first
+        if err := runCLI(*store, flag.Args()); err != nil {
+            fmt.Fprintln(os.Stderr, "Error:", err)
+            os.Exit(1)
+        }
+    }
 }
 
 /* ------------------- EXAMPLE OUTPUT -------------------
 
-Running this program will produce output similar to:
+Single commands such as "go run . list" or "go run . discount -sku BK-001
+-percent 20" work on a sample catalog; "go run . shell" reads commands
+from stdin (see cli.go). Add "-store catalog.json" to keep the catalog
+between runs.
+
+Running this program (go run .) will produce output similar to the text
+below, which is exactly what "go run . -deterministic" prints:
+Use "go run . demo" for the same tour with a pause between steps.
 
+=== Step 1/41: Creating items and a catalog ===
+NewBook is a factory function; the catalog keeps items under unique SKUs.
+-------------------------------------------------------------------------
 Harry Potter by J.K. Rowling - $10.99
 Original Seller: Flourish & Blotts
 New Seller: Obscurus Books
+Error: invalid book "": title is required; price cannot be negative
+Negative price? true
 Harry Potter by J.K. Rowling - $12.99
-Price: 12.99
+Price: $12.99
 Category Code: BOOK
-
-=== Demonstrating interface behavior ===
-Book pricing:
-Original price: $12.99
-Price with 20% discount: $10.39
-
-Magazine pricing:
-Original price: $12.99
-Price with 20% discount: $9.35
-
-Note: The page count will be random each time you run the program.
+Error: SKU "BK-001" is already in the catalog
+Catalog SKUs: [BK-001 MG-001]
+No item with SKU BK-404
+
+=== Step 2/41: Interfaces and discounts ===
+Book, Magazine and AudioBook all satisfy PricedItem, so the same code prices each of them.
+------------------------------------------------------------------------------------------
+BK-001 pricing:
+Original price: $12.99 (€11.95)
+Price with 20% discount: $10.39 (€9.56)
+
+MG-001 pricing:
+Original price: $12.99 (€11.95)
+Price with 20% discount: $10.39 (€9.56)
+
+Harry Potter by J.K. Rowling, read by Stephen Fry (8h 24m) - $21.00
+Original price: $21.00 (€19.32)
+Price with 20% discount: $16.80 (€15.46)
+
+Harry Potter by J.K. Rowling, read by Stephen Fry (8h 24m) - 1 credit
+Original price: $0.00 (€0.00)
+Note: included with a subscription credit ($21.00 to buy)
+Price with 20% discount: $0.00 (€0.00)
+
+=== Step 3/41: Generic collections ===
+Collection[T] works for any PricedItem type; with T = *Book no type assertions are needed.
+------------------------------------------------------------------------------------------
+  $9.99    Frank Herbert
+  $12.99   J.K. Rowling
+  $34.99   Alan Donovan
+Under $20: [Harry Potter Dune]
+Catalog: 2 items worth $25.98, cheapest Harry Potter
+
+=== Step 4/41: E-books ===
+EBook is a third PricedItem; the cart prices it without knowing what it is.
+---------------------------------------------------------------------------
+Harry Potter by J.K. Rowling (EPUB, 2.4 MB) - $7.99
+In stock without any inventory: true
+Cart with paper edition: false total $7.99
+Cart with paper edition: true  total $16.99
+
+=== Step 5/41: Bundles ===
+A Bundle is a PricedItem made of PricedItems, so bundles can hold bundles.
+--------------------------------------------------------------------------
+Error: bundle "Paper + e-book" cannot contain itself
+Paper + e-book (2 items: [Harry Potter Harry Potter]) - $15.74, 25% off buying them separately
+Collector's box (2 items: [Paper + e-book Vogue]) - $25.86, 10% off buying them separately
+Box set: $25.86 instead of $28.73, saving $2.87
+Box set with 20% off: $20.68
+Error: a bundle's price is the sum of its contents
+
+=== Step 6/41: Discount policies ===
+A PricingEngine combines policies; its stacking rule settles conflicts between them.
+------------------------------------------------------------------------------------
+BK-001 x1: $12.99 -> $10.39 (Spring sale (20% off))
+BK-001 x10: $12.99 -> $9.87 (Spring sale (20% off) + 5% off 10+ units)
+MG-001 x1: $12.99 -> $9.35 (Spring sale (20% off) + 10% off MAGAZINE over $10.00)
+MG-001 x10: $12.99 -> $8.88 (Spring sale (20% off) + 10% off MAGAZINE over $10.00 + 5% off 10+ units)
+
+BK-001 x10 for a member with coupon SPRING10, during a 15% sale:
+stack-all         $9.44 (coupon SPRING10 (10% off) + store sale + 5% off 10+ units)
+best-for-customer $11.04 (store sale)
+best-for-store    $12.34 (5% off 10+ units)
+additive-with-cap $9.74 (coupon SPRING10 (10% off) + store sale + member price + 5% off 10+ units, capped at 25%)
+
+=== Step 7/41: Catalog drift detection ===
+One hash per catalog tells whether two copies match; item hashes tell where.
+----------------------------------------------------------------------------
+Roots match: false
+  BK-001: changed
+  BK-999: extra
+  MG-001: missing
+After repair, roots match: true
+
+=== Step 8/41: Concurrent catalog ===
+Goroutines change prices while others read them; an RWMutex keeps the catalog consistent.
+-----------------------------------------------------------------------------------------
+3 writers made 60 price changes while 5 readers made 300 reads, 0 of them bad
+  BK-000 ends at $11.00
+  BK-001 ends at $11.00
+  BK-002 ends at $11.00
+
+=== Step 9/41: Bulk repricing ===
+A pool of worker goroutines reprices the whole catalog; each item's error is kept.
+----------------------------------------------------------------------------------
+Changed 2, unchanged 1, failed 1
+Errors: MG-001: magazine prices are set by the publisher
+  BK-001 Dune: $10.99
+  BK-002 Emma: $8.79
+  EB-001 Dune: $4.99
+  MG-001 Vogue: $12.99
+
+=== Step 10/41: Cancellation and timeouts ===
+A context.Context carries a deadline; slow work checks ctx.Done() and gives up.
+-------------------------------------------------------------------------------
+Changed 2, skipped 1
+Stopped: EB-001: context deadline exceeded
+1 skipped: context deadline exceeded
+The deadline passed; the rest of the catalog was left alone
+
+=== Step 11/41: Signed page cursors ===
+Page tokens carry an HMAC signature, so clients cannot forge them.
+------------------------------------------------------------------
+Page 1: [BK-001]
+Page 2: [MG-001] last page: true
+Tampered: invalid cursor: bad signature
+An hour later: invalid cursor: token expired
+
+=== Step 12/41: HTTP API ===
+Handlers map catalog errors to status codes; try "go run . serve".
+------------------------------------------------------------------
+GET /items/MG-001 -> 200 {"sku":"MG-001","category":"MAGAZINE","item":{"name":"Vogue","price":12.99,"issueNumber":123},"title":"Vogue","language":"en"}
+PUT /items/BK-001/price -> 422 {"error":"price cannot be negative"}
+POST /items/BK-001/discount -> 200 {"currency":"USD","discounted":6.50,"price":12.99}
+GET /items/XX-404 -> 404 {"error":"item \"XX-404\" not found"}
+POST /batch -> 409 {"committed":false,"results":[{"op":"adjust_stock","sku":"MG-001","status":200,"rolled_back":true},{"op":"update_price","sku":"MG-001","status":422,"error":"price cannot be negative"}]}
+
+=== Step 13/41: Book previews ===
+Excerpts are stored gzip-compressed and served on their own, with Range support.
+--------------------------------------------------------------------------------
+Excerpt: 4960 bytes, stored in 148
+GET /items/BK-001 -> 200 {"sku":"BK-001","category":"BOOK","item":{"title":"Harry Potter","author":"J.K. Rowling","price":12.99,"pageCount":407,"seller":"Obscurus Books"},"hasPreview":true,"title":"Harry Potter","language":"en"}
+GET /items/BK-001/preview (Range: bytes=0-59) -> 206 bytes 0-59/4960
+"Mr. and Mrs. Dursley, of number four, Privet Drive, were pro"
+
+=== Step 14/41: Shopping cart ===
+Checkout freezes the cart's prices, plus tax per rate, into an Order with a fixed lifecycle.
+--------------------------------------------------------------------------------------------
+Subtotal $142.89, with discounts $136.39
+  Harry Potter x1  $12.99 each []
+  Vogue        x10 $12.34 each [5% off 10+ units]
+  VAT 7%           $0.91 on $12.99
+  VAT 19%          $23.45 on $123.40
+Order total $160.75; cart now has 0 lines
+Checkout again: cannot check out an empty cart
+Error: cannot move a shipped order to cancelled
+  Mar 15 10:05  pending -> paid
+  Mar 15 16:00  paid -> shipped
+  Mar 17 10:00  shipped -> delivered
+
+=== Step 15/41: Gapless order numbers ===
+Each store numbers its orders 1, 2, 3... with no gaps or repeats, even across restarts.
+---------------------------------------------------------------------------------------
+AIRPORT 10 orders, AIRPORT-000001 to AIRPORT-000010, gapless: true
+MAIN    20 orders, MAIN-000001 to MAIN-000020, gapless: true
+After a restart:
+Order MAIN-000021
+Harry Potter x1  $12.99
+TOTAL            $12.99
+
+=== Step 16/41: Member prices ===
+Member prices and member-only promotions are discount policies that check the customer.
+---------------------------------------------------------------------------------------
+Member: false
+Harry Potter x1  $12.99
+Vogue x2         $25.98
+TOTAL            $38.97
+Member: true
+Harry Potter x1  $9.99   ($9.99 each, was $12.99)
+Vogue x2         $23.38  ($11.69 each, was $12.99)
+TOTAL            $33.37
+You saved $5.60 today!
+
+=== Step 17/41: Quotes for business customers ===
+A quote locks today's prices for N days; converting it later ignores price changes.
+-----------------------------------------------------------------------------------
+QUOTE Q-7 for Acme Corp
+Issued 2024-03-15, valid until 2024-04-14
+ITEM              QTY  LIST   QUOTED  AMOUNT
+The Economist     50   $8.99  $8.54   $427.00
+Total before tax                      $427.00
+Ordered at $8.54 each, total $427.00 (list price now $9.99)
+Two months later: quote Q-8: quote has expired on 2024-04-14
+
+=== Step 18/41: JSON round trip ===
+MarshalJSON exposes private fields through a DTO; notes stay internal.
+----------------------------------------------------------------------
+{"name":"Vogue","price":12.99,"issueNumber":123}
+Restored: Vogue, $12.99
+Rejected: book "Bad": price cannot be negative
+
+=== Step 19/41: Catalog CSV ===
+Books and magazines go out and come in as CSV; bad rows are reported and skipped.
+---------------------------------------------------------------------------------
+type,sku,title,author,price,currency,pages,issue,seller,description
+book,BK-001,Harry Potter,J.K. Rowling,12.99,USD,407,,Obscurus Books,
+magazine,MG-001,Vogue,,12.99,USD,,123,,
+line 3 skipped: price "cheap" is not an amount like 9.99
+line 5 skipped: type "comic" is not book or magazine
+Imported 2 items: Dune, Part One; The "New" Yorker
+
+=== Step 20/41: Inventory and selling out ===
+Reserve takes units from stock; asking for more than is left is an error.
+-------------------------------------------------------------------------
+Vogue in stock: true - available: 3
+Vogue in stock: false - available: 0
+Sold out: cannot reserve 1, only 0 available
+
+=== Step 21/41: Expiring reservations ===
+A hold on stock lives in a TTLStore; unless paid in time, it expires and the copies go back.
+--------------------------------------------------------------------------------------------
+Held for 15 minutes, available: 2
+cart-1 paid; cart-2 has 5m0s left
+cart-2's hold expired, 1 back on the shelf
+Available: 3
+
+=== Step 22/41: Packs and single copies ===
+Sealed packs are counted in units too; breaking one is just bookkeeping.
+------------------------------------------------------------------------
+Received:              34 available = 3 sealed packs + 4 loose
+After 1 pack:          24 available = 2 sealed packs + 4 loose
+After 6 copies:        18 available = 1 sealed packs + 8 loose
+Opened 1 pack(s) into 10 copies at 10:00
+
+=== Step 23/41: Reorder points ===
+Reorder when stock <= lead time x daily sales + safety stock.
+-------------------------------------------------------------
+ITEM          SUPPLIER    AVAILABLE  PER DAY  REORDER AT  
+Harry Potter  Bloomsbury  20         2.0      19          
+Vogue         Condé Nast  15         0.5      4           
+
+=== Step 24/41: Purchase orders ===
+Deliveries arrive in parts; stock is valued oldest lot first (FIFO).
+--------------------------------------------------------------------
+PO PO-1001 to Bloomsbury: partially received, total $330.00
+ITEM          ORDERED  RECEIVED  OUTSTANDING  UNIT COST
+Harry Potter  30       15        15           $9.50
+Vogue         20       20        0            $2.25
+Error: purchase order PO-1001: received 20, only 15 outstanding
+Status: received, supplier lead time now 144h0m0s
+Harry Potter stock: 40 units worth $365.00
+After selling 12:   28 units worth $266.00
+
+=== Step 25/41: Values vs pointers ===
+Assigning a struct copies it; only pointers share (see value_semantics.go).
+---------------------------------------------------------------------------
+Copy is independent of original: true
+Pointer alias shares changes:    true
+Value parameter is a copy:       true
+Book value is a PricedItem:      false
+*Book is a PricedItem:           true
+Copy detected by guard:          true
+
+=== Step 26/41: Localization ===
+Translations fall back to the base language, then to the original title.
+------------------------------------------------------------------------
+fr-ch: Harry Potter à l'école des sorciers
+de: Harry Potter
+
+=== Step 27/41: Deal of the day ===
+A date-seeded weighted pick: every server agrees on today's deal.
+-----------------------------------------------------------------
+Today's deal: Harry Potter ($12.99)
+
+=== Step 28/41: Order cutoff and shipping ===
+Orders after 17:00 or on closed days ship on the next business day.
+-------------------------------------------------------------------
+Friday 10:00: Order now and it ships today
+Saturday 17:30: Ships on Tuesday, Mar 19
+
+=== Step 29/41: Internal notes ===
+Staff notes keep their edit history and never show up in Summary().
+-------------------------------------------------------------------
+#1 by alex: damaged batch received, 3 copies returned (1 earlier version)
+
+=== Step 30/41: Overflow-safe arithmetic ===
+int64 math wraps around silently; checked_math.go catches it.
+-------------------------------------------------------------
+Huge order rejected: integer overflow
+Error: page count must be between 1 and 100000
+
+=== Step 31/41: Price change throttling ===
+At most 2 price changes per item per hour; errors.As reveals the wait.
+----------------------------------------------------------------------
+$11.99 accepted
+$10.99 accepted
+$9.99 rejected, retry in 40m0s
+
+=== Step 32/41: Price history ===
+Every SetPrice is logged with its reason; LowestPrice looks back N days.
+------------------------------------------------------------------------
+$10.99 -> $12.99: (no reason given)
+$12.99 -> $11.99: (no reason given)
+$11.99 -> $10.99: (no reason given)
+$10.99 -> $12.99: (no reason given)
+$12.99 -> $9.99: weekend promotion
+$9.99 -> $12.99: promotion over
+Lowest price in the last 30 days: $9.99
+
+=== Step 33/41: Domain events ===
+Price changes, restocks and orders are published on an EventBus; listeners subscribe.
+-------------------------------------------------------------------------------------
+[event] price-changed: The Hobbit: $14.99 -> $11.99 (clearance)
+[price-drop] The Hobbit now $11.99
+[event] restocked: The Hobbit: +2, 2 available
+[event] stock-depleted: The Hobbit: none available, 2 reserved
+[low-stock] The Hobbit is sold out
+[event] order-placed: 1 lines, total $23.98
+Thank-you email queued for an order of $23.98
+order-placed: 1
+price-changed: 1
+restocked: 1
+stock-depleted: 1
+
+=== Step 34/41: Transactional outbox ===
+A price and its event are saved together; a relay publishes the event at least once.
+------------------------------------------------------------------------------------
+Saved; events waiting in the outbox: 2
+[event] price-changed: The Hobbit: $14.99 -> $12.99 (clearance)
+First run: marking outbox-1 as published: relay crashed
+[event] price-changed: The Hobbit: $12.99 -> $11.99 (clearance)
+Second run published 2; 3 deliveries in all, 0 left in the outbox
+
+=== Step 35/41: Read-through cache ===
+An LRU cache wraps the repository; writes and sync events evict entries.
+------------------------------------------------------------------------
+  BK-001: Dune from the repository
+  BK-001: Dune from the cache
+  BK-002: Emma from the repository
+  BK-001: Dune from the repository
+  BK-001: Dune from the repository
+  BK-001: Dune from the cache
+  BK-001: Dune from the repository
+Stats: 2 hits, 5 misses (29% hit rate), 1 cached
+
+=== Step 36/41: Store-wide sale ===
+25% off everything except blacked-out items, never below an item's floor.
+-------------------------------------------------------------------------
+[sale] Sale on: 25% off!
+$12.99 -> $12.99
+$12.99 -> $9.74
+$40.00 -> $32.00
+[sale] Sale over
+
+=== Step 37/41: Price source aggregation ===
+Quotes far from the median (in MAD units) are rejected before pricing.
+----------------------------------------------------------------------
+Reference price: $12.99 from 3 sources
+Rejected broken-feed: 0.01 is more than 3.0 MAD from median 12.74
+
+=== Step 38/41: Automatic repricing ===
+Opted-in items undercut the cheapest trusted competitor, down to a floor.
+-------------------------------------------------------------------------
+DRY RUN - no prices were changed
+ITEM          OLD     NEW     LOWEST COMPETITOR  NOTE
+Harry Potter  $12.99  $12.39  bookshop $12.49    undercut
+Vogue         $12.99  $12.50  newsstand $11.99   held at floor
+
+=== Step 39/41: Demand pricing ===
+Prices follow days of cover, moving only when two runs in a row agree.
+----------------------------------------------------------------------
+Run 1:
+ITEM       OLD     NEW     SOLD/DAY  STOCK  COVER       NOTE
+Dune       $9.99   $9.99   0.57      2      3.5 days    waiting (1 of 2 runs)
+Moby Dick  $14.99  $14.99  0.07      39     546.0 days  waiting (1 of 2 runs)
+Run 2:
+ITEM       OLD     NEW     SOLD/DAY  STOCK  COVER       NOTE
+Dune       $9.99   $10.49  0.57      2      3.5 days    raise
+Moby Dick  $14.99  $14.24  0.07      39     546.0 days  lower
+Moby Dick history: $14.99 -> $14.24 (demand pricing: 546.0 days of cover)
+
+=== Step 40/41: Roles and impersonation ===
+One Authorizer checks every action and audits the decision.
+-----------------------------------------------------------
+Denied: permission denied: sam (clerk) may not change the price of BK-001 (needs prices:edit)
+Allowed: sam (clerk, impersonated by alex) may restock MG-001
+Audit log:
+  10:00AM sam (clerk): change the price of BK-001 -> denied
+  10:00AM alex (admin): impersonate sam -> allowed
+  10:00AM sam (clerk, impersonated by alex): restock MG-001 -> allowed
+
+=== Step 41/41: Marketplace commission ===
+Commission depends on seller tier, category and date.
+-----------------------------------------------------
+standard seller: book $1.95, magazine $1.04
+gold seller: book $1.43, magazine $0.78
+
+Note: The page count will be random each time you run the program,
+and the deal of the day changes with the date.
 
 ------------------- ADDITIONAL GO CONCEPTS -------------------
 
@@ -267,6 +747,8 @@ Note: The page count will be random each time you run the program.
    - Much simpler than Python's threading/multiprocessing
    - Use 'go' keyword to start a goroutine
    - Channels for communication between goroutines
+   - See RepriceAll (bulk_reprice.go) for a worker pool built from
+     channels and sync.WaitGroup
 
 2. DEFER STATEMENT
    - defer delays execution until surrounding function returns
@@ -0,0 +1,78 @@
+package main
+
+// ------------------- MEMBERSHIP PRICING ----------------------
+// Members of the store's loyalty club pay less. Each item can have a
+// member price next to its list price, and some promotions are for
+// members only. Both are ordinary DiscountPolicies that look at
+// DiscountContext.Member, so a PricingEngine combines them with every
+// other discount, and non-members simply never see them:
+//
+//	members := NewMemberPricing()
+//	members.SetMemberPrice(book, Dollars(9.99))
+//	engine := NewPricingEngine(StackAll, members,
+//		MembersOnly{PercentageDiscount{Percent: MustPercent(5)}})
+//
+// Order.PrintReceipt then tells the customer how much they saved.
+
+import "fmt"
+
+// MemberPricing holds member prices, keyed by item like the other
+// per-item features
+type MemberPricing struct {
+	prices map[PricedItem]Money
+}
+
+func NewMemberPricing() *MemberPricing {
+	return &MemberPricing{prices: make(map[PricedItem]Money)}
+}
+
+// SetMemberPrice sets what members pay for item
+func (m *MemberPricing) SetMemberPrice(item PricedItem, price Money) error {
+	if price.IsNegative() {
+		return fmt.Errorf("member price: %w", ErrNegativePrice)
+	}
+	if _, err := price.Cmp(item.Price()); err != nil {
+		return fmt.Errorf("member price: %w", err)
+	}
+	m.prices[item] = price
+	return nil
+}
+
+// MemberPrice returns the member price of item, if it has one
+func (m *MemberPricing) MemberPrice(item PricedItem) (Money, bool) {
+	price, ok := m.prices[item]
+	return price, ok
+}
+
+func (m *MemberPricing) Name() string {
+	return "member price"
+}
+
+// Apply caps the price at the member price. A member price above the
+// current price (say, the list price dropped since) changes nothing.
+func (m *MemberPricing) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	if !ctx.Member {
+		return price, false
+	}
+	memberPrice, ok := m.prices[ctx.Item]
+	if !ok || !memberPrice.Less(price) {
+		return price, false
+	}
+	return memberPrice, true
+}
+
+// MembersOnly restricts any policy to members
+type MembersOnly struct {
+	Policy DiscountPolicy
+}
+
+func (d MembersOnly) Name() string {
+	return "members: " + d.Policy.Name()
+}
+
+func (d MembersOnly) Apply(price Money, ctx DiscountContext) (Money, bool) {
+	if !ctx.Member {
+		return price, false
+	}
+	return d.Policy.Apply(price, ctx)
+}
@@ -0,0 +1,209 @@
+package main
+
+// ------------------- MONEY -----------------------------------
+// float64 can't hold most decimal fractions exactly: 0.1 + 0.2 is
+// 0.30000000000000004, and a 20% discount on $10.99 is 8.792000000000002.
+// Money stores a whole number of minor units (cents) instead, plus the
+// currency, so prices are always exact and rounding happens in one
+// place, on purpose.
+//
+// Python's answer is decimal.Decimal; Go has no decimal type in the
+// standard library, so integer cents it is.
+//
+// Every currency is assumed to have 2 decimal places (cents, pence...).
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when a price doesn't say otherwise
+const DefaultCurrency = "USD"
+
+// ErrCurrencyMismatch is returned when combining different currencies
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// currencySymbols are printed before the amount; other currencies are
+// printed as "12.99 CHF"
+var currencySymbols = map[string]string{"USD": "$", "EUR": "€", "GBP": "£"}
+
+// Money is an exact amount in one currency.
+// The zero value is 0 in DefaultCurrency.
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// NewMoney returns minor units (cents) of currency, e.g. NewMoney(1299, "USD")
+func NewMoney(minor int64, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return Money{}, fmt.Errorf("currency %q must be a 3-letter code such as USD", currency)
+	}
+	if currency == DefaultCurrency {
+		currency = ""
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// Dollars converts a dollar amount written in the source code, such as
+// Dollars(12.99), rounding to the nearest cent
+func Dollars(v float64) Money {
+	return amountIn(v, "")
+}
+
+// amountIn converts a float amount from an outside source, such as a
+// competitor feed, to the nearest minor unit of currency
+func amountIn(v float64, currency string) Money {
+	return Money{minor: int64(math.Round(v * 100)), currency: currency}
+}
+
+// ParseMoney reads an exact decimal amount such as "12.99", "$12.99"
+// or "-3.5", without going through float64
+func ParseMoney(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	s = strings.TrimPrefix(s, currencySymbols[strings.ToUpper(currency)])
+	whole, frac, _ := strings.Cut(s, ".")
+	if !isDigits(whole) || len(frac) > 2 || (frac != "" && !isDigits(frac)) {
+		return Money{}, fmt.Errorf("amount %q is not a number with at most 2 decimals", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	// Only digits are left, so parsing can fail only by overflowing
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, ErrOverflow
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	minor, err := CheckedMul(units, 100)
+	if err == nil {
+		minor, err = CheckedAdd(minor, cents)
+	}
+	if err != nil {
+		return Money{}, err
+	}
+	if negative {
+		minor = -minor
+	}
+	return NewMoney(minor, currency)
+}
+
+// isDigits reports whether s is a non-empty run of 0-9
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// Minor returns the amount in minor units, e.g. 1299 for $12.99
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Currency returns the 3-letter currency code
+func (m Money) Currency() string {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+// Float64 is the amount as a float, for statistics and charts only;
+// never do money arithmetic on the result
+func (m Money) Float64() float64 {
+	return float64(m.minor) / 100
+}
+
+func (m Money) IsNegative() bool { return m.minor < 0 }
+func (m Money) IsZero() bool     { return m.minor == 0 }
+
+// Add returns m+o; both must be in the same currency
+func (m Money) Add(o Money) (Money, error) {
+	if m.currency != o.currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency(), o.Currency())
+	}
+	sum, err := CheckedAdd(m.minor, o.minor)
+	return Money{minor: sum, currency: m.currency}, err
+}
+
+// Sub returns m-o; both must be in the same currency
+func (m Money) Sub(o Money) (Money, error) {
+	if m.currency != o.currency {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrCurrencyMismatch, m.Currency(), o.Currency())
+	}
+	diff, err := CheckedSub(m.minor, o.minor)
+	return Money{minor: diff, currency: m.currency}, err
+}
+
+// Times multiplies by a quantity, e.g. unit price × 3 copies
+func (m Money) Times(qty int) (Money, error) {
+	product, err := CheckedMul(m.minor, int64(qty))
+	return Money{minor: product, currency: m.currency}, err
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or more than o
+func (m Money) Cmp(o Money) (int, error) {
+	if m.currency != o.currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency(), o.Currency())
+	}
+	switch {
+	case m.minor < o.minor:
+		return -1, nil
+	case m.minor > o.minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Less reports m < o. Amounts in different currencies are never less
+// than each other; use Cmp when that case matters.
+func (m Money) Less(o Money) bool {
+	c, err := m.Cmp(o)
+	return err == nil && c < 0
+}
+
+// Scale multiplies by a factor such as 0.8, rounding half away from zero
+// to whole minor units. This is the one place where prices are rounded.
+func (m Money) Scale(factor float64) Money {
+	return Money{minor: int64(math.Round(float64(m.minor) * factor)), currency: m.currency}
+}
+
+// Off returns m reduced by p: Dollars(10).Off(MustPercent(20)) is $8.00
+func (m Money) Off(p Percent) Money {
+	return m.Scale(1 - p.Fraction())
+}
+
+// Portion returns p of m: Dollars(10).Portion(MustPercent(20)) is $2.00
+func (m Money) Portion(p Percent) Money {
+	return m.Scale(p.Fraction())
+}
+
+// String prints "$12.99", "-€3.50" or "12.99 CHF"
+func (m Money) String() string {
+	sign, amount := m.parts()
+	if symbol, ok := currencySymbols[m.Currency()]; ok {
+		return sign + symbol + amount
+	}
+	return sign + amount + " " + m.Currency()
+}
+
+// Decimal prints the bare amount, e.g. "12.99" or "-3.50", for files and JSON
+func (m Money) Decimal() string {
+	sign, amount := m.parts()
+	return sign + amount
+}
+
+// parts splits m into its sign and the digits of its absolute value
+func (m Money) parts() (sign, amount string) {
+	// Negating as uint64 also works for math.MinInt64, whose absolute
+	// value doesn't fit in an int64
+	abs := uint64(m.minor)
+	if m.minor < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	return sign, fmt.Sprintf("%d.%02d", abs/100, abs%100)
+}
@@ -0,0 +1,148 @@
+package main
+
+// ------------------- INTERNAL NOTES --------------------------
+// Store staff can attach notes to items ("damaged batch received 3/4").
+// Notes are for internal use only: public listings such as Summary()
+// never include them. Editing a note keeps the previous text, so
+// nothing written about an item is ever silently lost.
+//
+// Staff read and write notes with "bookstore notes" (see cli.go), which
+// needs the notes:manage permission. The stores (filestore.go,
+// repository.go) keep them next to each item's JSON rather than inside
+// it, since that JSON is also what the public API sends.
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// NoteRevision is one version of a note's text
+type NoteRevision struct {
+	Author string    `json:"author"`
+	Text   string    `json:"text"`
+	At     time.Time `json:"at"`
+}
+
+// Note is an internal remark on an item. History holds the earlier
+// revisions, oldest first; the current text is in the Note itself.
+type Note struct {
+	ID int `json:"id"`
+	NoteRevision
+	History []NoteRevision `json:"history,omitempty"`
+}
+
+// Annotated is implemented by items that accept internal notes
+type Annotated interface {
+	AddNote(author, text string) (int, error)
+	EditNote(id int, author, text string) error
+	Notes() []Note
+}
+
+// annotations is embedded in Book and Magazine, like translations
+type annotations struct {
+	notes  []Note
+	nextID int
+}
+
+// AddNote records a new note and returns its ID
+func (a *annotations) AddNote(author, text string) (int, error) {
+	if err := validateNote(author, text); err != nil {
+		return 0, err
+	}
+	a.nextID++
+	a.notes = append(a.notes, Note{
+		ID:           a.nextID,
+		NoteRevision: NoteRevision{Author: author, Text: text, At: currentTime()},
+	})
+	return a.nextID, nil
+}
+
+// EditNote replaces a note's text, moving the old text into its history
+func (a *annotations) EditNote(id int, author, text string) error {
+	if err := validateNote(author, text); err != nil {
+		return err
+	}
+	i := slices.IndexFunc(a.notes, func(n Note) bool { return n.ID == id })
+	if i < 0 {
+		return fmt.Errorf("note %d not found", id)
+	}
+	// Take a pointer into the slice so we modify the stored note,
+	// not a copy of it
+	n := &a.notes[i]
+	n.History = append(n.History, n.NoteRevision)
+	n.NoteRevision = NoteRevision{Author: author, Text: text, At: currentTime()}
+	return nil
+}
+
+// Notes returns a copy of all notes, oldest first.
+// Returning the internal slice would let callers change it behind our back.
+func (a *annotations) Notes() []Note {
+	// slices.Clone copies the outer slice; each History slice
+	// still points at shared storage, so clone those too
+	out := slices.Clone(a.notes)
+	for i := range out {
+		out[i].History = slices.Clone(out[i].History)
+	}
+	return out
+}
+
+// restoreNotes puts back notes read from a store, replacing any the
+// item had. New notes are numbered after the highest ID among them.
+func (a *annotations) restoreNotes(notes []Note) error {
+	a.notes, a.nextID = nil, 0
+	for _, n := range notes {
+		if err := validateNote(n.Author, n.Text); err != nil {
+			return fmt.Errorf("note %d: %w", n.ID, err)
+		}
+		a.nextID = max(a.nextID, n.ID)
+	}
+	a.notes = slices.Clone(notes)
+	return nil
+}
+
+// itemNotes returns the notes on item, if it takes any
+func itemNotes(item PricedItem) []Note {
+	if a, ok := item.(Annotated); ok {
+		return a.Notes()
+	}
+	return nil
+}
+
+// setItemNotes puts notes read from a store back on item
+func setItemNotes(item PricedItem, notes []Note) error {
+	if len(notes) == 0 {
+		return nil
+	}
+	r, ok := item.(interface{ restoreNotes([]Note) error })
+	if !ok {
+		return fmt.Errorf("%s does not take notes", itemTitle(item))
+	}
+	return r.restoreNotes(notes)
+}
+
+// Annotate runs fn on the notes of the item under sku while holding the
+// write lock, like SetPrice does for prices
+func (c *Catalog) Annotate(sku string, fn func(Annotated) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[sku]
+	if !ok {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	annotated, ok := item.(Annotated)
+	if !ok {
+		return fmt.Errorf("%s does not take notes", itemTitle(item))
+	}
+	return fn(annotated)
+}
+
+func validateNote(author, text string) error {
+	if author == "" {
+		return fmt.Errorf("note author cannot be empty")
+	}
+	if text == "" {
+		return fmt.Errorf("note text cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestNoteHistory(t *testing.T) {
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	id, err := book.AddNote("sam", "damaged batch received")
+	assert.NoError(t, err)
+	assert.NoError(t, book.EditNote(id, "alex", "3 copies returned"))
+	if err := book.EditNote(id+1, "alex", "no such note"); err == nil {
+		t.Error("edited a missing note")
+	}
+	if _, err := book.AddNote("", "anonymous"); err == nil {
+		t.Error("added a note without an author")
+	}
+
+	notes := book.Notes()
+	assert.Equal(t, len(notes), 1)
+	assert.Equal(t, notes[0].Author, "alex")
+	assert.Equal(t, notes[0].Text, "3 copies returned")
+	assert.Equal(t, len(notes[0].History), 1)
+	assert.Equal(t, notes[0].History[0].Text, "damaged batch received")
+
+	// Notes hands out copies
+	notes[0].History[0].Text = "changed"
+	assert.Equal(t, book.Notes()[0].History[0].Text, "damaged batch received")
+}
+
+// annotatedCatalog is the sample catalog with one edited note on BK-001
+func annotatedCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c := sampleCatalog()
+	err := c.Annotate("BK-001", func(a Annotated) error {
+		id, err := a.AddNote("sam", "damaged batch received")
+		if err != nil {
+			return err
+		}
+		return a.EditNote(id, "alex", "3 copies returned")
+	})
+	assert.NoError(t, err)
+	return c
+}
+
+func TestFileStoreKeepsNotes(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "catalog.json")}
+	assert.NoError(t, store.Save(annotatedCatalog(t)))
+	loaded, err := store.Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+	notes := Must(loaded.Get("BK-001")).(Annotated).Notes()
+	assert.Equal(t, len(notes), 1)
+	assert.Equal(t, notes[0].Text, "3 copies returned")
+	assert.Equal(t, len(notes[0].History), 1)
+
+	// A note added after loading continues the numbering
+	id, err := Must(loaded.Get("BK-001")).(Annotated).AddNote("kim", "reordered")
+	if assert.NoError(t, err) {
+		assert.Equal(t, id, 2)
+	}
+}
+
+func TestNotesCommand(t *testing.T) {
+	s := &cliSession{catalog: sampleCatalog(), user: Actor{Name: "sam", Role: RoleClerk}, authz: NewAuthorizer()}
+	var out bytes.Buffer
+	assert.NoError(t, s.runCommand([]string{"notes", "-sku", "MG-001", "-add", "damaged batch received"}, &out))
+	assert.NoError(t, s.runCommand([]string{"notes", "-sku", "MG-001", "-edit", "1", "-text", "3 copies returned"}, &out))
+	notes := Must(s.catalog.Get("MG-001")).(Annotated).Notes()
+	assert.Equal(t, len(notes), 1)
+	assert.Equal(t, notes[0].Author, "sam")
+	if !strings.Contains(out.String(), "3 copies returned") {
+		t.Errorf("notes not listed:\n%s", out.String())
+	}
+
+	// Notes are for staff: a viewer can't even list them
+	s.user = Actor{Name: "guest", Role: RoleViewer}
+	err := s.runCommand([]string{"notes", "-sku", "MG-001"}, &out)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func TestCSVExportNotes(t *testing.T) {
+	c := annotatedCatalog(t)
+	var plain, staff bytes.Buffer
+	_, err := c.ExportCSV(&plain, CSVExportOptions{})
+	assert.NoError(t, err)
+	_, err = c.ExportCSV(&staff, CSVExportOptions{Notes: true})
+	assert.NoError(t, err)
+	if strings.Contains(plain.String(), "copies returned") {
+		t.Errorf("notes exported without the flag:\n%s", plain.String())
+	}
+	if !strings.Contains(staff.String(), "#1 alex: 3 copies returned") {
+		t.Errorf("notes missing with the flag:\n%s", staff.String())
+	}
+	// The extra column doesn't get in the way of importing the file
+	result, err := NewCatalog().ImportCSV(&staff)
+	if assert.NoError(t, err) {
+		assert.Equal(t, result.Added, 2)
+	}
+}
+
+func TestAPINeverSendsNotes(t *testing.T) {
+	server := Must(NewCatalogServer(annotatedCatalog(t)))
+	assert.NoError(t, server.Authz.AddKey(managerKey, Actor{Name: "kim", Role: RoleManager}))
+	for _, path := range []string{"/items", "/items/BK-001"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+managerKey)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		assert.Equal(t, rec.Code, http.StatusOK)
+		if body := rec.Body.String(); strings.Contains(body, "copies returned") || strings.Contains(body, "notes") {
+			t.Errorf("GET %s sent notes: %s", path, body)
+		}
+	}
+}
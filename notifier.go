@@ -0,0 +1,201 @@
+package main
+
+// ------------------- NOTIFICATIONS ---------------------------
+// Features report noteworthy things (a sale started, a price dropped)
+// by sending a Notification, without knowing where it ends up.
+// Anything with a Notify method is a Notifier: the terminal, a webhook,
+// an email gateway. A NotificationHub fans each notification out to
+// the channels whose filter accepts it, retrying failed deliveries.
+//
+// This is the Python "logging handlers" idea: the code calling
+// logger.info() doesn't care whether records go to a file or Slack.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// NotificationKind says what a notification is about
+type NotificationKind string
+
+const (
+	KindLowStock       NotificationKind = "low-stock"
+	KindPriceDrop      NotificationKind = "price-drop"
+	KindOverdueLending NotificationKind = "overdue-lending"
+	KindSale           NotificationKind = "sale"
+)
+
+// Notification is one message to deliver
+type Notification struct {
+	Kind    NotificationKind `json:"kind"`
+	Subject string           `json:"subject"`
+	Body    string           `json:"body"`
+	At      time.Time        `json:"at"`
+}
+
+// Notifier delivers notifications to one destination
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// ------------------- CHANNELS --------------------------------
+
+// TerminalNotifier prints notifications, e.g. to os.Stdout
+type TerminalNotifier struct {
+	Out io.Writer
+}
+
+func (t *TerminalNotifier) Notify(n Notification) error {
+	_, err := fmt.Fprintf(t.Out, "[%s] %s\n", n.Kind, n.Subject)
+	return err
+}
+
+// WebhookNotifier POSTs each notification as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // nil means a client with a 10s timeout
+}
+
+func (w *WebhookNotifier) Notify(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	// The body must be closed even if we don't read it,
+	// or the connection can't be reused
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier sends notifications through an SMTP server
+type EmailNotifier struct {
+	Addr string // host:port of the SMTP server
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+func (e *EmailNotifier) Notify(n Notification) error {
+	msg, err := emailMessage(e.From, e.To, n)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, msg)
+}
+
+// emailMessage builds the headers and body of n. Header lines end in
+// CRLF, so a subject containing "\r\nBcc: ..." (item titles end up in
+// subjects) would add headers of its own: CR and LF are refused.
+func emailMessage(from string, to []string, n Notification) ([]byte, error) {
+	for _, v := range append([]string{from, n.Subject}, to...) {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("email header %q contains a line break", v)
+		}
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	// Headers are ASCII; Q-encoding turns "Café" into
+	// "=?utf-8?q?Caf=C3=A9?=" and leaves plain ASCII as it is
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(n.Body)
+	return []byte(msg.String()), nil
+}
+
+// ------------------- FAN-OUT ---------------------------------
+
+// NotificationFilter decides whether a channel wants a notification
+type NotificationFilter func(n Notification) bool
+
+// OnlyKinds accepts notifications of the listed kinds
+func OnlyKinds(kinds ...NotificationKind) NotificationFilter {
+	return func(n Notification) bool {
+		for _, k := range kinds {
+			if n.Kind == k {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type notificationChannel struct {
+	name     string
+	notifier Notifier
+	filter   NotificationFilter
+}
+
+// NotificationHub sends each notification to every matching channel
+type NotificationHub struct {
+	// Attempts is how often delivery is tried per channel (at least once)
+	Attempts int
+	// Backoff is the wait before the first retry; it doubles each time
+	Backoff time.Duration
+
+	channels []notificationChannel
+	now      func() time.Time
+}
+
+// NewNotificationHub tries each delivery 3 times, starting at 100ms backoff
+func NewNotificationHub() *NotificationHub {
+	return &NotificationHub{Attempts: 3, Backoff: 100 * time.Millisecond, now: currentTime}
+}
+
+// Register adds a channel; a nil filter accepts everything
+func (h *NotificationHub) Register(name string, n Notifier, filter NotificationFilter) {
+	h.channels = append(h.channels, notificationChannel{name, n, filter})
+}
+
+// Send delivers to every channel whose filter matches. One failing
+// channel doesn't stop the others; all failures are returned together.
+func (h *NotificationHub) Send(kind NotificationKind, subject, body string) error {
+	n := Notification{Kind: kind, Subject: subject, Body: body, At: h.now()}
+	var errs []error
+	for _, ch := range h.channels {
+		if ch.filter != nil && !ch.filter(n) {
+			continue
+		}
+		if err := h.deliver(ch.notifier, n); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver retries with exponential backoff: 100ms, 200ms, 400ms...
+func (h *NotificationHub) deliver(notifier Notifier, n Notification) error {
+	attempts := max(h.Attempts, 1)
+	wait := h.Backoff
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		if err = notifier.Notify(n); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", attempts, err)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+// recordingNotifier keeps what it is sent; its first fail calls fail
+type recordingNotifier struct {
+	mu    sync.Mutex
+	fail  int
+	calls int
+	sent  []Notification
+}
+
+func (r *recordingNotifier) Notify(n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls <= r.fail {
+		return errors.New("unreachable")
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *recordingNotifier) subjects() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Map(r.sent, func(n Notification) string { return n.Subject })
+}
+
+func TestEmailMessage(t *testing.T) {
+	to := []string{"ops@example.com", "buyer@example.com"}
+	msg, err := emailMessage("shop@example.com", to, Notification{Subject: "Low stock: Dune", Body: "3 left"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, string(msg), "From: shop@example.com\r\n"+
+		"To: ops@example.com, buyer@example.com\r\n"+
+		"Subject: Low stock: Dune\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n"+
+		"3 left")
+
+	msg, err = emailMessage("shop@example.com", to, Notification{Subject: "Sold out: Café Society"})
+	if assert.NoError(t, err) && !strings.Contains(string(msg), "Subject: =?utf-8?q?Sold_out:_Caf=C3=A9_Society?=\r\n") {
+		t.Errorf("subject not Q-encoded:\n%s", msg)
+	}
+}
+
+func TestEmailMessageRefusesHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      []string
+		subject string
+	}{
+		{"subject", "shop@example.com", []string{"ops@example.com"}, "Sold out: Dune\r\nBcc: everyone@example.com"},
+		{"bare LF", "shop@example.com", []string{"ops@example.com"}, "Dune\nBcc: x@example.com"},
+		{"from", "shop@example.com\r\nBcc: x@example.com", []string{"ops@example.com"}, "Hi"},
+		{"to", "shop@example.com", []string{"ops@example.com\r\nBcc: x@example.com"}, "Hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := emailMessage(tt.from, tt.to, Notification{Subject: tt.subject}); err == nil {
+				t.Error("a header with a line break was accepted")
+			}
+		})
+	}
+}
+
+func TestNotificationHub(t *testing.T) {
+	hub := NewNotificationHub()
+	hub.Backoff = time.Millisecond
+	everything, stock := &recordingNotifier{}, &recordingNotifier{}
+	flaky, down := &recordingNotifier{fail: 2}, &recordingNotifier{fail: 99}
+	hub.Register("everything", everything, nil)
+	hub.Register("stock", stock, OnlyKinds(KindLowStock))
+	hub.Register("flaky", flaky, nil)
+	hub.Register("down", down, nil)
+
+	err := hub.Send(KindPriceDrop, "Dune now $8.99", "")
+	// Only the channel that never answers fails, after every attempt
+	if err == nil || !strings.Contains(err.Error(), "down: gave up after 3 attempts") || strings.Contains(err.Error(), "flaky") {
+		t.Errorf("got %v, want only down to fail", err)
+	}
+	assert.Equal(t, everything.subjects(), []string{"Dune now $8.99"})
+	assert.Equal(t, len(stock.subjects()), 0)
+	assert.Equal(t, flaky.subjects(), []string{"Dune now $8.99"})
+	assert.Equal(t, flaky.calls, 3)
+	assert.Equal(t, down.calls, 3)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	leaktest.Check(t)
+	var got []Notification
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil || r.Method != "POST" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		got = append(got, n)
+		w.WriteHeader(status)
+	}))
+	// Closing the server also closes its client's idle connections,
+	// whose goroutines leaktest would otherwise report
+	t.Cleanup(server.Close)
+
+	webhook := &WebhookNotifier{URL: server.URL, Client: server.Client()}
+	assert.NoError(t, webhook.Notify(Notification{Kind: KindSale, Subject: "Spring sale"}))
+	status = http.StatusInternalServerError
+	if err := webhook.Notify(Notification{Kind: KindSale, Subject: "Again"}); err == nil {
+		t.Error("a 500 answer counted as delivered")
+	}
+	assert.Equal(t, Map(got, func(n Notification) string { return n.Subject }), []string{"Spring sale", "Again"})
+}
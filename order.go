@@ -0,0 +1,181 @@
+package main
+
+// ------------------- ORDERS ----------------------------------
+// An order moves through a fixed set of statuses:
+//
+//	Pending --Pay--> Paid --Ship--> Shipped --Deliver--> Delivered
+//	   |               |
+//	   +----Cancel-----+
+//
+// Anything else (shipping a cancelled order, paying twice...) is an
+// error. The allowed moves live in one table, so the rules can be read
+// in one place instead of being scattered over if statements.
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// OrderStatus is where an order is in its lifecycle
+type OrderStatus int
+
+const (
+	OrderPending OrderStatus = iota
+	OrderPaid
+	OrderShipped
+	OrderDelivered
+	OrderCancelled
+)
+
+func (s OrderStatus) String() string {
+	switch s {
+	case OrderPending:
+		return "pending"
+	case OrderPaid:
+		return "paid"
+	case OrderShipped:
+		return "shipped"
+	case OrderDelivered:
+		return "delivered"
+	case OrderCancelled:
+		return "cancelled"
+	default:
+		return fmt.Sprintf("OrderStatus(%d)", int(s))
+	}
+}
+
+// orderStatusAliases are other names bookkeeping uses for a status
+var orderStatusAliases = map[string]OrderStatus{
+	"fulfilled": OrderDelivered,
+}
+
+// ParseOrderStatus reads a status name as printed by String, or one of
+// its aliases such as "fulfilled" for delivered
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	if status, ok := orderStatusAliases[strings.ToLower(s)]; ok {
+		return status, nil
+	}
+	for status := OrderPending; status <= OrderCancelled; status++ {
+		if strings.EqualFold(s, status.String()) {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown order status %q", s)
+}
+
+// orderTransitions lists the statuses each status may move to
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+	OrderShipped: {OrderDelivered},
+}
+
+// OrderTransition is one entry of an order's history
+type OrderTransition struct {
+	From, To OrderStatus
+	At       time.Time
+}
+
+// OrderHistory records every status change, oldest first
+type OrderHistory []OrderTransition
+
+// OrderLine is a cart line with its prices frozen at checkout
+type OrderLine struct {
+	Item      PricedItem
+	Quantity  int
+	UnitPrice Money // regular price
+	Paid      Money // discounted unit price
+	Discounts []string
+}
+
+// Order is a placed purchase; see Cart.Checkout
+type Order struct {
+	Number   string // e.g. "MAIN-000042"; empty unless the cart has Numbers
+	PlacedAt time.Time
+	Lines    []OrderLine
+	Subtotal Money
+	Tax      []TaxLine // one line per tax rate; empty without tax
+	TaxTotal Money
+	Total    Money // after discounts, plus tax
+
+	status  OrderStatus
+	history OrderHistory
+}
+
+func newOrder(at time.Time) *Order {
+	return &Order{PlacedAt: at, status: OrderPending}
+}
+
+// Status is the current status; change it with Pay, Ship, Deliver or Cancel
+func (o *Order) Status() OrderStatus {
+	return o.status
+}
+
+// History returns a copy of the recorded transitions
+func (o *Order) History() OrderHistory {
+	return append(OrderHistory(nil), o.history...)
+}
+
+// Savings is what discounts and member prices took off the list prices
+func (o *Order) Savings() (Money, error) {
+	net, err := o.Total.Sub(o.TaxTotal)
+	if err != nil {
+		return Money{}, err
+	}
+	return o.Subtotal.Sub(net)
+}
+
+// PrintReceipt writes the customer's receipt; label names each item
+func (o *Order) PrintReceipt(w io.Writer, label func(PricedItem) string) error {
+	if o.Number != "" {
+		if _, err := fmt.Fprintf(w, "Order %s\n", o.Number); err != nil {
+			return err
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, l := range o.Lines {
+		amount, err := l.Paid.Times(l.Quantity)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s x%d\t%v", label(l.Item), l.Quantity, amount)
+		if l.Paid != l.UnitPrice {
+			fmt.Fprintf(tw, "\t(%v each, was %v)", l.Paid, l.UnitPrice)
+		}
+		fmt.Fprintln(tw)
+	}
+	for _, t := range o.Tax {
+		fmt.Fprintf(tw, "%s\t%v\n", t.Label, t.Amount)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%v\n", o.Total)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	saved, err := o.Savings()
+	if err != nil {
+		return err
+	}
+	if saved.IsNegative() || saved.IsZero() {
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "You saved %v today!\n", saved)
+	return err
+}
+
+func (o *Order) Pay(at time.Time) error     { return o.transition(OrderPaid, at) }
+func (o *Order) Ship(at time.Time) error    { return o.transition(OrderShipped, at) }
+func (o *Order) Deliver(at time.Time) error { return o.transition(OrderDelivered, at) }
+func (o *Order) Cancel(at time.Time) error  { return o.transition(OrderCancelled, at) }
+
+func (o *Order) transition(to OrderStatus, at time.Time) error {
+	if !slices.Contains(orderTransitions[o.status], to) {
+		return fmt.Errorf("cannot move a %v order to %v", o.status, to)
+	}
+	o.history = append(o.history, OrderTransition{From: o.status, To: to, At: at})
+	o.status = to
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+// ------------------- ORDER EXPORT ----------------------------
+// Bookkeeping wants orders in a spreadsheet, not on a receipt:
+//
+//	q1 := OrderFilter{From: jan1, To: apr1, Status: &delivered}
+//	n, err := ExportOrders(f, orders, q1, DefaultOrderColumns)
+//
+// There is no CLI command for it yet: orders are not stored anywhere,
+// so a command would have nothing real to export. It belongs next to
+// whatever persists them.
+//
+// The export is a CSV file (Excel and every other spreadsheet open it)
+// with one row per order and a TOTAL row at the end. Amounts are bare
+// decimals such as 12.99, which spreadsheets read as numbers; the
+// currency has its own column. Like csv.DictWriter in Python, the
+// columns are chosen by name:
+//
+//	ExportOrders(f, orders, filter, []string{"placed", "status", "total"})
+//
+// Only numeric columns are added up in the TOTAL row.
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrderFilter selects the orders to export; zero fields match anything
+type OrderFilter struct {
+	From, To time.Time // placed at or after From and before To
+	Status   *OrderStatus
+}
+
+func (f OrderFilter) Match(o *Order) bool {
+	if !f.From.IsZero() && o.PlacedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !o.PlacedAt.Before(f.To) {
+		return false
+	}
+	return f.Status == nil || o.Status() == *f.Status
+}
+
+// exportColumn is one column of the export; exactly one func is set.
+// Money and count columns are added up for the TOTAL row.
+type exportColumn struct {
+	value func(o *Order) string
+	money func(o *Order) (Money, error)
+	count func(o *Order) int
+}
+
+// orderExportColumns are the columns ExportOrders can write, by name
+var orderExportColumns = map[string]exportColumn{
+	"number": {value: func(o *Order) string { return o.Number }},
+	"placed": {value: func(o *Order) string { return o.PlacedAt.Format(time.DateTime) }},
+	"status": {value: func(o *Order) string { return o.Status().String() }},
+	"items": {value: func(o *Order) string {
+		names := Map(o.Lines, func(l OrderLine) string { return fmt.Sprintf("%s x%d", itemTitle(l.Item), l.Quantity) })
+		return strings.Join(names, "; ")
+	}},
+	"quantity": {count: func(o *Order) int {
+		return Reduce(o.Lines, 0, func(n int, l OrderLine) int { return n + l.Quantity })
+	}},
+	"subtotal":  {money: func(o *Order) (Money, error) { return o.Subtotal, nil }},
+	"discounts": {money: (*Order).Savings},
+	"tax":       {money: func(o *Order) (Money, error) { return o.TaxTotal, nil }},
+	"total":     {money: func(o *Order) (Money, error) { return o.Total, nil }},
+	"currency":  {value: func(o *Order) string { return o.Total.Currency() }},
+}
+
+// DefaultOrderColumns suit a bookkeeper with no particular preference
+var DefaultOrderColumns = []string{"placed", "status", "items", "quantity", "subtotal", "discounts", "tax", "total", "currency"}
+
+// ExportOrders writes the orders matching filter as CSV and returns how
+// many were written. All exported orders must share a currency, since
+// the TOTAL row adds their amounts.
+func ExportOrders(w io.Writer, orders []*Order, filter OrderFilter, columns []string) (int, error) {
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("no columns to export")
+	}
+	cols := make([]exportColumn, len(columns))
+	for i, name := range columns {
+		col, ok := orderExportColumns[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown column %q (columns: %s)", name, strings.Join(DefaultOrderColumns, ", "))
+		}
+		cols[i] = col
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return 0, err
+	}
+	moneyTotals := make([]lineSum, len(cols))
+	countTotals := make([]int, len(cols))
+	exported := 0
+	for _, o := range Filter(orders, filter.Match) {
+		row := make([]string, len(cols))
+		for i, col := range cols {
+			switch {
+			case col.money != nil:
+				amount, err := col.money(o)
+				if err != nil {
+					return exported, err
+				}
+				if err := moneyTotals[i].add(amount, 1); err != nil {
+					return exported, fmt.Errorf("column %s: %w", columns[i], err)
+				}
+				row[i] = amount.Decimal()
+			case col.count != nil:
+				n := col.count(o)
+				countTotals[i] += n
+				row[i] = strconv.Itoa(n)
+			default:
+				row[i] = col.value(o)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+
+	totals := make([]string, len(cols))
+	for i, col := range cols {
+		switch {
+		case col.money != nil:
+			totals[i] = moneyTotals[i].total.Decimal()
+		case col.count != nil:
+			totals[i] = strconv.Itoa(countTotals[i])
+		}
+	}
+	// The label goes in the first column, unless that holds a total
+	if totals[0] == "" {
+		totals[0] = "TOTAL"
+	}
+	if err := cw.Write(totals); err != nil {
+		return exported, err
+	}
+	cw.Flush()
+	return exported, cw.Error()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+// quarterOrders places one order a month in the first quarter of 2024:
+// January's delivered, February's paid, March's cancelled
+func quarterOrders(t *testing.T) []*Order {
+	t.Helper()
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(10), ""))
+	var orders []*Order
+	for month, moves := range [][]func(*Order, time.Time) error{
+		{(*Order).Pay, (*Order).Ship, (*Order).Deliver},
+		{(*Order).Pay},
+		{(*Order).Cancel},
+	} {
+		at := time.Date(2024, time.Month(month+1), 10, 12, 0, 0, 0, time.UTC)
+		cart := &Cart{Tax: FlatTax{Label: "Sales tax 8%", Rate: MustPercent(8)}}
+		assert.NoError(t, cart.AddItem(book, month+1))
+		order := Must(cart.Checkout(NewPricingEngine(StackAll), at))
+		for _, move := range moves {
+			assert.NoError(t, move(order, at.Add(time.Hour)))
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+func TestExportOrders(t *testing.T) {
+	orders := quarterOrders(t)
+	fulfilled := Must(ParseOrderStatus("Fulfilled"))
+	assert.Equal(t, fulfilled, OrderDelivered)
+
+	tests := []struct {
+		name    string
+		filter  OrderFilter
+		columns []string
+		want    [][]string
+	}{
+		{"everything", OrderFilter{}, []string{"status", "quantity", "total", "currency"}, [][]string{
+			{"status", "quantity", "total", "currency"},
+			{"delivered", "1", "10.80", "USD"},
+			{"paid", "2", "21.60", "USD"},
+			{"cancelled", "3", "32.40", "USD"},
+			{"TOTAL", "6", "64.80", ""},
+		}},
+		{"February", OrderFilter{
+			From: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		}, []string{"placed", "subtotal", "tax"}, [][]string{
+			{"placed", "subtotal", "tax"},
+			{"2024-02-10 12:00:00", "20.00", "1.60"},
+			{"TOTAL", "20.00", "1.60"},
+		}},
+		{"fulfilled", OrderFilter{Status: &fulfilled}, []string{"quantity", "items"}, [][]string{
+			{"quantity", "items"},
+			{"1", "Dune x1"},
+			{"1", ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := ExportOrders(&buf, orders, tt.filter, tt.columns)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, n, len(tt.want)-2)
+			assert.Equal(t, Must(csv.NewReader(&buf).ReadAll()), tt.want)
+		})
+	}
+
+	var buf bytes.Buffer
+	if _, err := ExportOrders(&buf, orders, OrderFilter{}, []string{"placed", "colour"}); err == nil {
+		t.Error("exported an unknown column")
+	}
+	if _, err := ParseOrderStatus("lost"); err == nil {
+		t.Error("parsed an unknown status")
+	}
+}
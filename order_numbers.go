@@ -0,0 +1,161 @@
+package main
+
+// ------------------- ORDER NUMBERS ---------------------------
+// Some countries require invoice numbers to be gapless: 1, 2, 3... with
+// none skipped and none used twice, so an auditor can tell that no
+// invoice was made to disappear. Each store counts on its own:
+//
+//	MAIN-000001, MAIN-000002, AIRPORT-000001, MAIN-000003 ...
+//
+// A database sequence (or Python's itertools.count) isn't enough: a
+// sequence skips numbers taken by failed transactions, and a counter in
+// memory starts over after a restart. Three rules keep numbers gapless:
+//
+//   - the counter is saved before a number is handed out, so a restart
+//     carries on where it stopped (no duplicates)
+//   - a number only counts once the order under it is saved: if saving
+//     fails, the number is handed back and the next order gets it
+//   - numbers are taken one at a time per sequence, from handing one
+//     out until its order is saved, so nobody can take the next number
+//     while the current one might still come back
+//
+// So concurrent checkouts wait for each other while numbering, and
+// saving an order should be quick.
+//
+// A crash is the exception. Only SQLRepository.NumberOrderTx, which
+// saves the order in the transaction that takes its number, keeps the
+// numbers exact through one. Elsewhere the counter and the order are
+// saved separately, and a crash between the two costs one number:
+// FileSequence saves the counter first, so the number is counted but
+// not used, a gap. SQLRepository.NumberOrder commits the counter last,
+// so the order is saved but its number handed back, a duplicate.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OrderSequence hands out order numbers, counting separately per store
+type OrderSequence interface {
+	// NumberOrder passes the store's next number, starting at 1, to
+	// save, which stores the order under it. If save fails the number
+	// is handed back and NumberOrder returns save's error.
+	NumberOrder(store string, save func(n int64) error) (int64, error)
+}
+
+// FormatOrderNumber is how an order number is printed on receipts
+func FormatOrderNumber(store string, n int64) string {
+	return fmt.Sprintf("%s-%06d", store, n)
+}
+
+// FileSequence keeps the last number of every store in a JSON file:
+//
+//	{"AIRPORT": 1, "MAIN": 3}
+//
+// It is safe for concurrent use, but only one process may use a file at
+// a time; several processes should share SQLRepository's sequence. A
+// crash while save runs leaves a gap, since the counter is already
+// written.
+type FileSequence struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewFileSequence(path string) *FileSequence {
+	return &FileSequence{Path: path}
+}
+
+func (s *FileSequence) NumberOrder(store string, save func(n int64) error) (int64, error) {
+	if store == "" {
+		return 0, errors.New("store cannot be empty")
+	}
+	// Held until the order is saved: the number may still come back
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Read the file every time rather than caching it: a file edited or
+	// restored while running is then never overwritten with stale numbers
+	last := make(map[string]int64)
+	data, err := os.ReadFile(s.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &last); err != nil {
+			return 0, fmt.Errorf("%s: %w", s.Path, err)
+		}
+	}
+
+	// The counter is saved first, so a crash while the order is being
+	// saved can't lead to the number being given out twice
+	n := last[store] + 1
+	last[store] = n
+	if err := s.write(last); err != nil {
+		return 0, fmt.Errorf("saving order number: %w", err)
+	}
+	if err := save(n); err != nil {
+		last[store] = n - 1
+		if rerr := s.write(last); rerr != nil {
+			return 0, errors.Join(err, fmt.Errorf("handing back order number %d: %w", n, rerr))
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// write saves the last number of every store
+func (s *FileSequence) write(last map[string]int64) error {
+	data, err := json.MarshalIndent(last, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, data)
+}
+
+// NumberOrder counts in the order_sequences table. save runs while the
+// transaction that took the number is open, so a failed save rolls the
+// count back, and the row stays locked, serializing other callers (even
+// from other processes), until it is done.
+//
+// save itself isn't part of the transaction, though: if Commit fails
+// or the process dies after save succeeded, the order is saved but its
+// number is handed back and goes to the next order as well. To save
+// the order in the same transaction, use NumberOrderTx.
+func (r *SQLRepository) NumberOrder(store string, save func(n int64) error) (int64, error) {
+	return r.NumberOrderTx(store, func(_ *sql.Tx, n int64) error { return save(n) })
+}
+
+// NumberOrderTx is NumberOrder for a save that writes to this database:
+// it must use tx, since the transaction holds the database's write lock
+// until save returns
+func (r *SQLRepository) NumberOrderTx(store string, save func(tx *sql.Tx, n int64) error) (int64, error) {
+	if store == "" {
+		return 0, errors.New("store cannot be empty")
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	// Rollback after Commit does nothing, so it's safe to defer
+	defer tx.Rollback()
+	var n int64
+	// RETURNING needs SQLite 3.35 or later
+	err = tx.QueryRow(`INSERT INTO order_sequences (store, last) VALUES (?, 1)
+		ON CONFLICT (store) DO UPDATE SET last = last + 1
+		RETURNING last`, store).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	if err := save(tx, n); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
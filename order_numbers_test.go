@@ -0,0 +1,234 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+// assertGapless checks that numbers are exactly 1..len(numbers)
+func assertGapless(t *testing.T, store string, numbers []int64) {
+	t.Helper()
+	got := slices.Sorted(slices.Values(numbers))
+	for i, n := range got {
+		if n != int64(i+1) {
+			t.Errorf("%s: numbers %v are not 1..%d", store, got, len(got))
+			return
+		}
+	}
+}
+
+func TestFileSequenceConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "order-numbers.json")
+	seq := NewFileSequence(path)
+	errFull := errors.New("disk full")
+	var (
+		mu    sync.Mutex
+		saved = make(map[string][]int64)
+		wg    sync.WaitGroup
+	)
+	// 90 orders over three stores; every fifth save fails, and its
+	// number must go to another order
+	for i := range 90 {
+		store := []string{"MAIN", "AIRPORT", "STATION"}[i%3]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := seq.NumberOrder(store, func(n int64) error {
+				if i%5 == 0 {
+					return errFull
+				}
+				mu.Lock()
+				saved[store] = append(saved[store], n)
+				mu.Unlock()
+				return nil
+			})
+			if i%5 == 0 {
+				assert.ErrorIs(t, err, errFull)
+				return
+			}
+			assert.NoError(t, err)
+			if n == 0 {
+				t.Error("a saved order got no number")
+			}
+		}()
+	}
+	wg.Wait()
+	for store, numbers := range saved {
+		assert.Equal(t, len(numbers), 24)
+		assertGapless(t, store, numbers)
+	}
+
+	// A restart carries on after the last saved order
+	for store := range saved {
+		n, err := NewFileSequence(path).NumberOrder(store, func(int64) error { return nil })
+		if assert.NoError(t, err) {
+			assert.Equal(t, n, int64(25))
+		}
+	}
+}
+
+func TestCheckoutHandsNumberBackOnFailedSave(t *testing.T) {
+	seq := NewFileSequence(filepath.Join(t.TempDir(), "order-numbers.json"))
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	engine := NewPricingEngine(StackAll)
+	checkout := func(save func(*Order) error) (*Order, error) {
+		cart := Cart{Numbers: seq, Store: "MAIN", Save: save}
+		assert.NoError(t, cart.AddItem(book, 1))
+		return cart.Checkout(engine, time.Now())
+	}
+
+	first := Must(checkout(nil))
+	assert.Equal(t, first.Number, "MAIN-000001")
+
+	var attempted string
+	_, err := checkout(func(o *Order) error {
+		attempted = o.Number
+		return errors.New("database down")
+	})
+	if err == nil {
+		t.Fatal("checkout succeeded although the order wasn't saved")
+	}
+	assert.Equal(t, attempted, "MAIN-000002")
+
+	var saved []string
+	second := Must(checkout(func(o *Order) error {
+		saved = append(saved, o.Number)
+		return nil
+	}))
+	assert.Equal(t, second.Number, "MAIN-000002")
+	assert.Equal(t, saved, []string{"MAIN-000002"})
+}
+
+func TestCheckoutConcurrentNumbering(t *testing.T) {
+	seq := NewFileSequence(filepath.Join(t.TempDir(), "order-numbers.json"))
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	engine := NewPricingEngine(StackAll)
+	var (
+		mu      sync.Mutex
+		numbers []int64
+		wg      sync.WaitGroup
+	)
+	for i := range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cart := Cart{Numbers: seq, Store: "MAIN", Save: func(o *Order) error {
+				if i%4 == 0 {
+					return fmt.Errorf("order %d lost", i)
+				}
+				return nil
+			}}
+			assert.NoError(t, cart.AddItem(book, 1))
+			order, err := cart.Checkout(engine, time.Now())
+			if i%4 == 0 {
+				if err == nil {
+					t.Error("checkout succeeded although the order wasn't saved")
+				}
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			var n int64
+			fmt.Sscanf(order.Number, "MAIN-%d", &n)
+			mu.Lock()
+			numbers = append(numbers, n)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, len(numbers), 30)
+	assertGapless(t, "MAIN", numbers)
+}
+
+// insertOrder saves an order numbered n in tx, in the fake database's
+// orders table
+func insertOrder(tx *sql.Tx, store string, n int64) error {
+	_, err := tx.Exec(`INSERT INTO orders (number) VALUES (?)`, FormatOrderNumber(store, n))
+	return err
+}
+
+func TestSQLSequenceTx(t *testing.T) {
+	db, fake := openFakeDB(t)
+	repo := Must(NewSQLRepository(db))
+	var (
+		mu      sync.Mutex
+		numbers = make(map[string][]int64)
+		wg      sync.WaitGroup
+	)
+	// Every fifth save fails, and its number goes to another order
+	for i := range 60 {
+		store := []string{"MAIN", "AIRPORT"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := repo.NumberOrderTx(store, func(tx *sql.Tx, n int64) error {
+				if err := insertOrder(tx, store, n); err != nil {
+					return err
+				}
+				if i%5 == 0 {
+					return errors.New("payment declined")
+				}
+				return nil
+			})
+			if i%5 == 0 {
+				if err == nil {
+					t.Error("numbered an order whose save failed")
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				mu.Lock()
+				numbers[store] = append(numbers[store], n)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for store, got := range numbers {
+		assert.Equal(t, len(got), 24)
+		assertGapless(t, store, got)
+	}
+	// The failed saves' orders were rolled back with their numbers
+	assert.Equal(t, len(fake.tables.orders), 48)
+
+	// A failed commit undoes the order and the count together
+	errCommit := errors.New("disk I/O error")
+	fake.failCommit = errCommit
+	_, err := repo.NumberOrderTx("MAIN", func(tx *sql.Tx, n int64) error { return insertOrder(tx, "MAIN", n) })
+	assert.ErrorIs(t, err, errCommit)
+	assert.Equal(t, len(fake.tables.orders), 48)
+	n, err := repo.NumberOrderTx("MAIN", func(tx *sql.Tx, n int64) error { return insertOrder(tx, "MAIN", n) })
+	if assert.NoError(t, err) {
+		assert.Equal(t, n, int64(25))
+	}
+	assert.Equal(t, fake.tables.orders[len(fake.tables.orders)-1], "MAIN-000025")
+}
+
+func TestSQLSequenceSaveOutsideTx(t *testing.T) {
+	db, fake := openFakeDB(t)
+	repo := Must(NewSQLRepository(db))
+	var saved []int64
+	save := func(n int64) error {
+		saved = append(saved, n)
+		return nil
+	}
+	assert.Equal(t, Must(repo.NumberOrder("MAIN", save)), int64(1))
+
+	// The limit NumberOrder documents: the commit fails after an order
+	// saved elsewhere took the number, and the next order gets it too
+	errCommit := errors.New("disk I/O error")
+	fake.failCommit = errCommit
+	_, err := repo.NumberOrder("MAIN", save)
+	assert.ErrorIs(t, err, errCommit)
+	assert.Equal(t, Must(repo.NumberOrder("MAIN", save)), int64(2))
+	assert.Equal(t, saved, []int64{1, 2, 2})
+}
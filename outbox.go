@@ -0,0 +1,316 @@
+package main
+
+// ------------------- TRANSACTIONAL OUTBOX --------------------
+// Saving a price and then publishing PriceChanged is two steps. If the
+// program crashes in between, the price is saved but nobody hears about
+// it. Publishing first is no better: the save may still fail.
+//
+// The outbox pattern makes the event part of the save. The repository
+// writes the new price and an outbox row in the same transaction, so
+// either both happen or neither does. An OutboxRelay then reads the
+// outbox, publishes each event and marks it as published:
+//
+//	repo.SetPrice  --one transaction-->  items + outbox
+//	OutboxRelay    outbox --Publish--> Events --> listeners
+//
+// A crash after publishing but before marking means the event goes out
+// again on the next run: delivery is "at least once". Every relayed
+// event carries the outbox row's Key, and DedupEvents lets a listener
+// skip the ones it has already seen.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// OutboxMessage is one event waiting in an outbox
+type OutboxMessage struct {
+	ID   int64
+	SKU  string // the item the event is about
+	Name string // the EventName
+	Data []byte // the event as JSON
+}
+
+// Key identifies the message across redeliveries
+func (m OutboxMessage) Key() string {
+	return fmt.Sprintf("outbox-%d", m.ID)
+}
+
+// OutboxStore is implemented by repositories with an outbox
+type OutboxStore interface {
+	// Pending returns up to limit unpublished messages, oldest first
+	Pending(limit int) ([]OutboxMessage, error)
+	MarkPublished(id int64) error
+}
+
+// priceChangedJSON is how PriceChanged is stored; the item is the SKU
+type priceChangedJSON struct {
+	Old         json.Number `json:"old"`
+	OldCurrency string      `json:"oldCurrency"`
+	New         json.Number `json:"new"`
+	Currency    string      `json:"currency"`
+	Reason      string      `json:"reason,omitempty"`
+	At          time.Time   `json:"at"`
+}
+
+// newOutboxMessage encodes e for the outbox
+func newOutboxMessage(sku string, e Event) (OutboxMessage, error) {
+	pc, ok := e.(PriceChanged)
+	if !ok {
+		return OutboxMessage{}, fmt.Errorf("%s events can't be stored in the outbox", e.EventName())
+	}
+	data, err := json.Marshal(priceChangedJSON{
+		Old:         json.Number(pc.Old.Decimal()),
+		OldCurrency: pc.Old.Currency(),
+		New:         json.Number(pc.New.Decimal()),
+		Currency:    pc.New.Currency(),
+		Reason:      pc.Reason,
+		At:          pc.At,
+	})
+	return OutboxMessage{SKU: sku, Name: e.EventName(), Data: data}, err
+}
+
+// event decodes the message, using item as the event's Item
+func (m OutboxMessage) event(item PricedItem) (Event, error) {
+	if m.Name != (PriceChanged{}).EventName() {
+		return nil, fmt.Errorf("outbox message %d: unknown event %q", m.ID, m.Name)
+	}
+	var dto priceChangedJSON
+	if err := json.Unmarshal(m.Data, &dto); err != nil {
+		return nil, fmt.Errorf("outbox message %d: %w", m.ID, err)
+	}
+	old, err := ParseMoney(dto.Old.String(), dto.OldCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("outbox message %d: %w", m.ID, err)
+	}
+	new, err := ParseMoney(dto.New.String(), dto.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("outbox message %d: %w", m.ID, err)
+	}
+	return PriceChanged{Item: item, Old: old, New: new, Reason: dto.Reason, At: dto.At, Key: m.Key()}, nil
+}
+
+// setPriceQueued is setPriceBecause, except that the item's events are
+// returned instead of published. Items without a price history publish
+// none anyway.
+func setPriceQueued(item PricedItem, price Money, reason string) ([]Event, error) {
+	// An interface can be declared inline, like a Python Protocol
+	// nobody else needs
+	src, ok := item.(interface{ sendEventsTo(func(Event)) })
+	if !ok {
+		return nil, setPriceBecause(item, price, reason)
+	}
+	var events []Event
+	src.sendEventsTo(func(e Event) { events = append(events, e) })
+	defer src.sendEventsTo(nil)
+	err := setPriceBecause(item, price, reason)
+	return events, err
+}
+
+// ------------------- SQL OUTBOX ------------------------------
+
+// SetPrice changes the price of the item under sku and stores the
+// PriceChanged event in the outbox, in one transaction
+func (r *SQLRepository) SetPrice(sku string, price Money, reason string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var typ, data string
+	err = tx.QueryRow(`SELECT type, data FROM items WHERE sku = ?`, sku).Scan(&typ, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	if err != nil {
+		return err
+	}
+	item, err := decodeItem(typ, []byte(data))
+	if err != nil {
+		return err
+	}
+	events, err := setPriceQueued(item, price, reason)
+	if err != nil {
+		return err
+	}
+	// The item was read without its notes, so leave that column alone
+	row, err := newItemRow(item)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE items SET type = ?, title = ?, price_minor = ?, currency = ?, data = ? WHERE sku = ?`,
+		row.typ, row.title, row.priceMinor, row.currency, row.data, sku)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		msg, err := newOutboxMessage(sku, e)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO outbox (sku, name, data) VALUES (?, ?, ?)`, msg.SKU, msg.Name, string(msg.Data))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SQLRepository) Pending(limit int) ([]OutboxMessage, error) {
+	rows, err := r.db.Query(`SELECT id, sku, name, data FROM outbox WHERE published = 0 ORDER BY id LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		var data string
+		if err := rows.Scan(&msg.ID, &msg.SKU, &msg.Name, &data); err != nil {
+			return nil, err
+		}
+		msg.Data = []byte(data)
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+func (r *SQLRepository) MarkPublished(id int64) error {
+	_, err := r.db.Exec(`UPDATE outbox SET published = 1 WHERE id = ?`, id)
+	return err
+}
+
+// ------------------- MEMORY OUTBOX ---------------------------
+
+// SetPrice changes the price of the item under sku and queues the
+// PriceChanged event in the outbox; the lock stands in for a transaction
+func (r *MemoryRepository) SetPrice(sku string, price Money, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.items[sku]
+	if !ok {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	events, err := setPriceQueued(item, price, reason)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		msg, err := newOutboxMessage(sku, e)
+		if err != nil {
+			return err
+		}
+		r.nextOutboxID++
+		msg.ID = r.nextOutboxID
+		r.outbox = append(r.outbox, msg)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Pending(limit int) ([]OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.outbox[:min(limit, len(r.outbox))]), nil
+}
+
+// MarkPublished drops the message; unlike the SQL outbox, the memory
+// one keeps no record of what was sent
+func (r *MemoryRepository) MarkPublished(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outbox = slices.DeleteFunc(r.outbox, func(m OutboxMessage) bool { return m.ID == id })
+	return nil
+}
+
+// ------------------- RELAY -----------------------------------
+
+// DefaultOutboxBatch is how many messages a relay reads at a time
+const DefaultOutboxBatch = 100
+
+// OutboxRelay publishes outbox messages on a bus
+type OutboxRelay struct {
+	Store OutboxStore
+	Bus   *EventBus
+	// Lookup finds the item an event is about, e.g. a repository's
+	// FindByID
+	Lookup func(sku string) (PricedItem, error)
+	// BatchSize is DefaultOutboxBatch if zero
+	BatchSize int
+}
+
+// Flush publishes every pending message and returns how many it
+// published. It stops at the first error; the messages that are left
+// go out on the next Flush.
+func (r *OutboxRelay) Flush() (int, error) {
+	batch := r.BatchSize
+	if batch == 0 {
+		batch = DefaultOutboxBatch
+	}
+	published := 0
+	for {
+		msgs, err := r.Store.Pending(batch)
+		if err != nil || len(msgs) == 0 {
+			return published, err
+		}
+		for _, msg := range msgs {
+			item, err := r.Lookup(msg.SKU)
+			var notFound *ItemNotFoundError
+			if errors.As(err, &notFound) {
+				// The item is gone, so is anyone's interest in its price
+				if err := r.Store.MarkPublished(msg.ID); err != nil {
+					return published, err
+				}
+				continue
+			}
+			if err != nil {
+				return published, err
+			}
+			e, err := msg.event(item)
+			if err != nil {
+				return published, err
+			}
+			r.Bus.Publish(e)
+			published++
+			// A crash right here publishes msg again next time
+			if err := r.Store.MarkPublished(msg.ID); err != nil {
+				return published, fmt.Errorf("marking %s as published: %w", msg.Key(), err)
+			}
+		}
+	}
+}
+
+// Start flushes the outbox every interval until stop is called, passing
+// errors to onError. In deterministic mode nothing is scheduled.
+func (r *OutboxRelay) Start(interval time.Duration, onError func(error)) (stop func()) {
+	return every(interval, func() {
+		if _, err := r.Flush(); err != nil {
+			onError(err)
+		}
+	})
+}
+
+// DedupEvents wraps a listener so that an event relayed twice (same
+// Key) reaches it only once. Events without a Key always go through.
+// The keys seen are kept for the life of the listener.
+func DedupEvents(fn func(Event)) func(Event) {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	return func(e Event) {
+		if pc, ok := e.(PriceChanged); ok && pc.Key != "" {
+			mu.Lock()
+			dup := seen[pc.Key]
+			seen[pc.Key] = true
+			mu.Unlock()
+			if dup {
+				return
+			}
+		}
+		fn(e)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+func TestOutboxRelayStart(t *testing.T) {
+	leaktest.Check(t)
+	repo := NewMemoryRepository()
+	assert.NoError(t, repo.Create("BK-042", Must(NewBook("The Hobbit", "J.R.R. Tolkien", Dollars(14.99), ""))))
+	bus := NewEventBus()
+	changes := make(chan PriceChanged, 2)
+	Subscribe(bus, func(e PriceChanged) { changes <- e })
+
+	assert.NoError(t, repo.SetPrice("BK-042", Dollars(12.99), "clearance"))
+	assert.NoError(t, repo.SetPrice("BK-042", Dollars(11.99), "clearance"))
+	relay := &OutboxRelay{Store: repo, Bus: bus, Lookup: repo.FindByID}
+	stop := relay.Start(time.Millisecond, func(err error) { t.Error(err) })
+	t.Cleanup(stop)
+
+	for _, want := range []Money{Dollars(12.99), Dollars(11.99)} {
+		select {
+		case e := <-changes:
+			assert.Equal(t, e.New, want)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the relay never published")
+		}
+	}
+	pending, err := repo.Pending(DefaultOutboxBatch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, len(pending), 0)
+	}
+}
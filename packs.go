@@ -0,0 +1,158 @@
+package main
+
+// ------------------- PACKS AND UNITS -------------------------
+// Magazines often arrive in sealed packs of, say, 10. A pack can be
+// sold whole, or broken open to sell single copies.
+//
+// The inventory still keeps ONE count of available units; packs are
+// only a note of how many of those units are still sealed. A pack of 10
+// is 10 units whether it is sealed or not, so the two counts can never
+// drift apart. Breaking a pack just lowers the sealed count, and every
+// break is recorded.
+
+import (
+	"fmt"
+	"time"
+)
+
+// StockUnit is what a quantity is counted in
+type StockUnit int
+
+const (
+	UnitEach StockUnit = iota
+	UnitPack
+)
+
+func (u StockUnit) String() string {
+	switch u {
+	case UnitEach:
+		return "each"
+	case UnitPack:
+		return "pack"
+	default:
+		return fmt.Sprintf("StockUnit(%d)", int(u))
+	}
+}
+
+// packStock is the pack bookkeeping inside a stockLevel
+type packStock struct {
+	size   int // units per pack; 0 means the item isn't sold in packs
+	sealed int
+	breaks []PackBreak
+}
+
+// PackBreak records sealed packs being opened into single units
+type PackBreak struct {
+	At    time.Time
+	Packs int
+	Units int
+}
+
+// SetPackSize declares how many units one pack of item holds
+func (inv *Inventory) SetPackSize(item PricedItem, size int) error {
+	if size < 2 {
+		return fmt.Errorf("a pack must hold at least 2 units")
+	}
+	l := inv.level(item)
+	if l.packs.sealed > 0 && l.packs.size != size {
+		return fmt.Errorf("cannot change pack size with %d sealed packs in stock", l.packs.sealed)
+	}
+	l.packs.size = size
+	return nil
+}
+
+// toUnits converts qty in unit to single units
+func (inv *Inventory) toUnits(item PricedItem, qty int, unit StockUnit) (int, error) {
+	switch unit {
+	case UnitEach:
+		return qty, nil
+	case UnitPack:
+		size := inv.level(item).packs.size
+		if size == 0 {
+			return 0, fmt.Errorf("item is not sold in packs")
+		}
+		units, err := CheckedMul(int64(qty), int64(size))
+		return int(units), err
+	default:
+		return 0, fmt.Errorf("unknown unit %v", unit)
+	}
+}
+
+// RestockIn adds qty units or sealed packs
+func (inv *Inventory) RestockIn(item PricedItem, qty int, unit StockUnit) error {
+	units, err := inv.toUnits(item, qty, unit)
+	if err != nil {
+		return err
+	}
+	if err := inv.Restock(item, units); err != nil {
+		return err
+	}
+	if unit == UnitPack {
+		inv.level(item).packs.sealed += qty
+	}
+	return nil
+}
+
+// ReserveIn reserves qty units or whole sealed packs
+func (inv *Inventory) ReserveIn(item PricedItem, qty int, unit StockUnit) error {
+	if unit != UnitPack {
+		return inv.Reserve(item, qty)
+	}
+	units, err := inv.toUnits(item, qty, unit)
+	if err != nil {
+		return err
+	}
+	l := inv.level(item)
+	if qty > l.packs.sealed {
+		return fmt.Errorf("cannot reserve %d packs, only %d sealed", qty, l.packs.sealed)
+	}
+	// Take the packs out of the sealed count first, so Reserve finds
+	// enough loose units and doesn't break any other pack
+	l.packs.sealed -= qty
+	if err := inv.Reserve(item, units); err != nil {
+		l.packs.sealed += qty
+		return err
+	}
+	return nil
+}
+
+// SealedPacks is how many unopened packs are available
+func (inv *Inventory) SealedPacks(item PricedItem) int {
+	if l, ok := inv.levels[item]; ok {
+		return l.packs.sealed
+	}
+	return 0
+}
+
+// LooseUnits is how many available units are not in a sealed pack
+func (inv *Inventory) LooseUnits(item PricedItem) int {
+	if l, ok := inv.levels[item]; ok {
+		return l.available - l.packs.sealed*l.packs.size
+	}
+	return 0
+}
+
+// PackBreaks returns the recorded pack openings, oldest first
+func (inv *Inventory) PackBreaks(item PricedItem) []PackBreak {
+	if l, ok := inv.levels[item]; ok {
+		return append([]PackBreak(nil), l.packs.breaks...)
+	}
+	return nil
+}
+
+// breakPacksFor opens just enough sealed packs to free qty loose units.
+// The caller has already checked that qty units are available.
+func (inv *Inventory) breakPacksFor(item PricedItem, l *stockLevel, qty int) {
+	loose := inv.LooseUnits(item)
+	if qty <= loose || l.packs.size == 0 {
+		return
+	}
+	// Round up: needing 12 units from packs of 10 opens 2 packs
+	packs := (qty - loose + l.packs.size - 1) / l.packs.size
+	l.packs.sealed -= packs
+	l.packs.breaks = append(l.packs.breaks, PackBreak{
+		At:    inv.now(),
+		Packs: packs,
+		Units: packs * l.packs.size,
+	})
+}
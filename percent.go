@@ -0,0 +1,71 @@
+package main
+
+// ------------------- PERCENT TYPE ----------------------------
+// A float64 percentage invites two bugs: values outside 0-100, and
+// mixing up 20 (percent) with 0.2 (fraction). A dedicated type fixes
+// both. The value field is unexported, so code outside this package
+// can only get a Percent from NewPercent, which validates once.
+// After that, every function taking a Percent can trust it.
+//
+// In Python you might subclass float and validate in __new__; Go has no
+// inheritance, so we wrap the float in a struct instead.
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrInvalidPercentage is returned for percentages outside 0-100
+var ErrInvalidPercentage = errors.New("percentage must be between 0 and 100")
+
+// Percent is a validated percentage between 0 and 100.
+// The zero value is 0%, which is valid.
+type Percent struct {
+	value float64
+}
+
+// NewPercent validates v (in percent, so 20 means 20%)
+func NewPercent(v float64) (Percent, error) {
+	// v != v is only true for NaN, which fails every comparison
+	if v != v || v < 0 || v > 100 {
+		return Percent{}, fmt.Errorf("%w, got %v", ErrInvalidPercentage, v)
+	}
+	return Percent{value: v}, nil
+}
+
+// MustPercent is NewPercent for values known to be valid, such as
+// constants in the source code. It panics on invalid input, following
+// the regexp.MustCompile convention.
+func MustPercent(v float64) Percent {
+	p, err := NewPercent(v)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Value returns the percentage, e.g. 20 for 20%
+func (p Percent) Value() float64 {
+	return p.value
+}
+
+// Fraction returns the percentage as a fraction, e.g. 0.2 for 20%
+func (p Percent) Fraction() float64 {
+	return p.value / 100
+}
+
+// Of returns this percentage of amount: MustPercent(20).Of(50) == 10
+func (p Percent) Of(amount float64) float64 {
+	return amount * p.Fraction()
+}
+
+// Off returns amount reduced by this percentage: MustPercent(20).Off(50) == 40
+func (p Percent) Off(amount float64) float64 {
+	return amount * (1 - p.Fraction())
+}
+
+// String prints "20%" or "12.5%"
+func (p Percent) String() string {
+	return strconv.FormatFloat(p.value, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,96 @@
+package main
+
+// ------------------- PRICE HISTORY ---------------------------
+// Every price change is recorded: old and new price, when, and why.
+// That answers "why did this cost $9.99 last week?" and supports rules
+// such as "a discount must be measured against the lowest price of
+// the last 30 days" (the EU's Omnibus Directive).
+//
+// SetPrice records the change with no reason; SetPriceBecause says
+// why. Code that changes prices automatically (the repricer, the price
+// import, the HTTP API) uses setPriceBecause, which falls back to
+// SetPrice for items without a history.
+
+import (
+	"slices"
+	"time"
+)
+
+// PriceChange is one entry of an item's price history
+type PriceChange struct {
+	Old, New Money
+	At       time.Time
+	Reason   string // "" when the caller gave none
+}
+
+// PriceHistorian is implemented by items that record price changes
+type PriceHistorian interface {
+	SetPriceBecause(price Money, reason string) error
+	PriceHistory() []PriceChange
+}
+
+// priceLog is embedded in the item types, like annotations
+type priceLog struct {
+	changes []PriceChange
+	// sink, when set, receives the events instead of the Events bus;
+	// the outbox uses it to hold them back until a transaction commits
+	sink func(Event)
+}
+
+// sendEventsTo redirects the item's events to fn; nil restores Events
+func (l *priceLog) sendEventsTo(fn func(Event)) {
+	l.sink = fn
+}
+
+// PriceHistory returns a copy of the recorded changes, oldest first
+func (l *priceLog) PriceHistory() []PriceChange {
+	return slices.Clone(l.changes)
+}
+
+// recordPrice logs a change of item's price from old to new and
+// publishes it as a PriceChanged event; setting the same price again
+// is not a change
+func (l *priceLog) recordPrice(item PricedItem, old, new Money, reason string) {
+	if old == new {
+		return
+	}
+	change := PriceChange{Old: old, New: new, At: currentTime(), Reason: reason}
+	l.changes = append(l.changes, change)
+	publish := Events.Publish
+	if l.sink != nil {
+		publish = l.sink
+	}
+	publish(PriceChanged{Item: item, Old: old, New: new, Reason: reason, At: change.At})
+}
+
+// setPriceBecause changes the price of any item, recording reason when
+// the item keeps a history
+func setPriceBecause(item PricedItem, price Money, reason string) error {
+	if h, ok := item.(PriceHistorian); ok {
+		return h.SetPriceBecause(price, reason)
+	}
+	return item.SetPrice(price)
+}
+
+// LowestPrice returns the lowest price item had at any time during the
+// last days days, including its current price
+func LowestPrice(item PricedItem, days int) Money {
+	lowest := item.Price()
+	h, ok := item.(PriceHistorian)
+	if !ok {
+		return lowest
+	}
+	since := currentTime().AddDate(0, 0, -days)
+	for _, c := range h.PriceHistory() {
+		if c.At.Before(since) {
+			continue
+		}
+		// Old was the price right up to the change, so it counts too
+		for _, p := range []Money{c.Old, c.New} {
+			if p.Less(lowest) {
+				lowest = p
+			}
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,228 @@
+package main
+
+// ------------------- SUPPLIER PRICE IMPORT -------------------
+// Suppliers send price lists as CSV, each with its own column names.
+// A column mapping says which column holds which field:
+//
+//	-map sku=ISBN -map price=RRP
+//
+// Fields without a mapping use a column of the same name ("title").
+// The file is parsed into a plan first; the plan can be previewed with
+// its issues and is only applied to the catalog when asked.
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// importFields are the fields a price list can provide
+var importFields = []string{"sku", "title", "price"}
+
+// ColumnMapping maps a field name to the CSV header holding it
+type ColumnMapping map[string]string
+
+// ParseColumnMapping reads "field=Header" specs
+func ParseColumnMapping(specs []string) (ColumnMapping, error) {
+	m := make(ColumnMapping)
+	for _, spec := range specs {
+		field, header, ok := strings.Cut(spec, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || header == "" {
+			return nil, fmt.Errorf("mapping %q: want field=Header", spec)
+		}
+		if !slices.Contains(importFields, field) {
+			return nil, fmt.Errorf("mapping %q: unknown field %q (fields: %s)", spec, field, strings.Join(importFields, ", "))
+		}
+		m[field] = strings.TrimSpace(header)
+	}
+	return m, nil
+}
+
+// header returns the CSV column name for field
+func (m ColumnMapping) header(field string) string {
+	if h, ok := m[field]; ok {
+		return h
+	}
+	return field
+}
+
+// PriceImportRow is one parsed line of the price list
+type PriceImportRow struct {
+	Line  int
+	SKU   string
+	Title string
+	Price Money
+}
+
+// ImportIssue is a problem found on one line; such lines are skipped
+type ImportIssue struct {
+	Line    int
+	Message string
+}
+
+// PriceImportPlan is the result of parsing, before anything changes
+type PriceImportPlan struct {
+	Rows   []PriceImportRow
+	Issues []ImportIssue
+}
+
+// PlanPriceImport parses r and checks every row against the catalog.
+// A long file can be abandoned by cancelling ctx.
+func PlanPriceImport(ctx context.Context, r io.Reader, mapping ColumnMapping, c *Catalog) (*PriceImportPlan, error) {
+	reader := csv.NewReader(r)
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	// Find each field's column; sku and price are required
+	columns := make(map[string]int)
+	for _, field := range importFields {
+		for i, h := range headers {
+			if strings.EqualFold(strings.TrimSpace(h), mapping.header(field)) {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"sku", "price"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no %q column for field %s (columns: %s)",
+				mapping.header(field), field, strings.Join(headers, ", "))
+		}
+	}
+
+	plan := &PriceImportPlan{}
+	seen := make(map[string]int)
+	for line := 2; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("stopped at line %d: %w", line, err)
+		}
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			plan.Issues = append(plan.Issues, ImportIssue{line, err.Error()})
+			continue
+		}
+		row := PriceImportRow{Line: line, SKU: strings.TrimSpace(record[columns["sku"]])}
+		if i, ok := columns["title"]; ok {
+			row.Title = strings.TrimSpace(record[i])
+		}
+		if issue := checkImportRow(&row, record[columns["price"]], c, seen); issue != "" {
+			plan.Issues = append(plan.Issues, ImportIssue{line, issue})
+			continue
+		}
+		seen[row.SKU] = line
+		plan.Rows = append(plan.Rows, row)
+	}
+	return plan, nil
+}
+
+// checkImportRow parses the price and returns a description of what is
+// wrong with the row, or "" if it can be imported
+func checkImportRow(row *PriceImportRow, rawPrice string, c *Catalog, seen map[string]int) string {
+	price, err := ParseMoney(rawPrice, DefaultCurrency)
+	switch {
+	case row.SKU == "":
+		return "missing SKU"
+	case err != nil:
+		return fmt.Sprintf("price %q is not an amount like 9.99", rawPrice)
+	case price.IsNegative():
+		return "price cannot be negative"
+	}
+	if first, dup := seen[row.SKU]; dup {
+		return fmt.Sprintf("SKU %s already imported on line %d", row.SKU, first)
+	}
+	if _, err := c.Get(row.SKU); err != nil {
+		return err.Error()
+	}
+	row.Price = price
+	return ""
+}
+
+// Preview prints the first n rows and every issue
+func (p *PriceImportPlan) Preview(w io.Writer, c *Catalog, n int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tSKU\tTITLE\tOLD PRICE\tNEW PRICE")
+	for _, row := range p.Rows[:min(n, len(p.Rows))] {
+		item, _ := c.Get(row.SKU)
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%v\t%v\n", row.Line, row.SKU, row.Title, item.Price(), row.Price)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(p.Rows) > n {
+		fmt.Fprintf(w, "... and %d more rows\n", len(p.Rows)-n)
+	}
+	for _, issue := range p.Issues {
+		fmt.Fprintf(w, "line %d skipped: %s\n", issue.Line, issue.Message)
+	}
+	return nil
+}
+
+// Apply sets the new prices; rows with issues were already left out.
+// If ctx is cancelled, the rows before the current one stay applied.
+func (p *PriceImportPlan) Apply(ctx context.Context, c *Catalog) error {
+	for _, row := range p.Rows {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("stopped before line %d: %w", row.Line, err)
+		}
+		item, err := c.Get(row.SKU)
+		if err != nil {
+			return err
+		}
+		if err := setPriceBecause(item, row.Price, "supplier price import"); err != nil {
+			return fmt.Errorf("line %d: %w", row.Line, err)
+		}
+	}
+	return nil
+}
+
+// stringList is a flag that may be repeated: -map a=b -map c=d
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func cmdImportPrices(s *cliSession, args []string, out io.Writer) error {
+	c := s.catalog
+	fs := newFlagSet("import-prices", out)
+	file := fs.String("file", "", "CSV price list to import")
+	preview := fs.Int("preview", 0, "only show the first N rows and issues, change nothing")
+	var specs stringList
+	fs.Var(&specs, "map", "field=Header column mapping (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	mapping, err := ParseColumnMapping(specs)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Ctrl-C cancels ctx, stopping the import cleanly between rows
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	plan, err := PlanPriceImport(ctx, f, mapping, c)
+	if err != nil {
+		return err
+	}
+	if *preview > 0 {
+		return plan.Preview(out, c, *preview)
+	}
+	if err := plan.Apply(ctx, c); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Updated %d prices, skipped %d lines\n", len(plan.Rows), len(plan.Issues))
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+// ------------------- PRICE SOURCE AGGREGATION ----------------
+// When several sources quote a price for the same item, one broken feed
+// ("$0.01", "$9999") should not drag the result around. We use the
+// median absolute deviation (MAD): quotes further than k·MAD from the
+// median are rejected, and the reference price is the median of the rest.
+// Unlike the mean and standard deviation, the median and MAD are
+// themselves barely affected by the outliers we are trying to catch.
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// DefaultOutlierK is a common choice: about 3 standard deviations
+// for normally distributed data
+const DefaultOutlierK = 3.0
+
+// PriceQuote is one source's opinion of an item's price
+type PriceQuote struct {
+	Source string
+	Price  float64
+}
+
+// AggregatedPrice is the outcome of AggregatePrices
+type AggregatedPrice struct {
+	// Reference is the median of the accepted quotes
+	Reference float64
+	Accepted  []PriceQuote
+	// Rejected quotes were outliers (or invalid), with the reason
+	Rejected []RejectedQuote
+}
+
+// RejectedQuote explains why a quote was discarded
+type RejectedQuote struct {
+	PriceQuote
+	Reason string
+}
+
+// AggregatePrices rejects invalid quotes and outliers beyond k·MAD from
+// the median, then returns the median of the remaining quotes
+func AggregatePrices(quotes []PriceQuote, k float64) (AggregatedPrice, error) {
+	var result AggregatedPrice
+	var valid []PriceQuote
+	for _, q := range quotes {
+		if math.IsNaN(q.Price) || math.IsInf(q.Price, 0) || q.Price < 0 {
+			result.Rejected = append(result.Rejected, RejectedQuote{q, "invalid price"})
+			continue
+		}
+		valid = append(valid, q)
+	}
+	if len(valid) == 0 {
+		return result, fmt.Errorf("no valid price quotes")
+	}
+
+	prices := Map(valid, func(q PriceQuote) float64 { return q.Price })
+	center := median(prices)
+	deviations := Map(prices, func(p float64) float64 { return math.Abs(p - center) })
+	mad := median(deviations)
+
+	for _, q := range valid {
+		// With MAD = 0 most sources agree exactly, so any
+		// disagreement at all counts as an outlier
+		if math.Abs(q.Price-center) > k*mad {
+			reason := fmt.Sprintf("%.2f is more than %.1f MAD from median %.2f", q.Price, k, center)
+			result.Rejected = append(result.Rejected, RejectedQuote{q, reason})
+			continue
+		}
+		result.Accepted = append(result.Accepted, q)
+	}
+	if len(result.Accepted) == 0 {
+		// Only possible with k < 1
+		return result, fmt.Errorf("every quote was rejected as an outlier")
+	}
+	result.Reference = median(Map(result.Accepted, func(q PriceQuote) float64 { return q.Price }))
+	return result, nil
+}
+
+// median of a non-empty slice (the input is not modified)
+func median(values []float64) float64 {
+	sorted := slices.Sorted(slices.Values(values))
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
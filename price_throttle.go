@@ -0,0 +1,118 @@
+package main
+
+// ------------------- PRICE CHANGE THROTTLING -----------------
+// Automated tools can flip a price back and forth many times a minute.
+// PriceChangeLimiter allows at most N changes per item within a sliding
+// window (an hour by default) and rejects the rest with an error that
+// says how long to wait - the same idea as HTTP's 429 + Retry-After,
+// which is what the HTTP API answers with (see server.go).
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPriceChangeThrottled matches any throttling error via errors.Is
+var ErrPriceChangeThrottled = errors.New("too many price changes")
+
+// PriceChangeThrottledError carries how long until the next change is allowed.
+// Use errors.As to get at RetryAfter; errors.Is(err, ErrPriceChangeThrottled)
+// also works thanks to the Is method below.
+type PriceChangeThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *PriceChangeThrottledError) Error() string {
+	return fmt.Sprintf("%v: retry after %v", ErrPriceChangeThrottled, e.RetryAfter.Round(time.Second))
+}
+
+// Is lets errors.Is treat every PriceChangeThrottledError as the sentinel
+func (e *PriceChangeThrottledError) Is(target error) bool {
+	return target == ErrPriceChangeThrottled
+}
+
+// PriceChangeLimiter guards SetPrice calls. It is safe for concurrent
+// use.
+type PriceChangeLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	// now is currentTime, replaceable so callers can control the clock
+	now func() time.Time
+	// Interface values holding pointers are comparable, so an item
+	// itself can be a map key - no ID needed
+	changes map[PricedItem][]time.Time
+}
+
+// NewPriceChangeLimiter allows "limit" changes per item per window
+func NewPriceChangeLimiter(limit int, window time.Duration) (*PriceChangeLimiter, error) {
+	// A limit of 0 would refuse every change; that's a frozen price,
+	// not a throttle
+	if limit < 1 {
+		return nil, fmt.Errorf("price change limit must be at least 1, got %d", limit)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("price change window must be positive, got %v", window)
+	}
+	return &PriceChangeLimiter{
+		limit:   limit,
+		window:  window,
+		now:     currentTime,
+		changes: make(map[PricedItem][]time.Time),
+	}, nil
+}
+
+// SetPrice changes the item's price unless it changed too often recently.
+// Setting the price it already has is not a change and is always allowed.
+// The item is read and changed under the limiter's lock, so it must not
+// be changed any other way meanwhile.
+func (l *PriceChangeLimiter) SetPrice(item PricedItem, price Money) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.change(item, item.Price(), price, func() error { return item.SetPrice(price) })
+}
+
+// Change is SetPrice for callers that set the price their own way, e.g.
+// through Catalog.SetPrice with a reason: set runs only if the change
+// is allowed, and counts only if it succeeds. current is the item's
+// price now; the limiter doesn't read it from the item, which callers
+// such as a Catalog lock themselves.
+func (l *PriceChangeLimiter) Change(item PricedItem, current, price Money, set func() error) error {
+	// Checking and recording must be one step, or two concurrent
+	// changes could both squeeze into the last free slot
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.change(item, current, price, set)
+}
+
+// change does the work of SetPrice and Change; the caller holds l.mu
+func (l *PriceChangeLimiter) change(item PricedItem, current, price Money, set func() error) error {
+	if current == price {
+		return nil
+	}
+	now := l.now()
+	recent := l.recentChanges(item, now)
+	if len(recent) >= l.limit {
+		// The oldest change leaves the window first
+		return &PriceChangeThrottledError{RetryAfter: recent[0].Add(l.window).Sub(now)}
+	}
+	if err := set(); err != nil {
+		return err
+	}
+	l.changes[item] = append(recent, now)
+	return nil
+}
+
+// recentChanges drops timestamps that fell out of the window. The
+// caller holds l.mu.
+func (l *PriceChangeLimiter) recentChanges(item PricedItem, now time.Time) []time.Time {
+	times := l.changes[item]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestNewPriceChangeLimiterRejectsBadSettings(t *testing.T) {
+	for _, tt := range []struct {
+		limit  int
+		window time.Duration
+	}{{0, time.Hour}, {-1, time.Hour}, {1, 0}, {1, -time.Second}} {
+		if _, err := NewPriceChangeLimiter(tt.limit, tt.window); err == nil {
+			t.Errorf("NewPriceChangeLimiter(%d, %v) succeeded", tt.limit, tt.window)
+		}
+	}
+}
+
+func TestPriceChangeLimiterWindow(t *testing.T) {
+	limiter := Must(NewPriceChangeLimiter(2, time.Hour))
+	clock := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return clock }
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(10), ""))
+
+	assert.NoError(t, limiter.SetPrice(book, Dollars(11)))
+	clock = clock.Add(10 * time.Minute)
+	assert.NoError(t, limiter.SetPrice(book, Dollars(12)))
+	// The same price again is not a change
+	assert.NoError(t, limiter.SetPrice(book, Dollars(12)))
+
+	clock = clock.Add(10 * time.Minute)
+	err := limiter.SetPrice(book, Dollars(13))
+	var throttled *PriceChangeThrottledError
+	if errors.As(err, &throttled) {
+		assert.Equal(t, throttled.RetryAfter, 40*time.Minute)
+	} else {
+		t.Fatalf("got %v, want a PriceChangeThrottledError", err)
+	}
+	assert.Equal(t, book.Price(), Dollars(12))
+
+	// The first change leaves the window an hour after it was made
+	clock = clock.Add(40 * time.Minute)
+	assert.NoError(t, limiter.SetPrice(book, Dollars(13)))
+
+	// A change that fails doesn't count: one slot is free again once
+	// the second change leaves the window, and it stays free
+	clock = clock.Add(10 * time.Minute)
+	assert.ErrorIs(t, limiter.SetPrice(book, Dollars(-1)), ErrNegativePrice)
+	assert.NoError(t, limiter.SetPrice(book, Dollars(14)))
+}
+
+func TestPriceChangeLimiterConcurrent(t *testing.T) {
+	limiter := Must(NewPriceChangeLimiter(5, time.Hour))
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(10), ""))
+	var accepted, throttled atomic.Int64
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			price := Dollars(float64(20 + i))
+			err := limiter.SetPrice(book, price)
+			switch {
+			case err == nil:
+				accepted.Add(1)
+			case errors.Is(err, ErrPriceChangeThrottled):
+				throttled.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, accepted.Load(), int64(5))
+	assert.Equal(t, throttled.Load(), int64(45))
+}
+
+func TestServerThrottlesPriceChanges(t *testing.T) {
+	server := Must(NewCatalogServer(sampleCatalog()))
+	server.Throttle = Must(NewPriceChangeLimiter(1, time.Hour))
+	assert.NoError(t, server.Authz.AddKey(managerKey, Actor{Name: "kim", Role: RoleManager}))
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+managerKey)
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	put := func(body string) *httptest.ResponseRecorder { return send("PUT", "/items/BK-001/price", body) }
+
+	// An invalid price is 422 and uses up nothing
+	assert.Equal(t, put(`{"price": -1}`).Code, http.StatusUnprocessableEntity)
+	assert.Equal(t, put(`{"price": 11.99}`).Code, http.StatusOK)
+	rec := put(`{"price": 10.99}`)
+	assert.Equal(t, rec.Code, http.StatusTooManyRequests)
+	assert.Equal(t, rec.Header().Get("Retry-After"), "3600")
+
+	rec = send("POST", "/batch", `{"operations": [{"op": "update_price", "sku": "BK-001", "price": 9.99}]}`)
+	if !strings.Contains(rec.Body.String(), `"status":429`) {
+		t.Errorf("batch update was not throttled: %s", rec.Body)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+// runPricedContract checks the promises every PricedItem makes, on
+// fresh items from newItem. A new item type is covered by adding one
+// line to TestPricedItemContract.
+//
+// It lives here rather than in a pricedtest package because PricedItem
+// is declared in package main, which other packages cannot import.
+func runPricedContract(t *testing.T, newItem func() PricedItem) {
+	t.Run("price is never negative", func(t *testing.T) {
+		if newItem().Price().IsNegative() {
+			t.Error("a new item has a negative price")
+		}
+	})
+
+	t.Run("negative price rejected", func(t *testing.T) {
+		item := newItem()
+		before := item.Price()
+		if err := item.SetPrice(amountIn(-1, before.currency)); err == nil {
+			t.Errorf("SetPrice accepted -1.00 %s", before.Currency())
+		}
+		assert.Equal(t, item.Price(), before)
+	})
+
+	t.Run("Price after SetPrice", func(t *testing.T) {
+		item := newItem()
+		before := item.Price()
+		want := amountIn(7.25, before.currency)
+		// Items whose price is derived (bundles) may refuse, but must
+		// then keep their price
+		if err := item.SetPrice(want); err != nil {
+			assert.Equal(t, item.Price(), before)
+			return
+		}
+		assert.Equal(t, item.Price(), want)
+	})
+
+	t.Run("invalid percentages rejected", func(t *testing.T) {
+		// A Percent can't hold NaN or an out-of-range value, so no
+		// CalculateDiscount ever sees one
+		for _, v := range []float64{-1, 100.5, math.NaN()} {
+			if _, err := NewPercent(v); !errors.Is(err, ErrInvalidPercentage) {
+				t.Errorf("NewPercent(%v): got %v, want ErrInvalidPercentage", v, err)
+			}
+		}
+	})
+
+	t.Run("discount bounds", func(t *testing.T) {
+		item := newItem()
+		price := item.Price()
+		none, err := item.CalculateDiscount(MustPercent(0))
+		if assert.NoError(t, err) {
+			assert.Equal(t, none, price)
+		}
+		all, err := item.CalculateDiscount(MustPercent(100))
+		if assert.NoError(t, err) && !all.IsZero() {
+			t.Errorf("100%% off %v is %v, want zero", price, all)
+		}
+		half, err := item.CalculateDiscount(MustPercent(50))
+		if assert.NoError(t, err) {
+			if half.IsNegative() || mustLess(t, price, half) {
+				t.Errorf("50%% off %v is %v, outside [0, price]", price, half)
+			}
+			assert.Equal(t, half.Currency(), price.Currency())
+		}
+		// CalculateDiscount previews; the price itself stays
+		assert.Equal(t, item.Price(), price)
+	})
+
+	t.Run("percentage discount in the item's currency", func(t *testing.T) {
+		item := newItem()
+		price := item.Price()
+		engine := NewPricingEngine(StackAll, PercentageDiscount{Percent: MustPercent(20)})
+		result := engine.Price(DiscountContext{Item: item, Quantity: 1})
+		assert.Equal(t, result.Final, price.Off(MustPercent(20)))
+		assert.Equal(t, result.Applied, []string{"20% off"})
+	})
+}
+
+// mustLess reports a < b, failing t on a currency mismatch
+func mustLess(t *testing.T, a, b Money) bool {
+	t.Helper()
+	c, err := a.Cmp(b)
+	assert.NoError(t, err)
+	return c < 0
+}
+
+func TestPricedItemContract(t *testing.T) {
+	implementations := map[string]func() PricedItem{
+		"Book": func() PricedItem {
+			return Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+		},
+		"Book in euros": func() PricedItem {
+			return Must(NewBook("Faust", "Goethe", Must(NewMoney(1000, "EUR")), ""))
+		},
+		"Magazine": func() PricedItem {
+			return Must(NewMagazine("Vogue", Dollars(12.99), 1))
+		},
+		"EBook": func() PricedItem {
+			return Must(NewEBook("Dune", "Frank Herbert", Dollars(6.99), FormatEPUB, 1<<20))
+		},
+		"AudioBook": func() PricedItem {
+			return Must(NewAudioBook("Dune", "Frank Herbert", "Scott Brick", Dollars(24.99), 21*time.Hour))
+		},
+		"Bundle": func() PricedItem {
+			b := Must(NewBundle("Sci-fi starter", MustPercent(10)))
+			b.Add(Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), "")))
+			b.Add(Must(NewMagazine("Analog", Dollars(7.99), 1)))
+			return b
+		},
+	}
+	for name, newItem := range implementations {
+		t.Run(name, func(t *testing.T) { runPricedContract(t, newItem) })
+	}
+}
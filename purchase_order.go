@@ -0,0 +1,173 @@
+package main
+
+// ------------------- PURCHASE ORDERS -------------------------
+// A purchase order (PO) is what the store sends a supplier: "please
+// deliver 50 of this at $8 each". Deliveries often come in parts, so
+// every line remembers how much has been received so far:
+//
+//	Open --some received--> PartiallyReceived --all received--> Received
+//
+// Receiving goes straight into the inventory as a costed lot, and the
+// final delivery teaches the supplier its lead time.
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// POStatus is where a purchase order is in its life
+type POStatus int
+
+const (
+	POOpen POStatus = iota
+	POPartiallyReceived
+	POReceived
+)
+
+func (s POStatus) String() string {
+	switch s {
+	case POOpen:
+		return "open"
+	case POPartiallyReceived:
+		return "partially received"
+	case POReceived:
+		return "received"
+	default:
+		return fmt.Sprintf("POStatus(%d)", int(s))
+	}
+}
+
+// POLine is one item on a purchase order
+type POLine struct {
+	Item     PricedItem
+	Quantity int
+	UnitCost Money
+	Received int
+}
+
+// Outstanding is how many units are still to come
+func (l POLine) Outstanding() int {
+	return l.Quantity - l.Received
+}
+
+// PurchaseOrder is created with Supplier.NewPurchaseOrder
+type PurchaseOrder struct {
+	ID         string
+	Supplier   *Supplier
+	OrderedAt  time.Time
+	ReceivedAt time.Time // zero until the last unit arrives
+	Lines      []POLine
+}
+
+// NewPurchaseOrder starts an empty order to this supplier
+func (s *Supplier) NewPurchaseOrder(id string, orderedAt time.Time) *PurchaseOrder {
+	return &PurchaseOrder{ID: id, Supplier: s, OrderedAt: orderedAt}
+}
+
+// AddLine orders qty units of item at unitCost each
+func (po *PurchaseOrder) AddLine(item PricedItem, qty int, unitCost Money) error {
+	if qty <= 0 {
+		return fmt.Errorf("order quantity must be positive")
+	}
+	if unitCost.IsNegative() {
+		return fmt.Errorf("unit cost cannot be negative")
+	}
+	if po.received() > 0 {
+		return fmt.Errorf("purchase order %s: cannot add lines after receiving", po.ID)
+	}
+	for _, l := range po.Lines {
+		if l.Item == item {
+			return fmt.Errorf("purchase order %s: item already ordered", po.ID)
+		}
+	}
+	po.Lines = append(po.Lines, POLine{Item: item, Quantity: qty, UnitCost: unitCost})
+	return nil
+}
+
+// Receive books qty delivered units of item into inv at the line's cost.
+// Receiving the last outstanding unit records the supplier's lead time.
+func (po *PurchaseOrder) Receive(inv *Inventory, item PricedItem, qty int, at time.Time) error {
+	i := po.line(item)
+	if i < 0 {
+		return fmt.Errorf("purchase order %s: item was not ordered", po.ID)
+	}
+	line := &po.Lines[i]
+	if qty > line.Outstanding() {
+		return fmt.Errorf("purchase order %s: received %d, only %d outstanding", po.ID, qty, line.Outstanding())
+	}
+	// Checked first: once the stock is booked there is no going back
+	if at.Before(po.OrderedAt) {
+		return fmt.Errorf("purchase order %s: delivery received before it was ordered", po.ID)
+	}
+	if err := inv.ReceiveLot(item, qty, line.UnitCost); err != nil {
+		return err
+	}
+	line.Received += qty
+	if po.Status() == POReceived {
+		po.ReceivedAt = at
+		return po.Supplier.RecordDelivery(po.OrderedAt, at)
+	}
+	return nil
+}
+
+func (po *PurchaseOrder) line(item PricedItem) int {
+	for i, l := range po.Lines {
+		if l.Item == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func (po *PurchaseOrder) received() int {
+	total := 0
+	for _, l := range po.Lines {
+		total += l.Received
+	}
+	return total
+}
+
+// Status is derived from the lines, so it can never disagree with them
+func (po *PurchaseOrder) Status() POStatus {
+	outstanding := 0
+	for _, l := range po.Lines {
+		outstanding += l.Outstanding()
+	}
+	switch {
+	case len(po.Lines) > 0 && outstanding == 0:
+		return POReceived
+	case po.received() > 0:
+		return POPartiallyReceived
+	default:
+		return POOpen
+	}
+}
+
+// Total is the cost of the whole order
+func (po *PurchaseOrder) Total() (Money, error) {
+	var sum lineSum
+	for _, l := range po.Lines {
+		if err := sum.add(l.UnitCost, l.Quantity); err != nil {
+			return Money{}, err
+		}
+	}
+	return sum.total, nil
+}
+
+// Print writes a status report of the order, one row per line
+func (po *PurchaseOrder) Print(w io.Writer, label func(PricedItem) string) error {
+	total, err := po.Total()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "PO %s to %s: %v, total %v\n", po.ID, po.Supplier.Name, po.Status(), total)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tORDERED\tRECEIVED\tOUTSTANDING\tUNIT COST")
+	for _, l := range po.Lines {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%v\n",
+			label(l.Item), l.Quantity, l.Received, l.Outstanding(), l.UnitCost)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestReceiveBeforeOrderedChangesNothing(t *testing.T) {
+	supplier := &Supplier{Name: "Penguin", DefaultLeadTime: 72 * time.Hour}
+	ordered := time.Date(2024, time.March, 15, 9, 0, 0, 0, time.UTC)
+	po := supplier.NewPurchaseOrder("PO-1", ordered)
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	assert.NoError(t, po.AddLine(book, 5, Dollars(4)))
+	inv := NewInventory()
+
+	if err := po.Receive(inv, book, 5, ordered.Add(-time.Hour)); err == nil {
+		t.Fatal("received a delivery from before the order")
+	}
+	assert.Equal(t, inv.AvailableQuantity(book), 0)
+	assert.Equal(t, po.Lines[0].Received, 0)
+	assert.Equal(t, po.Status(), POOpen)
+	assert.Equal(t, supplier.LeadTime(), 72*time.Hour)
+
+	// A valid delivery afterwards goes through as usual
+	assert.NoError(t, po.Receive(inv, book, 5, ordered.Add(48*time.Hour)))
+	assert.Equal(t, inv.AvailableQuantity(book), 5)
+	assert.Equal(t, po.Status(), POReceived)
+	assert.Equal(t, supplier.LeadTime(), 48*time.Hour)
+}
@@ -0,0 +1,103 @@
+package main
+
+// ------------------- QUOTES ----------------------------------
+// Business customers often need a written quote before they can place
+// an order: "20 copies of Vogue at $11.04 each, valid for 30 days".
+// A Quote freezes the cart's prices (discounts included) when it is
+// issued. Converting it to an order within the validity period uses
+// those prices, even if the catalog has changed in between.
+//
+// Tax is worked out at conversion time, like any order: a quote locks
+// prices, not tax law.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// ErrQuoteExpired is returned when converting a quote after ExpiresAt
+var ErrQuoteExpired = errors.New("quote has expired")
+
+// Quote is a price offer to one customer, valid until ExpiresAt
+type Quote struct {
+	ID        string
+	Customer  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Lines     []OrderLine
+	Total     Money // before tax
+
+	// order is set once the quote has been converted
+	order *Order
+}
+
+// Quote locks the cart's current prices for customer for validDays
+// days. The cart itself is left as it is.
+func (c *Cart) Quote(id, customer string, engine *PricingEngine, at time.Time, validDays int) (*Quote, error) {
+	if len(c.lines) == 0 {
+		return nil, fmt.Errorf("cannot quote an empty cart")
+	}
+	if validDays <= 0 {
+		return nil, fmt.Errorf("a quote must be valid for at least 1 day")
+	}
+	q := &Quote{
+		ID:        id,
+		Customer:  customer,
+		IssuedAt:  at,
+		ExpiresAt: at.AddDate(0, 0, validDays),
+		Lines:     c.priceLines(engine, at),
+	}
+	var sum lineSum
+	for _, l := range q.Lines {
+		if err := sum.add(l.Paid, l.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	q.Total = sum.total
+	return q, nil
+}
+
+// Valid reports whether the quote can still be converted at time at
+func (q *Quote) Valid(at time.Time) bool {
+	return q.order == nil && !at.After(q.ExpiresAt)
+}
+
+// ToOrder places an order at the quoted prices. A quote converts only
+// once; tax, if tax is not nil, is added for region.
+func (q *Quote) ToOrder(tax TaxCalculator, region string, at time.Time) (*Order, error) {
+	if q.order != nil {
+		return nil, fmt.Errorf("quote %s was already converted to an order", q.ID)
+	}
+	if at.After(q.ExpiresAt) {
+		return nil, fmt.Errorf("quote %s: %w on %s", q.ID, ErrQuoteExpired, q.ExpiresAt.Format(time.DateOnly))
+	}
+	// Copy the lines so the order and the quote can't change each other
+	order, err := orderFromLines(append([]OrderLine(nil), q.Lines...), tax, region, at)
+	if err != nil {
+		return nil, err
+	}
+	q.order = order
+	Events.Publish(OrderPlaced{Order: order})
+	return order, nil
+}
+
+// Print writes the quote document; label names each item
+func (q *Quote) Print(w io.Writer, label func(PricedItem) string) error {
+	fmt.Fprintf(w, "QUOTE %s for %s\n", q.ID, q.Customer)
+	fmt.Fprintf(w, "Issued %s, valid until %s\n",
+		q.IssuedAt.Format(time.DateOnly), q.ExpiresAt.Format(time.DateOnly))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tQTY\tLIST\tQUOTED\tAMOUNT")
+	for _, l := range q.Lines {
+		amount, err := l.Paid.Times(l.Quantity)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\n", label(l.Item), l.Quantity, l.UnitPrice, l.Paid, amount)
+	}
+	fmt.Fprintf(tw, "Total before tax\t\t\t\t%v\n", q.Total)
+	return tw.Flush()
+}
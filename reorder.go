@@ -0,0 +1,139 @@
+package main
+
+// ------------------- SUPPLIERS AND REORDER POINTS ------------
+// When should we order more? Early enough that stock doesn't run out
+// while the delivery is on its way:
+//
+//	reorder point = lead time (days) × sales per day + safety stock
+//
+// Lead times are learned from real deliveries per supplier, and sales
+// per day come from the inventory's record of committed sales.
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+)
+
+// Supplier is a company the store buys stock from
+type Supplier struct {
+	Name string
+	// DefaultLeadTime is assumed until a delivery has been recorded
+	DefaultLeadTime time.Duration
+
+	leadTimes []time.Duration
+}
+
+// RecordDelivery remembers how long one order took to arrive
+func (s *Supplier) RecordDelivery(ordered, received time.Time) error {
+	if received.Before(ordered) {
+		return fmt.Errorf("delivery received before it was ordered")
+	}
+	s.leadTimes = append(s.leadTimes, received.Sub(ordered))
+	return nil
+}
+
+// LeadTime is the average of recorded deliveries, or the default
+func (s *Supplier) LeadTime() time.Duration {
+	if len(s.leadTimes) == 0 {
+		return s.DefaultLeadTime
+	}
+	var total time.Duration
+	for _, lt := range s.leadTimes {
+		total += lt
+	}
+	return total / time.Duration(len(s.leadTimes))
+}
+
+// ReorderPoint applies the formula above, rounding up to whole units
+func ReorderPoint(leadTime time.Duration, unitsPerDay float64, safetyStock int) int {
+	days := leadTime.Hours() / 24
+	return int(math.Ceil(days*unitsPerDay)) + safetyStock
+}
+
+// ReorderPolicy says who supplies an item and how much spare stock to keep
+type ReorderPolicy struct {
+	Supplier    *Supplier
+	SafetyStock int
+}
+
+// ReorderPlanner watches the stock of items that have a ReorderPolicy
+type ReorderPlanner struct {
+	Inventory *Inventory
+	// VelocityWindow is how far back sales are averaged (default 30 days)
+	VelocityWindow time.Duration
+
+	items    []PricedItem // registration order, for stable reports
+	policies map[PricedItem]ReorderPolicy
+}
+
+func NewReorderPlanner(inv *Inventory) *ReorderPlanner {
+	return &ReorderPlanner{
+		Inventory:      inv,
+		VelocityWindow: 30 * 24 * time.Hour,
+		policies:       make(map[PricedItem]ReorderPolicy),
+	}
+}
+
+// Track starts (or updates) reorder planning for item
+func (p *ReorderPlanner) Track(item PricedItem, policy ReorderPolicy) error {
+	if policy.Supplier == nil {
+		return fmt.Errorf("reorder policy needs a supplier")
+	}
+	if policy.SafetyStock < 0 {
+		return fmt.Errorf("safety stock cannot be negative")
+	}
+	if _, seen := p.policies[item]; !seen {
+		p.items = append(p.items, item)
+	}
+	p.policies[item] = policy
+	return nil
+}
+
+// LowStockLine is one row of the low-stock report
+type LowStockLine struct {
+	Item         PricedItem
+	Supplier     string
+	Available    int
+	UnitsPerDay  float64
+	ReorderPoint int
+	// Reorder is true when available stock is at or below the reorder point
+	Reorder bool
+}
+
+// LowStockReport computes the reorder point of every tracked item
+func (p *ReorderPlanner) LowStockReport() []LowStockLine {
+	lines := make([]LowStockLine, 0, len(p.items))
+	for _, item := range p.items {
+		policy := p.policies[item]
+		velocity := p.Inventory.SalesVelocity(item, p.VelocityWindow)
+		point := ReorderPoint(policy.Supplier.LeadTime(), velocity, policy.SafetyStock)
+		available := p.Inventory.AvailableQuantity(item)
+		lines = append(lines, LowStockLine{
+			Item:         item,
+			Supplier:     policy.Supplier.Name,
+			Available:    available,
+			UnitsPerDay:  velocity,
+			ReorderPoint: point,
+			Reorder:      available <= point,
+		})
+	}
+	return lines
+}
+
+// PrintLowStockReport writes the report as a table, flagging rows to reorder
+func PrintLowStockReport(w io.Writer, lines []LowStockLine, label func(PricedItem) string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tSUPPLIER\tAVAILABLE\tPER DAY\tREORDER AT\t")
+	for _, l := range lines {
+		flag := ""
+		if l.Reorder {
+			flag = "REORDER"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.1f\t%d\t%s\n",
+			label(l.Item), l.Supplier, l.Available, l.UnitsPerDay, l.ReorderPoint, flag)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,340 @@
+package main
+
+// ------------------- SQL REPOSITORY --------------------------
+// A repository hides WHERE items are stored behind an interface, so
+// the rest of the program doesn't care whether it's a map, a JSON file
+// or a database.
+//
+// SQLRepository uses database/sql, Go's equivalent of Python's DB-API.
+// database/sql holds no driver itself: a driver package registers one
+// when imported, e.g. for SQLite without cgo
+//
+//	import _ "modernc.org/sqlite"
+//	db, err := sql.Open("sqlite", "bookstore.db")
+//
+// This module has no dependencies, so no driver is imported here; the
+// SQL below is written for SQLite. The tests run it against a small
+// fake driver in repository_test.go. MemoryRepository, at the end of
+// the file, needs no database at all.
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// CatalogEntry is an item together with its SKU
+type CatalogEntry struct {
+	SKU  string
+	Item PricedItem
+}
+
+// Repository stores items by SKU
+type Repository interface {
+	Create(sku string, item PricedItem) error
+	FindByID(sku string) (PricedItem, error)
+	FindAll() ([]CatalogEntry, error)
+	Update(sku string, item PricedItem) error
+	Delete(sku string) error
+}
+
+// migrations are applied in order, each exactly once. Never edit one
+// that has shipped; append a new one instead.
+var migrations = []string{
+	`CREATE TABLE items (
+		sku         TEXT PRIMARY KEY,
+		type        TEXT NOT NULL,
+		title       TEXT NOT NULL,
+		price_minor INTEGER NOT NULL, -- exact, in cents
+		currency    TEXT NOT NULL,
+		data        TEXT NOT NULL -- the item's full JSON form
+	)`,
+	// Prices only compare within one currency, so sort by it first
+	`CREATE INDEX items_price ON items (currency, price_minor)`,
+	// Events waiting to be published, see outbox.go
+	`CREATE TABLE outbox (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		sku       TEXT NOT NULL,
+		name      TEXT NOT NULL,
+		data      TEXT NOT NULL,
+		published INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX outbox_pending ON outbox (published, id)`,
+	// The last order number of each store, see order_numbers.go
+	`CREATE TABLE order_sequences (
+		store TEXT PRIMARY KEY,
+		last  INTEGER NOT NULL
+	)`,
+	// Staff notes as JSON, NULL when there are none (see notes.go)
+	`ALTER TABLE items ADD COLUMN notes TEXT`,
+}
+
+// SQLRepository keeps items in the items table. Title and price get
+// their own columns so SQL can search and sort on them. Like Money, the
+// price is stored as whole minor units with its currency, not as a REAL
+// that would round and mix currencies up. Everything else
+// lives in the JSON data column, apart from staff notes, which have a
+// column of their own.
+type SQLRepository struct {
+	db *sql.DB
+}
+
+// NewSQLRepository migrates the schema of db and returns a repository
+func NewSQLRepository(db *sql.DB) (*SQLRepository, error) {
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("migrating schema: %w", err)
+	}
+	return &SQLRepository{db: db}, nil
+}
+
+// migrate brings the schema up to date inside one transaction
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback after a successful Commit does nothing, so deferring it
+	// is the usual way to undo everything on an early return
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	var version int
+	err = tx.QueryRow(`SELECT version FROM schema_version`).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (0)`); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE schema_version SET version = ?`, len(migrations)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// itemRow holds the values of an item's columns
+type itemRow struct {
+	typ, title string
+	priceMinor int64
+	currency   string
+	data       string
+	// notes is nil, for SQL NULL, when the item has none
+	notes any
+}
+
+// newItemRow converts item to the values of its columns
+func newItemRow(item PricedItem) (itemRow, error) {
+	typ, err := itemType(item)
+	if err != nil {
+		return itemRow{}, err
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return itemRow{}, err
+	}
+	// The price columns are for searching and sorting; data holds the
+	// price too
+	price := item.Price()
+	row := itemRow{typ: typ, title: itemTitle(item), priceMinor: price.Minor(), currency: price.Currency(), data: string(data)}
+	if notes := itemNotes(item); len(notes) > 0 {
+		text, err := json.Marshal(notes)
+		if err != nil {
+			return itemRow{}, err
+		}
+		row.notes = string(text)
+	}
+	return row, nil
+}
+
+// scanItem rebuilds an item from its type, data and notes columns
+func scanItem(typ, data string, notes sql.NullString) (PricedItem, error) {
+	item, err := decodeItem(typ, []byte(data))
+	if err != nil || !notes.Valid {
+		return item, err
+	}
+	var list []Note
+	if err := json.Unmarshal([]byte(notes.String), &list); err != nil {
+		return nil, fmt.Errorf("notes: %w", err)
+	}
+	return item, setItemNotes(item, list)
+}
+
+func (r *SQLRepository) Create(sku string, item PricedItem) error {
+	if sku == "" {
+		return fmt.Errorf("SKU cannot be empty")
+	}
+	row, err := newItemRow(item)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM items WHERE sku = ?`, sku).Scan(&exists)
+	if err == nil {
+		return fmt.Errorf("SKU %q is already in the catalog", sku)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// ? placeholders let the driver escape values: never build SQL
+	// with fmt.Sprintf, that's how SQL injection happens
+	_, err = tx.Exec(`INSERT INTO items (sku, type, title, price_minor, currency, data, notes) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sku, row.typ, row.title, row.priceMinor, row.currency, row.data, row.notes)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLRepository) FindByID(sku string) (PricedItem, error) {
+	var typ, data string
+	var notes sql.NullString
+	err := r.db.QueryRow(`SELECT type, data, notes FROM items WHERE sku = ?`, sku).Scan(&typ, &data, &notes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &ItemNotFoundError{SKU: sku}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanItem(typ, data, notes)
+}
+
+func (r *SQLRepository) FindAll() ([]CatalogEntry, error) {
+	rows, err := r.db.Query(`SELECT sku, type, data, notes FROM items ORDER BY sku`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []CatalogEntry
+	for rows.Next() {
+		var sku, typ, data string
+		var notes sql.NullString
+		if err := rows.Scan(&sku, &typ, &data, &notes); err != nil {
+			return nil, err
+		}
+		item, err := scanItem(typ, data, notes)
+		if err != nil {
+			return nil, fmt.Errorf("item %q: %w", sku, err)
+		}
+		entries = append(entries, CatalogEntry{SKU: sku, Item: item})
+	}
+	// rows.Err reports a failure that ended the loop early
+	return entries, rows.Err()
+}
+
+func (r *SQLRepository) Update(sku string, item PricedItem) error {
+	row, err := newItemRow(item)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.Exec(`UPDATE items SET type = ?, title = ?, price_minor = ?, currency = ?, data = ?, notes = ? WHERE sku = ?`,
+		row.typ, row.title, row.priceMinor, row.currency, row.data, row.notes, sku)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, sku)
+}
+
+func (r *SQLRepository) Delete(sku string) error {
+	res, err := r.db.Exec(`DELETE FROM items WHERE sku = ?`, sku)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, sku)
+}
+
+// requireRow turns "0 rows affected" into a not-found error
+func requireRow(res sql.Result, sku string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	return nil
+}
+
+// MemoryRepository keeps items in a map. Each one is independent of
+// every other, which makes it handy wherever a throwaway store is
+// needed. It is safe for concurrent use.
+type MemoryRepository struct {
+	mu    sync.Mutex
+	items map[string]PricedItem
+	// outbox holds unpublished events, see outbox.go
+	outbox       []OutboxMessage
+	nextOutboxID int64
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{items: make(map[string]PricedItem)}
+}
+
+func (r *MemoryRepository) Create(sku string, item PricedItem) error {
+	if sku == "" {
+		return fmt.Errorf("SKU cannot be empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.items[sku]; exists {
+		return fmt.Errorf("SKU %q is already in the catalog", sku)
+	}
+	r.items[sku] = item
+	return nil
+}
+
+func (r *MemoryRepository) FindByID(sku string) (PricedItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.items[sku]
+	if !ok {
+		return nil, &ItemNotFoundError{SKU: sku}
+	}
+	return item, nil
+}
+
+func (r *MemoryRepository) FindAll() ([]CatalogEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]CatalogEntry, 0, len(r.items))
+	for _, sku := range slices.Sorted(maps.Keys(r.items)) {
+		entries = append(entries, CatalogEntry{SKU: sku, Item: r.items[sku]})
+	}
+	return entries, nil
+}
+
+func (r *MemoryRepository) Update(sku string, item PricedItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[sku]; !ok {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	r.items[sku] = item
+	return nil
+}
+
+func (r *MemoryRepository) Delete(sku string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[sku]; !ok {
+		return &ItemNotFoundError{SKU: sku}
+	}
+	delete(r.items, sku)
+	return nil
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+// ------------------- FAKE SQL DRIVER -------------------------
+// No real driver is available to the tests (the module has no
+// dependencies), so fakeDB stands in for SQLite. It knows exactly the
+// statements this package sends, by their text, and keeps the tables
+// in maps; any other statement fails, so a changed query shows up as an
+// error rather than passing silently. Like SQLite, one transaction at a
+// time may write: Begin waits for the previous one to end.
+
+// fakeItem is one row of the items table
+type fakeItem struct {
+	typ, title, currency, data string
+	priceMinor                 int64
+	notes                      driver.Value // nil or a string
+}
+
+type fakeOutboxRow struct {
+	id              int64
+	sku, name, data string
+	published       bool
+}
+
+// fakeTables is the whole database
+type fakeTables struct {
+	version    *int64 // the schema_version row
+	migrations []string
+	items      map[string]fakeItem
+	outbox     []fakeOutboxRow
+	sequences  map[string]int64
+	// orders is written by tests standing in for an orders table
+	orders []string
+}
+
+func (t fakeTables) clone() fakeTables {
+	c := t
+	if t.version != nil {
+		v := *t.version
+		c.version = &v
+	}
+	c.migrations = slices.Clone(t.migrations)
+	c.items = maps.Clone(t.items)
+	c.outbox = slices.Clone(t.outbox)
+	c.sequences = maps.Clone(t.sequences)
+	c.orders = slices.Clone(t.orders)
+	return c
+}
+
+type fakeDB struct {
+	// lock is held by the open transaction, or by one statement
+	lock   chan struct{}
+	tables fakeTables
+	// failCommit, if set, makes the next Commit fail and roll back
+	failCommit error
+}
+
+// openFakeDB returns a *sql.DB on an empty fake database
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	f := &fakeDB{
+		lock:   make(chan struct{}, 1),
+		tables: fakeTables{items: make(map[string]fakeItem), sequences: make(map[string]int64)},
+	}
+	db := sql.OpenDB(fakeConnector{f})
+	t.Cleanup(func() { db.Close() })
+	return db, f
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.lock <- struct{}{}
+	c.tx = &fakeTx{conn: c, saved: c.db.tables.clone()}
+	return c.tx, nil
+}
+
+type fakeTx struct {
+	conn  *fakeConn
+	saved fakeTables
+}
+
+func (tx *fakeTx) Commit() error {
+	db := tx.conn.db
+	if err := db.failCommit; err != nil {
+		db.failCommit = nil
+		tx.Rollback()
+		return err
+	}
+	tx.end()
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.db.tables = tx.saved
+	tx.end()
+	return nil
+}
+
+func (tx *fakeTx) end() {
+	tx.conn.tx = nil
+	<-tx.conn.db.lock
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+// run executes the statement, inside the connection's transaction or
+// on its own
+func (s *fakeStmt) run(args []driver.Value) (fakeResult, error) {
+	if s.conn.tx == nil {
+		s.conn.db.lock <- struct{}{}
+		defer func() { <-s.conn.db.lock }()
+	}
+	return fakeExec(&s.conn.db.tables, s.query, args)
+}
+
+type fakeResult struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+}
+
+// fakeExec runs one statement of those this package uses
+func fakeExec(t *fakeTables, query string, args []driver.Value) (fakeResult, error) {
+	arg := func(i int) string { s, _ := args[i].(string); return s }
+	switch {
+	case query == "CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)":
+		return fakeResult{}, nil
+	case query == "SELECT version FROM schema_version":
+		if t.version == nil {
+			return fakeResult{columns: []string{"version"}}, nil
+		}
+		return fakeResult{columns: []string{"version"}, rows: [][]driver.Value{{*t.version}}}, nil
+	case query == "INSERT INTO schema_version (version) VALUES (0)":
+		v := int64(0)
+		t.version = &v
+		return fakeResult{affected: 1}, nil
+	case query == "UPDATE schema_version SET version = ?":
+		v := args[0].(int64)
+		t.version = &v
+		return fakeResult{affected: 1}, nil
+	case strings.HasPrefix(query, "CREATE ") || strings.HasPrefix(query, "ALTER "):
+		t.migrations = append(t.migrations, query)
+		return fakeResult{}, nil
+
+	case query == "SELECT 1 FROM items WHERE sku = ?":
+		if _, ok := t.items[arg(0)]; !ok {
+			return fakeResult{columns: []string{"1"}}, nil
+		}
+		return fakeResult{columns: []string{"1"}, rows: [][]driver.Value{{int64(1)}}}, nil
+	case query == "INSERT INTO items (sku, type, title, price_minor, currency, data, notes) VALUES (?, ?, ?, ?, ?, ?, ?)":
+		if _, ok := t.items[arg(0)]; ok {
+			return fakeResult{}, errors.New("UNIQUE constraint failed: items.sku")
+		}
+		t.items[arg(0)] = fakeItem{arg(1), arg(2), arg(4), arg(5), args[3].(int64), args[6]}
+		return fakeResult{affected: 1}, nil
+	case query == "SELECT type, data, notes FROM items WHERE sku = ?",
+		query == "SELECT type, data FROM items WHERE sku = ?":
+		item, ok := t.items[arg(0)]
+		res := fakeResult{columns: []string{"type", "data", "notes"}}
+		if ok {
+			res.rows = [][]driver.Value{{item.typ, item.data, item.notes}}
+		}
+		if strings.Contains(query, "data FROM") {
+			res.columns = res.columns[:2]
+			for i := range res.rows {
+				res.rows[i] = res.rows[i][:2]
+			}
+		}
+		return res, nil
+	case query == "SELECT sku, type, data, notes FROM items ORDER BY sku":
+		res := fakeResult{columns: []string{"sku", "type", "data", "notes"}}
+		for _, sku := range slices.Sorted(maps.Keys(t.items)) {
+			item := t.items[sku]
+			res.rows = append(res.rows, []driver.Value{sku, item.typ, item.data, item.notes})
+		}
+		return res, nil
+	case query == "UPDATE items SET type = ?, title = ?, price_minor = ?, currency = ?, data = ?, notes = ? WHERE sku = ?",
+		query == "UPDATE items SET type = ?, title = ?, price_minor = ?, currency = ?, data = ? WHERE sku = ?":
+		sku := arg(len(args) - 1)
+		item, ok := t.items[sku]
+		if !ok {
+			return fakeResult{}, nil
+		}
+		item.typ, item.title, item.priceMinor, item.currency, item.data = arg(0), arg(1), args[2].(int64), arg(3), arg(4)
+		if len(args) == 7 {
+			item.notes = args[5]
+		}
+		t.items[sku] = item
+		return fakeResult{affected: 1}, nil
+	case query == "DELETE FROM items WHERE sku = ?":
+		if _, ok := t.items[arg(0)]; !ok {
+			return fakeResult{}, nil
+		}
+		delete(t.items, arg(0))
+		return fakeResult{affected: 1}, nil
+
+	case query == "INSERT INTO outbox (sku, name, data) VALUES (?, ?, ?)":
+		t.outbox = append(t.outbox, fakeOutboxRow{id: int64(len(t.outbox) + 1), sku: arg(0), name: arg(1), data: arg(2)})
+		return fakeResult{affected: 1}, nil
+	case query == "SELECT id, sku, name, data FROM outbox WHERE published = 0 ORDER BY id LIMIT ?":
+		res := fakeResult{columns: []string{"id", "sku", "name", "data"}}
+		for _, row := range t.outbox {
+			if !row.published && int64(len(res.rows)) < args[0].(int64) {
+				res.rows = append(res.rows, []driver.Value{row.id, row.sku, row.name, row.data})
+			}
+		}
+		return res, nil
+	case query == "UPDATE outbox SET published = 1 WHERE id = ?":
+		for i := range t.outbox {
+			if t.outbox[i].id == args[0].(int64) {
+				t.outbox[i].published = true
+				return fakeResult{affected: 1}, nil
+			}
+		}
+		return fakeResult{}, nil
+
+	case query == "INSERT INTO order_sequences (store, last) VALUES (?, 1) ON CONFLICT (store) DO UPDATE SET last = last + 1 RETURNING last":
+		t.sequences[arg(0)]++
+		return fakeResult{columns: []string{"last"}, rows: [][]driver.Value{{t.sequences[arg(0)]}}}, nil
+	case query == "INSERT INTO orders (number) VALUES (?)":
+		t.orders = append(t.orders, arg(0))
+		return fakeResult{affected: 1}, nil
+	}
+	return fakeResult{}, fmt.Errorf("fake driver: unsupported statement %q", query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// ------------------- SQL REPOSITORY TESTS --------------------
+
+func assertNotFound(t *testing.T, err error) {
+	t.Helper()
+	var notFound *ItemNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("got error %v, want ItemNotFoundError", err)
+	}
+}
+
+func TestSQLRepositoryMigrates(t *testing.T) {
+	db, fake := openFakeDB(t)
+	_, err := NewSQLRepository(db)
+	assert.NoError(t, err)
+	assert.Equal(t, *fake.tables.version, int64(len(migrations)))
+	assert.Equal(t, len(fake.tables.migrations), len(migrations))
+
+	// A second start finds the schema current and changes nothing
+	_, err = NewSQLRepository(db)
+	assert.NoError(t, err)
+	assert.Equal(t, len(fake.tables.migrations), len(migrations))
+}
+
+func TestSQLRepositoryCRUD(t *testing.T) {
+	db, fake := openFakeDB(t)
+	repo := Must(NewSQLRepository(db))
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	magazine := Must(NewMagazine("Vogue", Must(NewMoney(1250, "EUR")), 123))
+
+	assert.NoError(t, repo.Create("BK-001", book))
+	assert.NoError(t, repo.Create("MG-001", magazine))
+	if err := repo.Create("BK-001", book); err == nil {
+		t.Error("created BK-001 twice")
+	}
+	if err := repo.Create("", book); err == nil {
+		t.Error("created an item without a SKU")
+	}
+
+	// The price columns hold exact minor units and the currency
+	row := fake.tables.items["MG-001"]
+	assert.Equal(t, row.priceMinor, int64(1250))
+	assert.Equal(t, row.currency, "EUR")
+	assert.Equal(t, row.title, "Vogue")
+
+	found, err := repo.FindByID("BK-001")
+	if assert.NoError(t, err) {
+		assert.Equal(t, found.Price(), Dollars(9.99))
+		assert.Equal(t, itemTitle(found), "Dune")
+	}
+	_, err = repo.FindByID("BK-404")
+	assertNotFound(t, err)
+
+	assert.NoError(t, book.SetPrice(Dollars(8.49)))
+	assert.NoError(t, repo.Update("BK-001", book))
+	assert.Equal(t, fake.tables.items["BK-001"].priceMinor, int64(849))
+	assertNotFound(t, repo.Update("BK-404", book))
+
+	all, err := repo.FindAll()
+	if assert.NoError(t, err) {
+		assert.Equal(t, Map(all, func(e CatalogEntry) string { return e.SKU }), []string{"BK-001", "MG-001"})
+		assert.Equal(t, all[0].Item.Price(), Dollars(8.49))
+		assert.Equal(t, all[1].Item.Price(), Must(NewMoney(1250, "EUR")))
+	}
+
+	assert.NoError(t, repo.Delete("MG-001"))
+	assertNotFound(t, repo.Delete("MG-001"))
+	assert.Equal(t, len(Must(repo.FindAll())), 1)
+}
+
+func TestSQLRepositoryKeepsNotes(t *testing.T) {
+	db, fake := openFakeDB(t)
+	repo := Must(NewSQLRepository(db))
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	id := Must(book.AddNote("sam", "damaged batch received"))
+	assert.NoError(t, book.EditNote(id, "alex", "3 copies returned"))
+	assert.NoError(t, repo.Create("BK-001", book))
+	if strings.Contains(fake.tables.items["BK-001"].data, "copies returned") {
+		t.Error("notes stored in the item's JSON")
+	}
+
+	found := Must(repo.FindByID("BK-001"))
+	notes := found.(Annotated).Notes()
+	assert.Equal(t, len(notes), 1)
+	assert.Equal(t, notes[0].Text, "3 copies returned")
+	assert.Equal(t, len(notes[0].History), 1)
+
+	// A price change through the outbox leaves the notes alone
+	assert.NoError(t, repo.SetPrice("BK-001", Dollars(8.99), "spring sale"))
+	assert.Equal(t, len(Must(repo.FindByID("BK-001")).(Annotated).Notes()), 1)
+}
+
+func TestSQLRepositoryOutbox(t *testing.T) {
+	db, _ := openFakeDB(t)
+	repo := Must(NewSQLRepository(db))
+	assert.NoError(t, repo.Create("BK-001", Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))))
+	assert.NoError(t, repo.SetPrice("BK-001", Dollars(8.99), "spring sale"))
+	assertNotFound(t, repo.SetPrice("BK-404", Dollars(1), ""))
+	assert.Equal(t, Must(repo.FindByID("BK-001")).Price(), Dollars(8.99))
+
+	pending, err := repo.Pending(10)
+	if !assert.NoError(t, err) || !assert.Equal(t, len(pending), 1) {
+		return
+	}
+	assert.Equal(t, pending[0].SKU, "BK-001")
+	assert.NoError(t, repo.MarkPublished(pending[0].ID))
+	assert.Equal(t, len(Must(repo.Pending(10))), 0)
+}
@@ -0,0 +1,187 @@
+package main
+
+// ------------------- AUTOMATIC REPRICING ---------------------
+// The repricer sets an item's price just below the cheapest competitor.
+// Items take part only if they were opted in, each with a floor price
+// the repricer will never go below. Competitor quotes go through
+// AggregatePrices first, so one broken feed can't trigger a price war.
+//
+// A dry run computes the same report without changing any price.
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Repricer adjusts opted-in items against competitor prices
+type Repricer struct {
+	// Undercut is how much cheaper than the lowest competitor to be
+	Undercut Money
+	// OutlierK is passed to AggregatePrices (DefaultOutlierK if zero)
+	OutlierK float64
+
+	// A slice rather than a map keeps reports in opt-in order
+	optedIn []repricedItem
+}
+
+type repricedItem struct {
+	item  PricedItem
+	floor Money
+}
+
+// NewRepricer undercuts competitors by the given amount
+func NewRepricer(undercut Money) *Repricer {
+	return &Repricer{Undercut: undercut}
+}
+
+// OptIn lets the repricer manage item, never pricing it below floor.
+// Opting in again just updates the floor.
+func (r *Repricer) OptIn(item PricedItem, floor Money) {
+	if i := r.index(item); i >= 0 {
+		r.optedIn[i].floor = floor
+		return
+	}
+	r.optedIn = append(r.optedIn, repricedItem{item, floor})
+}
+
+// OptOut hands the item's price back to manual control
+func (r *Repricer) OptOut(item PricedItem) {
+	r.optedIn = slices.DeleteFunc(r.optedIn, func(ri repricedItem) bool { return ri.item == item })
+}
+
+func (r *Repricer) index(item PricedItem) int {
+	return slices.IndexFunc(r.optedIn, func(ri repricedItem) bool { return ri.item == item })
+}
+
+// RepricingChange is one line of a repricing report
+type RepricingChange struct {
+	Item     PricedItem
+	OldPrice Money
+	NewPrice Money
+	// Lowest is the cheapest competitor quote that was trusted
+	Lowest PriceQuote
+	// Note says how NewPrice was chosen
+	Note string
+	Err  error
+}
+
+// RepricingReport lists what a run did (or would do, for a dry run)
+type RepricingReport struct {
+	DryRun  bool
+	Changes []RepricingChange
+}
+
+// Run reprices every opted-in item that has competitor quotes.
+// Items that were not opted in are ignored even if quotes are given.
+func (r *Repricer) Run(quotes map[PricedItem][]PriceQuote, dryRun bool) RepricingReport {
+	report := RepricingReport{DryRun: dryRun}
+	k := r.OutlierK
+	if k == 0 {
+		k = DefaultOutlierK
+	}
+	for _, ri := range r.optedIn {
+		item, floor := ri.item, ri.floor
+		itemQuotes, ok := quotes[item]
+		if !ok {
+			continue
+		}
+		change := RepricingChange{Item: item, OldPrice: item.Price(), NewPrice: item.Price()}
+		agg, err := AggregatePrices(itemQuotes, k)
+		if err != nil {
+			change.Err = err
+			report.Changes = append(report.Changes, change)
+			continue
+		}
+
+		change.Lowest = agg.Accepted[0]
+		for _, q := range agg.Accepted[1:] {
+			if q.Price < change.Lowest.Price {
+				change.Lowest = q
+			}
+		}
+		// Quotes are plain numbers from outside; read them in the item's currency
+		target, err := amountIn(change.Lowest.Price, change.OldPrice.currency).Sub(r.Undercut)
+		if err != nil {
+			change.Err = err
+			report.Changes = append(report.Changes, change)
+			continue
+		}
+		change.Note = "undercut"
+		if target.Less(floor) {
+			target = floor
+			change.Note = "held at floor"
+		}
+		if target == change.OldPrice {
+			change.Note = "unchanged"
+			report.Changes = append(report.Changes, change)
+			continue
+		}
+		change.NewPrice = target
+		if !dryRun {
+			change.Err = setPriceBecause(item, target, "repricer")
+		}
+		report.Changes = append(report.Changes, change)
+	}
+	return report
+}
+
+// Schedule runs the repricer every interval until stop is called.
+// fetch supplies fresh competitor quotes for each run. In deterministic
+// mode nothing is scheduled.
+func (r *Repricer) Schedule(interval time.Duration, dryRun bool,
+	fetch func() map[PricedItem][]PriceQuote, done func(RepricingReport)) (stop func()) {
+	return every(interval, func() { done(r.Run(fetch(), dryRun)) })
+}
+
+// every calls run every interval on its own goroutine until stop is
+// called; calling stop again does nothing. It is the scheduler behind
+// the pricing jobs; in deterministic mode it schedules nothing.
+func every(interval time.Duration, run func()) (stop func()) {
+	if !backgroundJobs {
+		return func() {}
+	}
+	// time.Ticker sends the current time on its channel C every interval
+	ticker := time.NewTicker(interval)
+	quit := make(chan struct{})
+	go func() {
+		for {
+			// select waits on several channels at once
+			select {
+			case <-ticker.C:
+				run()
+			case <-quit:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	// Closing a closed channel panics; Once makes stop safe to repeat
+	var once sync.Once
+	return func() { once.Do(func() { close(quit) }) }
+}
+
+// Print writes the report as an aligned table.
+// label names an item in the table, e.g. by its localized title.
+func (rep RepricingReport) Print(w io.Writer, label func(PricedItem) string) error {
+	if rep.DryRun {
+		fmt.Fprintln(w, "DRY RUN - no prices were changed")
+	}
+	// tabwriter aligns tab-separated columns, like Python's tabulate
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ITEM\tOLD\tNEW\tLOWEST COMPETITOR\tNOTE")
+	for _, c := range rep.Changes {
+		note := c.Note
+		if c.Err != nil {
+			note = "error: " + c.Err.Error()
+		}
+		// Quotes are plain numbers in the item's own currency
+		lowest := amountIn(c.Lowest.Price, c.OldPrice.currency)
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%s %v\t%s\n",
+			label(c.Item), c.OldPrice, c.NewPrice, c.Lowest.Source, lowest, note)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+func TestEveryStopTwice(t *testing.T) {
+	// stop must also end the ticker goroutine
+	leaktest.Check(t)
+	ticks := make(chan struct{}, 10)
+	stop := every(time.Millisecond, func() {
+		select {
+		case ticks <- struct{}{}:
+		default:
+		}
+	})
+	<-ticks
+	<-ticks
+	stop()
+	// A second stop, e.g. from a deferred cleanup, must not panic
+	stop()
+}
+
+func TestRepricingReportPrintsQuoteCurrency(t *testing.T) {
+	euros := func(minor int64) Money { return Must(NewMoney(minor, "EUR")) }
+	book := Must(NewBook("Faust", "Goethe", euros(1500), ""))
+	repricer := NewRepricer(euros(10))
+	repricer.OptIn(book, euros(1000))
+
+	quotes := map[PricedItem][]PriceQuote{book: {{"shop-a", 13.5}, {"shop-b", 14}, {"shop-c", 14.25}}}
+	report := repricer.Run(quotes, false)
+	assert.Equal(t, book.Price(), euros(1340))
+
+	var out strings.Builder
+	assert.NoError(t, report.Print(&out, itemTitle))
+	if !strings.Contains(out.String(), "shop-a €13.50") {
+		t.Errorf("lowest quote not printed in euros:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "$") {
+		t.Errorf("dollar sign in a euro report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+// ------------------- RESERVATION HOLDS -----------------------
+// A customer who starts checking out gets their copies held for a
+// while. Paying in time turns the hold into a sale; otherwise it
+// expires and the copies go back on the shelf, with nobody having to
+// remember to release them.
+//
+//	Place --> held --Confirm--> sold
+//	            |----Cancel---> back in stock
+//	            '---expiry----> back in stock
+//
+// The deadlines live in a TTLStore (see ttlstore.go), whose timer
+// expires holds from its own goroutine. An Inventory is not locked
+// itself, so Holds locks every change it makes; while holds are in
+// use, reserve and release the item's stock only through them.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoHold means the hold was never placed, already settled or expired
+var ErrNoHold = errors.New("no such hold")
+
+// Hold is qty units of Item set aside for one customer
+type Hold struct {
+	Item PricedItem
+	Qty  int
+}
+
+// Holds reserves stock in an Inventory for a limited time. Call Close
+// when done to stop the expiry timer.
+type Holds struct {
+	mu        sync.Mutex
+	inventory *Inventory
+	// held are the open holds; deadlines keeps when each one expires
+	held      map[string]Hold
+	deadlines *TTLStore[string, struct{}]
+	onExpire  func(id string, h Hold, err error)
+}
+
+// NewHolds holds stock in inv. onExpire, which may be nil, is told
+// about every hold that expired once its units are back in stock; err
+// is set if releasing them failed.
+func NewHolds(inv *Inventory, onExpire func(id string, h Hold, err error)) *Holds {
+	h := &Holds{inventory: inv, held: make(map[string]Hold), onExpire: onExpire}
+	h.deadlines = NewTTLStore(func(id string, _ struct{}) { h.expire(id) })
+	return h
+}
+
+// Place reserves qty units of item under id for ttl
+func (h *Holds) Place(id string, item PricedItem, qty int, ttl time.Duration) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.held[id]; ok {
+		return fmt.Errorf("hold %q is already placed", id)
+	}
+	if err := h.inventory.Reserve(item, qty); err != nil {
+		return err
+	}
+	h.held[id] = Hold{Item: item, Qty: qty}
+	h.deadlines.Set(id, struct{}{}, ttl)
+	return nil
+}
+
+// Confirm sells the units held under id
+func (h *Holds) Confirm(id string) error {
+	return h.settle(id, h.inventory.Commit)
+}
+
+// Cancel puts the units held under id back in stock now
+func (h *Holds) Cancel(id string) error {
+	return h.settle(id, h.inventory.Release)
+}
+
+// settle ends the hold under id by passing its units to move. A hold
+// past its deadline is left to expire, even if the sweep hasn't got to
+// it yet.
+func (h *Holds) settle(id string, move func(PricedItem, int) error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hold, ok := h.held[id]
+	if _, live := h.deadlines.TTL(id); !ok || !live {
+		return fmt.Errorf("hold %q: %w", id, ErrNoHold)
+	}
+	delete(h.held, id)
+	h.deadlines.Delete(id)
+	return move(hold.Item, hold.Qty)
+}
+
+// expire releases the hold under id once its deadline has passed. The
+// store calls it without holding its own lock, so taking h.mu here
+// can't deadlock with Place or settle, which call into the store.
+func (h *Holds) expire(id string) {
+	h.mu.Lock()
+	hold, ok := h.held[id]
+	var err error
+	if ok {
+		delete(h.held, id)
+		err = h.inventory.Release(hold.Item, hold.Qty)
+	}
+	h.mu.Unlock()
+	if ok && h.onExpire != nil {
+		h.onExpire(id, hold, err)
+	}
+}
+
+// TTL returns how long the hold under id has left
+func (h *Holds) TTL(id string) (time.Duration, bool) {
+	return h.deadlines.TTL(id)
+}
+
+// Available is the item's available stock, read under the holds' lock
+func (h *Holds) Available(item PricedItem) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.inventory.AvailableQuantity(item)
+}
+
+// Sweep expires every hold past its deadline now, without waiting for
+// the timer
+func (h *Holds) Sweep() {
+	h.deadlines.Sweep()
+}
+
+// Close stops the expiry timer. Holds past their deadline can no longer
+// be settled, but are only released by Sweep.
+func (h *Holds) Close() {
+	h.deadlines.Close()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+// newTestHolds holds stock of 5 copies of book on a clock the test moves
+func newTestHolds(t *testing.T, onExpire func(string, Hold, error)) (*Holds, PricedItem, *time.Time) {
+	t.Helper()
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	inv := NewInventory()
+	assert.NoError(t, inv.Restock(book, 5))
+	holds := NewHolds(inv, onExpire)
+	t.Cleanup(holds.Close)
+	clock := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	holds.deadlines.now = func() time.Time { return clock }
+	return holds, book, &clock
+}
+
+func TestHoldsLifecycle(t *testing.T) {
+	var expired []string
+	holds, book, clock := newTestHolds(t, func(id string, h Hold, err error) {
+		assert.NoError(t, err)
+		expired = append(expired, fmt.Sprintf("%s:%d", id, h.Qty))
+	})
+	assert.NoError(t, holds.Place("paid", book, 2, 15*time.Minute))
+	assert.NoError(t, holds.Place("cancelled", book, 1, 15*time.Minute))
+	assert.NoError(t, holds.Place("abandoned", book, 1, 15*time.Minute))
+	if err := holds.Place("paid", book, 1, time.Minute); err == nil {
+		t.Error("placed the same hold twice")
+	}
+	if err := holds.Place("too-many", book, 2, time.Minute); err == nil {
+		t.Error("held more than is available")
+	}
+	assert.Equal(t, holds.Available(book), 1)
+
+	*clock = clock.Add(10 * time.Minute)
+	assert.NoError(t, holds.Confirm("paid"))
+	assert.NoError(t, holds.Cancel("cancelled"))
+	assert.ErrorIs(t, holds.Confirm("paid"), ErrNoHold)
+	assert.Equal(t, holds.Available(book), 2)
+
+	// Past the deadline the hold can't be paid for, swept or not
+	*clock = clock.Add(10 * time.Minute)
+	assert.ErrorIs(t, holds.Confirm("abandoned"), ErrNoHold)
+	holds.Sweep()
+	assert.Equal(t, expired, []string{"abandoned:1"})
+	assert.Equal(t, holds.Available(book), 3)
+	assert.Equal(t, holds.inventory.ReservedQuantity(book), 0)
+
+	// The id is free again once the hold has expired
+	assert.NoError(t, holds.Place("abandoned", book, 1, time.Minute))
+}
+
+func TestHoldsExpireOnTheirOwn(t *testing.T) {
+	leaktest.Check(t)
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	inv := NewInventory()
+	assert.NoError(t, inv.Restock(book, 1))
+	done := make(chan Hold)
+	holds := NewHolds(inv, func(_ string, h Hold, err error) {
+		assert.NoError(t, err)
+		done <- h
+	})
+	defer holds.Close()
+	assert.NoError(t, holds.Place("cart", book, 1, 10*time.Millisecond))
+	select {
+	case h := <-done:
+		assert.Equal(t, h.Qty, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hold never expired")
+	}
+	assert.Equal(t, holds.Available(book), 1)
+}
+
+func TestHoldsConfirmRacesExpiry(t *testing.T) {
+	// Each hold is either sold or released, never both and never lost
+	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	inv := NewInventory()
+	assert.NoError(t, inv.Restock(book, 200))
+	holds := NewHolds(inv, nil)
+	defer holds.Close()
+	for i := range 200 {
+		assert.NoError(t, holds.Place(fmt.Sprint(i), book, 1, time.Millisecond))
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sold := 0
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := holds.Confirm(fmt.Sprint(i))
+			if err != nil && !errors.Is(err, ErrNoHold) {
+				t.Error(err)
+			}
+			if err == nil {
+				mu.Lock()
+				sold++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// The timer may still be releasing the last holds
+	for deadline := time.Now().Add(5 * time.Second); holds.Available(book)+sold < 200 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		holds.Sweep()
+	}
+	assert.Equal(t, holds.Available(book)+sold, 200)
+}
@@ -0,0 +1,172 @@
+package main
+
+// ------------------- SCHEMA OVERVIEW -------------------------
+// "bookstore schema" prints the main types of the store, their fields
+// and how they point at each other: a map of the codebase for readers.
+//
+// It is built with reflection (package reflect), Go's version of
+// Python's inspect/typing.get_type_hints: a reflect.Type describes a
+// type at run time, including unexported fields. The output therefore
+// can't go stale; only the list of entities below is written by hand.
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// schemaEntities are the structs shown by the schema command
+var schemaEntities = []reflect.Type{
+	reflect.TypeFor[Catalog](),
+	reflect.TypeFor[Book](),
+	reflect.TypeFor[Magazine](),
+	reflect.TypeFor[EBook](),
+	reflect.TypeFor[AudioBook](),
+	reflect.TypeFor[Bundle](),
+	reflect.TypeFor[Money](),
+	reflect.TypeFor[Inventory](),
+	reflect.TypeFor[Supplier](),
+	reflect.TypeFor[PurchaseOrder](),
+	reflect.TypeFor[POLine](),
+	reflect.TypeFor[Cart](),
+	reflect.TypeFor[CartLine](),
+	reflect.TypeFor[Order](),
+	reflect.TypeFor[OrderLine](),
+}
+
+// schemaInterfaces are the interfaces entities are checked against.
+// reflect.TypeFor of an interface type gives the interface itself.
+var schemaInterfaces = []reflect.Type{
+	reflect.TypeFor[PricedItem](),
+	reflect.TypeFor[Categorized](),
+	reflect.TypeFor[Translatable](),
+	reflect.TypeFor[Annotated](),
+	reflect.TypeFor[PriceHistorian](),
+}
+
+// SchemaField is one field of an entity
+type SchemaField struct {
+	Name     string
+	Type     string
+	Embedded bool
+}
+
+// SchemaRelation is a field that refers to another entity or interface
+type SchemaRelation struct {
+	From, Field, To string
+	Many            bool // a slice or map rather than a single value
+}
+
+// SchemaEntity describes one struct
+type SchemaEntity struct {
+	Name       string
+	Implements []string
+	Fields     []SchemaField
+}
+
+// Schema is the whole overview
+type Schema struct {
+	Entities  []SchemaEntity
+	Relations []SchemaRelation
+	// Implementers lists, per interface, the entities that satisfy it
+	Implementers map[string][]string
+}
+
+// BuildSchema inspects schemaEntities
+func BuildSchema() Schema {
+	known := make(map[reflect.Type]bool)
+	for _, t := range slices.Concat(schemaEntities, schemaInterfaces) {
+		known[t] = true
+	}
+	s := Schema{Implementers: make(map[string][]string)}
+	for _, t := range schemaEntities {
+		e := SchemaEntity{Name: t.Name()}
+		for _, iface := range schemaInterfaces {
+			// Methods use pointer receivers, so ask about *T
+			if reflect.PointerTo(t).Implements(iface) {
+				e.Implements = append(e.Implements, iface.Name())
+				s.Implementers[iface.Name()] = append(s.Implementers[iface.Name()], t.Name())
+			}
+		}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if f.Type.Kind() == reflect.Func {
+				continue // injectable clocks and hooks aren't data
+			}
+			// Type.String says "main.Money"; the package name is noise here
+			typ := strings.ReplaceAll(f.Type.String(), "main.", "")
+			e.Fields = append(e.Fields, SchemaField{Name: f.Name, Type: typ, Embedded: f.Anonymous})
+			if target, many, ok := relationTarget(f.Type, known); ok {
+				s.Relations = append(s.Relations, SchemaRelation{From: t.Name(), Field: f.Name, To: target.Name(), Many: many})
+			}
+		}
+		s.Entities = append(s.Entities, e)
+	}
+	return s
+}
+
+// relationTarget unwraps pointers, slices and maps until it finds a
+// known type. A map counts as a relation to its key or its value.
+func relationTarget(t reflect.Type, known map[reflect.Type]bool) (target reflect.Type, many bool, ok bool) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return relationTarget(t.Elem(), known)
+	case reflect.Slice, reflect.Array:
+		target, _, ok = relationTarget(t.Elem(), known)
+		return target, true, ok
+	case reflect.Map:
+		if target, _, ok = relationTarget(t.Elem(), known); !ok {
+			target, _, ok = relationTarget(t.Key(), known)
+		}
+		return target, true, ok
+	}
+	// Money is a value, not something an entity relates to
+	if known[t] && t != reflect.TypeFor[Money]() {
+		return t, false, true
+	}
+	return nil, false, false
+}
+
+// Print writes the overview as text
+func (s Schema) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, e := range s.Entities {
+		fmt.Fprint(tw, e.Name)
+		if len(e.Implements) > 0 {
+			fmt.Fprintf(tw, " (implements %s)", strings.Join(e.Implements, ", "))
+		}
+		fmt.Fprintln(tw)
+		for _, f := range e.Fields {
+			if f.Embedded {
+				fmt.Fprintf(tw, "  %s\t%s\t(embedded)\n", f.Name, f.Type)
+			} else {
+				fmt.Fprintf(tw, "  %s\t%s\n", f.Name, f.Type)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintln(tw, "RELATIONS")
+	for _, r := range s.Relations {
+		count := "one"
+		if r.Many {
+			count = "many"
+		}
+		to := r.To
+		if impls, ok := s.Implementers[r.To]; ok {
+			to += " (" + strings.Join(impls, ", ") + ")"
+		}
+		fmt.Fprintf(tw, "  %s.%s\t-> %s\t%s\n", r.From, r.Field, count, to)
+	}
+	return tw.Flush()
+}
+
+func cmdSchema(s *cliSession, args []string, out io.Writer) error {
+	fs := newFlagSet("schema", out)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return BuildSchema().Print(out)
+}
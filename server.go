@@ -0,0 +1,466 @@
+package main
+
+// ------------------- HTTP API --------------------------------
+// A small REST API over the catalog, using only net/http:
+//
+//	GET  /items                 list (?limit=N&cursor=TOKEN)
+//	GET  /items/{id}            one item
+//	POST /items                 add an item
+//	PUT  /items/{id}/price      change the price
+//	POST /items/{id}/discount   preview a discounted price
+//	GET  /items/{id}/preview    a book's excerpt, as text (see excerpt.go)
+//	POST /batch                 several of the above at once (see batch.go)
+//
+// Callers identify themselves with "Authorization: Bearer KEY", a key
+// registered with the server's Authorizer (see authz.go). Without one
+// they are an anonymous viewer; an unknown key is 401 Unauthorized.
+//
+// Item responses carry the title and description in the language that
+// best matches the request's Accept-Language header (see localization.go).
+//
+// Since Go 1.22 the standard ServeMux understands methods and {wildcards}
+// in patterns, much like Flask's @app.route("/items/<id>").
+//
+// Every request runs on its own goroutine. The Catalog locks itself, so
+// handlers change prices with Catalog.SetPrice and read items with
+// Catalog.Read. Only batches share a lock, see batch.go.
+//
+// Writes need a staff API key: adding items needs catalog:manage,
+// changing prices and previewing discounts prices:edit. A caller without
+// the permission gets 403 Forbidden.
+//
+// Each request's context (r.Context()) is cancelled when the client
+// disconnects, and ServeHTTP adds a deadline of Timeout. Handlers doing
+// a lot of work, such as a batch, check it and stop early.
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CatalogServer serves the HTTP API; it implements http.Handler
+type CatalogServer struct {
+	catalog *Catalog
+	// batchMu makes batches run one at a time and guards inventory,
+	// which only batches use
+	batchMu   sync.Mutex
+	inventory *Inventory
+	cursors   *CursorSigner
+	mux       *http.ServeMux
+	// Timeout bounds the work done for one request
+	Timeout time.Duration
+	// Authz knows the callers' API keys and checks what they may do
+	Authz *Authorizer
+	// Throttle limits how often each price may change; nil means no
+	// limit. Throttled changes get 429 Too Many Requests.
+	Throttle *PriceChangeLimiter
+}
+
+// DefaultRequestTimeout is a CatalogServer's Timeout
+const DefaultRequestTimeout = 10 * time.Second
+
+// DefaultPriceChangesPerHour is the limit of a CatalogServer's Throttle
+const DefaultPriceChangesPerHour = 10
+
+// NewCatalogServer serves c, signing list cursors with a random key
+func NewCatalogServer(c *Catalog) (*CatalogServer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	cursors, err := NewCursorSigner(key, 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	throttle, err := NewPriceChangeLimiter(DefaultPriceChangesPerHour, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	s := &CatalogServer{
+		catalog:   c,
+		inventory: NewInventory(),
+		cursors:   cursors,
+		mux:       http.NewServeMux(),
+		Timeout:   DefaultRequestTimeout,
+		Authz:     NewAuthorizer(),
+		Throttle:  throttle,
+	}
+	s.mux.HandleFunc("GET /items", s.listItems)
+	s.mux.HandleFunc("GET /items/{id}", s.getItem)
+	s.mux.HandleFunc("POST /items", s.createItem)
+	s.mux.HandleFunc("PUT /items/{id}/price", s.setPrice)
+	s.mux.HandleFunc("POST /items/{id}/discount", s.discount)
+	s.mux.HandleFunc("GET /items/{id}/preview", s.preview)
+	s.mux.HandleFunc("POST /batch", s.batch)
+	return s, nil
+}
+
+func (s *CatalogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.Timeout)
+	defer cancel()
+	// Responses depend on Accept-Language; caches must keep them apart
+	w.Header().Set("Vary", "Accept-Language")
+	caller, err := s.caller(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	ctx = context.WithValue(ctx, callerKey{}, caller)
+	s.mux.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// callerKey is the context key of the Actor making a request
+type callerKey struct{}
+
+// anonymous is a caller without an API key
+var anonymous = Actor{Name: "anonymous", Role: RoleViewer}
+
+// caller finds who sent r from its bearer token
+func (s *CatalogServer) caller(r *http.Request) (Actor, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return anonymous, nil
+	}
+	key, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return Actor{}, errors.New("Authorization must be a Bearer API key")
+	}
+	actor, ok := s.Authz.ActorForKey(key)
+	if !ok {
+		return Actor{}, errors.New("unknown API key")
+	}
+	return actor, nil
+}
+
+// authorize checks that the request's caller holds perm. A denial is
+// written as 403 Forbidden and authorize returns false.
+func (s *CatalogServer) authorize(w http.ResponseWriter, r *http.Request, perm Permission, action string) bool {
+	err := s.Authz.Authorize(requestCaller(r), perm, action)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// requestCaller is the Actor ServeHTTP found for r
+func requestCaller(r *http.Request) Actor {
+	if actor, ok := r.Context().Value(callerKey{}).(Actor); ok {
+		return actor
+	}
+	return anonymous
+}
+
+// itemResponse is how one catalog entry looks in JSON
+type itemResponse struct {
+	SKU      string          `json:"sku"`
+	Category string          `json:"category"`
+	Item     json.RawMessage `json:"item"`
+	// HasPreview says an excerpt can be fetched from /preview
+	HasPreview bool `json:"hasPreview,omitempty"`
+	// Title and Description are in Language, the best match for the
+	// request's Accept-Language
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Language    string `json:"language"`
+}
+
+type listResponse struct {
+	Items []itemResponse `json:"items"`
+	Next  string         `json:"next,omitempty"`
+}
+
+// createRequest says which type "item" should be decoded into
+type createRequest struct {
+	SKU  string          `json:"sku"`
+	Type string          `json:"type"` // "book" or "magazine"
+	Item json.RawMessage `json:"item"`
+}
+
+func (s *CatalogServer) listItems(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 100 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+	page, err := s.catalog.ListPage(s.cursors, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp := listResponse{Items: []itemResponse{}, Next: page.Next}
+	langs := requestLanguages(r)
+	for _, sku := range page.SKUs {
+		resp.Items = append(resp.Items, s.response(sku, langs))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *CatalogServer) getItem(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("id")
+	if _, err := s.catalog.Get(sku); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(sku, requestLanguages(r)))
+}
+
+func (s *CatalogServer) createItem(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, PermManageCatalog, "add items") {
+		return
+	}
+	var req createRequest
+	if err := decodeBody(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	item, err := decodeItem(req.Type, req.Item)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.catalog.Add(req.SKU, item); err != nil {
+		writeError(w, addErrorStatus(s.catalog, req.SKU), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.response(req.SKU, requestLanguages(r)))
+}
+
+func (s *CatalogServer) setPrice(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("id")
+	if !s.authorize(w, r, PermEditPrices, "change the price of "+sku) {
+		return
+	}
+	var req struct {
+		// json.Number keeps the digits as sent, so no float64 rounding
+		Price    json.Number `json:"price"`
+		Currency string      `json:"currency"`
+		Reason   string      `json:"reason"` // optional, kept in the price history
+	}
+	if err := decodeBody(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	price, err := parsePrice(req.Price, req.Currency)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	item, err := s.catalog.Get(sku)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := s.changePrice(sku, item, price, req.Reason); err != nil {
+		writePriceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(sku, requestLanguages(r)))
+}
+
+// changePrice sets the price of item, found under sku, through the
+// catalog and subject to the Throttle. The current price is read with
+// Catalog.Price, under the catalog's lock like every other access.
+func (s *CatalogServer) changePrice(sku string, item PricedItem, price Money, reason string) error {
+	set := func() error { return s.catalog.SetPrice(sku, price, reason) }
+	if s.Throttle == nil {
+		return set()
+	}
+	current, err := s.catalog.Price(sku)
+	if err != nil {
+		return err
+	}
+	return s.Throttle.Change(item, current, price, set)
+}
+
+// addErrorStatus is the HTTP status for a failed Catalog.Add of sku
+func addErrorStatus(c *Catalog, sku string) int {
+	if _, err := c.Get(sku); err == nil {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// priceErrorStatus is the HTTP status for an error from changePrice
+func priceErrorStatus(err error) int {
+	if errors.Is(err, ErrPriceChangeThrottled) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// writePriceError answers a failed price change; a throttled one says
+// in Retry-After how many seconds to wait
+func writePriceError(w http.ResponseWriter, err error) {
+	var throttled *PriceChangeThrottledError
+	if errors.As(err, &throttled) {
+		seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	writeError(w, priceErrorStatus(err), err.Error())
+}
+
+func (s *CatalogServer) discount(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("id")
+	if !s.authorize(w, r, PermEditPrices, "preview a discount on "+sku) {
+		return
+	}
+	var req struct {
+		Percent float64 `json:"percent"`
+	}
+	if err := decodeBody(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	p, err := NewPercent(req.Percent)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	var price, discounted Money
+	var calcErr error
+	err = s.catalog.Read(sku, func(item PricedItem) {
+		price = item.Price()
+		discounted, calcErr = item.CalculateDiscount(p)
+	})
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err = calcErr; err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"price":      json.Number(price.Decimal()),
+		"discounted": json.Number(discounted.Decimal()),
+		"currency":   discounted.Currency(),
+	})
+}
+
+// preview serves a book's excerpt. http.ServeContent answers Range
+// requests ("Range: bytes=0-999") with 206 Partial Content, so a reader
+// app can fetch it piece by piece.
+func (s *CatalogServer) preview(w http.ResponseWriter, r *http.Request) {
+	item, err := s.catalog.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	b, ok := item.(*Book)
+	if !ok || !b.HasExcerpt() {
+		writeError(w, http.StatusNotFound, itemTitle(item)+" has no preview")
+		return
+	}
+	text, err := b.Excerpt()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(text))
+}
+
+// parsePrice reads a price sent as a JSON number, USD unless currency
+// says otherwise
+func parsePrice(price json.Number, currency string) (Money, error) {
+	if price == "" {
+		return Money{}, fmt.Errorf("price is required")
+	}
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return ParseMoney(price.String(), currency)
+}
+
+// requestLanguages are the languages the client prefers, best first
+func requestLanguages(r *http.Request) []string {
+	return ParseLanguagePreferences(r.Header.Get("Accept-Language"))
+}
+
+// response describes the item at sku, its text localized for langs
+// The item is encoded while the catalog is read-locked, so a concurrent
+// price change can't be seen half done.
+func (s *CatalogServer) response(sku string, langs []string) itemResponse {
+	resp := itemResponse{SKU: sku}
+	s.catalog.Read(sku, func(item PricedItem) {
+		text, lang := localizeFor(item, langs)
+		resp.Category, resp.Title, resp.Description, resp.Language = categoryOf(item), text.Title, text.Description, lang
+		// Excerpts are served on their own, keeping lists small
+		if b, ok := item.(*Book); ok && b.HasExcerpt() {
+			item, resp.HasPreview = b.withoutExcerpt(), true
+		}
+		resp.Item, _ = json.Marshal(item)
+	})
+	return resp
+}
+
+// decodeBody reads one JSON object, refusing unknown fields so typos
+// like "prise" are reported instead of ignored
+func decodeBody(body io.Reader, v any) error {
+	dec := json.NewDecoder(io.LimitReader(body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// cmdServe runs the API until the process is stopped
+func cmdServe(s *cliSession, args []string, out io.Writer) error {
+	fs := newFlagSet("serve", out)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	staff := fs.String("staff", "", "JSON file of staff API keys (see Authorizer.LoadKeys); without it the API is read-only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	server, err := NewCatalogServer(s.catalog)
+	if err != nil {
+		return err
+	}
+	if *staff != "" {
+		f, err := os.Open(*staff)
+		if err != nil {
+			return err
+		}
+		err = server.Authz.LoadKeys(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "Serving the catalog API on http://%s/items\n", *addr)
+	// A bare http.ListenAndServe would wait forever on slow clients
+	srv := &http.Server{Addr: *addr, Handler: server, ReadHeaderTimeout: 5 * time.Second}
+	err = srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+// API keys the server tests register, for kim the manager and sam the
+// clerk
+const (
+	managerKey = "kim-0123456789abcdef"
+	clerkKey   = "sam-0123456789abcdef"
+)
+
+// newTestServer serves the sample catalog to kim and sam
+func newTestServer(t *testing.T) *CatalogServer {
+	t.Helper()
+	server := Must(NewCatalogServer(sampleCatalog()))
+	assert.NoError(t, server.Authz.AddKey(managerKey, Actor{Name: "kim", Role: RoleManager}))
+	assert.NoError(t, server.Authz.AddKey(clerkKey, Actor{Name: "sam", Role: RoleClerk}))
+	return server
+}
+
+// send makes one request with the API key, if any, and records the answer
+func send(server *CatalogServer, key, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerIdentifiesCallers(t *testing.T) {
+	server := newTestServer(t)
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/items/BK-001", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	assert.Equal(t, get("").Code, http.StatusOK)
+	assert.Equal(t, get("Bearer "+managerKey).Code, http.StatusOK)
+	for _, auth := range []string{"Bearer guess-0123456789abcd", "Basic a2ltOnNlY3JldA=="} {
+		rec := get(auth)
+		assert.Equal(t, rec.Code, http.StatusUnauthorized)
+		assert.Equal(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+	}
+}
+
+func TestServerAuthorizesWrites(t *testing.T) {
+	server := newTestServer(t)
+	book := `{"sku": "BK-002", "type": "book", "item": {"title": "Dune", "author": "Frank Herbert", "price": 9.99}}`
+
+	tests := []struct {
+		name, key, method, path, body string
+		want                          int
+	}{
+		{"anonymous create", "", "POST", "/items", book, http.StatusForbidden},
+		{"clerk create", clerkKey, "POST", "/items", book, http.StatusForbidden},
+		{"anonymous price", "", "PUT", "/items/BK-001/price", `{"price": 1}`, http.StatusForbidden},
+		{"clerk price", clerkKey, "PUT", "/items/BK-001/price", `{"price": 1}`, http.StatusForbidden},
+		{"anonymous discount", "", "POST", "/items/BK-001/discount", `{"percent": 10}`, http.StatusForbidden},
+		{"anonymous read", "", "GET", "/items/BK-001", "", http.StatusOK},
+		{"manager price", managerKey, "PUT", "/items/BK-001/price", `{"price": 11.99}`, http.StatusOK},
+		{"manager discount", managerKey, "POST", "/items/BK-001/discount", `{"percent": 10}`, http.StatusOK},
+		{"manager create", managerKey, "POST", "/items", book, http.StatusCreated},
+		{"manager create again", managerKey, "POST", "/items", book, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := send(server, tt.key, tt.method, tt.path, tt.body)
+			assert.Equal(t, rec.Code, tt.want)
+		})
+	}
+	assert.Equal(t, Must(server.catalog.Price("BK-001")), Dollars(11.99))
+
+	// A clerk's batch may adjust stock but not change prices
+	rec := send(server, clerkKey, "POST", "/batch", `{"operations": [
+		{"op": "adjust_stock", "sku": "MG-001", "delta": 5},
+		{"op": "update_price", "sku": "MG-001", "price": 1}]}`)
+	body := rec.Body.String()
+	if !strings.Contains(body, `"status":200`) || !strings.Contains(body, `"status":403`) {
+		t.Errorf("clerk batch: %s", body)
+	}
+	assert.Equal(t, Must(server.catalog.Price("MG-001")), Dollars(12.99))
+}
+
+func TestServerSetPrice(t *testing.T) {
+	server := newTestServer(t)
+	tests := []struct {
+		name, path, body string
+		want             int
+	}{
+		{"new price", "/items/BK-001/price", `{"price": 10.49, "reason": "spring sale"}`, http.StatusOK},
+		{"in euros", "/items/MG-001/price", `{"price": 11, "currency": "EUR"}`, http.StatusOK},
+		{"unknown item", "/items/BK-404/price", `{"price": 1}`, http.StatusNotFound},
+		{"no price", "/items/BK-001/price", `{}`, http.StatusBadRequest},
+		{"typo", "/items/BK-001/price", `{"prise": 1}`, http.StatusBadRequest},
+		{"negative", "/items/BK-001/price", `{"price": -1}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := send(server, managerKey, "PUT", tt.path, tt.body)
+			assert.Equal(t, rec.Code, tt.want)
+		})
+	}
+	assert.Equal(t, Must(server.catalog.Price("BK-001")), Dollars(10.49))
+	assert.Equal(t, Must(server.catalog.Price("MG-001")), Must(NewMoney(1100, "EUR")))
+
+	// The response is the updated item
+	var resp itemResponse
+	rec := send(server, managerKey, "PUT", "/items/BK-001/price", `{"price": 9.99}`)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, resp.SKU, "BK-001")
+	if !strings.Contains(string(resp.Item), `"price":9.99`) {
+		t.Errorf("response has the old price: %s", resp.Item)
+	}
+}
+
+func TestServerThrottleRetryAfter(t *testing.T) {
+	server := newTestServer(t)
+	clock := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	server.Throttle = Must(NewPriceChangeLimiter(1, time.Hour))
+	server.Throttle.now = func() time.Time { return clock }
+
+	assert.Equal(t, send(server, managerKey, "PUT", "/items/BK-001/price", `{"price": 11.99}`).Code, http.StatusOK)
+	clock = clock.Add(20*time.Minute + 500*time.Millisecond)
+	rec := send(server, managerKey, "PUT", "/items/BK-001/price", `{"price": 10.99}`)
+	assert.Equal(t, rec.Code, http.StatusTooManyRequests)
+	// Whole seconds, rounded up so a client retrying then succeeds
+	assert.Equal(t, rec.Header().Get("Retry-After"), "2400")
+	// The price it already has is no change, and not throttled
+	assert.Equal(t, send(server, managerKey, "PUT", "/items/BK-001/price", `{"price": 11.99}`).Code, http.StatusOK)
+}
+
+func TestServerDiscountError(t *testing.T) {
+	server := newTestServer(t)
+	inner := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+	bundle := Must(NewBundle("Sci-fi starter", MustPercent(10)))
+	assert.NoError(t, bundle.Add(inner))
+	assert.NoError(t, bundle.Add(Must(NewMagazine("Analog", Dollars(7.99), 1))))
+	assert.NoError(t, server.catalog.Add("BN-001", bundle))
+	// One of the contents is now priced in euros, so there is no total
+	assert.NoError(t, inner.SetPrice(Must(NewMoney(999, "EUR"))))
+
+	rec := send(server, managerKey, "POST", "/items/BN-001/discount", `{"percent": 10}`)
+	assert.Equal(t, rec.Code, http.StatusUnprocessableEntity)
+	rec = send(server, managerKey, "POST", "/items/BK-404/discount", `{"percent": 10}`)
+	assert.Equal(t, rec.Code, http.StatusNotFound)
+	rec = send(server, managerKey, "POST", "/items/BK-001/discount", `{"percent": 120}`)
+	assert.Equal(t, rec.Code, http.StatusUnprocessableEntity)
+}
+
+func TestServerConcurrentReadsAndWrites(t *testing.T) {
+	server := newTestServer(t)
+	server.Throttle = nil
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			send(server, managerKey, "PUT", "/items/BK-001/price", `{"price": 1`+strconv.Itoa(i)+`}`)
+		}()
+		go func() {
+			defer wg.Done()
+			send(server, "", "GET", "/items", "")
+		}()
+	}
+	wg.Wait()
+}
+
+// TestServerConcurrentPriceAndBatch runs single price changes next to
+// batches that change the same price and roll back, all through the
+// throttle. Run with -race.
+func TestServerConcurrentPriceAndBatch(t *testing.T) {
+	server := newTestServer(t)
+	server.Throttle = Must(NewPriceChangeLimiter(1000, time.Hour))
+	// Stock adjustments between the price change and the failure keep
+	// the batch busy while single changes come in
+	stock := strings.Repeat(`{"op": "adjust_stock", "sku": "MG-001", "delta": 1},`, 50)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 5 {
+				send(server, managerKey, "PUT", "/items/BK-001/price", fmt.Sprintf(`{"price": 10.%02d}`, i*5+j))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			rec := send(server, managerKey, "POST", "/batch", `{"transactional": true, "operations": [
+				{"op": "update_price", "sku": "BK-001", "price": 20.`+fmt.Sprintf("%02d", i)+`},`+stock+`
+				{"op": "update_price", "sku": "BK-404", "price": 1}]}`)
+			assert.Equal(t, rec.Code, http.StatusConflict)
+		}()
+	}
+	wg.Wait()
+	// Every batch rolled back, so the price is one a PUT set
+	price := Must(server.catalog.Price("BK-001"))
+	if c, _ := price.Cmp(Dollars(20)); c >= 0 {
+		t.Errorf("price %v was left by a rolled back batch", price)
+	}
+}
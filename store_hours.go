@@ -0,0 +1,130 @@
+package main
+
+// ------------------- STORE HOURS & SHIPPING ------------------
+// Opening hours per weekday, holidays, and the order cutoff rule:
+// orders placed before the cutoff on a business day ship that day,
+// later orders ship on the next business day.
+//
+// time.Duration counts nanoseconds, so 17*time.Hour reads naturally
+// as "17:00" when used as an offset from midnight.
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpeningHours is one day's window, as offsets from midnight
+type OpeningHours struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// StoreHours configures when the store operates
+type StoreHours struct {
+	// Weekly hours; a weekday missing from the map is a closed day
+	Weekly map[time.Weekday]OpeningHours
+	// Orders at or after this time of day ship on the next business day.
+	// A day closing earlier uses its closing time instead.
+	Cutoff time.Duration
+	// Location is the store's time zone (nil means UTC)
+	Location *time.Location
+
+	holidays map[string]bool // keyed by "2006-01-02"
+}
+
+// DefaultStoreHours: weekdays 9-18, Saturday 10-16, cutoff 17:00
+func DefaultStoreHours() *StoreHours {
+	weekday := OpeningHours{Open: 9 * time.Hour, Close: 18 * time.Hour}
+	return &StoreHours{
+		Weekly: map[time.Weekday]OpeningHours{
+			time.Monday:    weekday,
+			time.Tuesday:   weekday,
+			time.Wednesday: weekday,
+			time.Thursday:  weekday,
+			time.Friday:    weekday,
+			time.Saturday:  {Open: 10 * time.Hour, Close: 16 * time.Hour},
+		},
+		Cutoff: 17 * time.Hour,
+	}
+}
+
+// AddHoliday closes the store for the whole calendar day of date
+func (s *StoreHours) AddHoliday(date time.Time) {
+	if s.holidays == nil {
+		s.holidays = make(map[string]bool)
+	}
+	s.holidays[s.local(date).Format(time.DateOnly)] = true
+}
+
+// IsBusinessDay reports whether the store opens at all on t's date
+func (s *StoreHours) IsBusinessDay(t time.Time) bool {
+	t = s.local(t)
+	if s.holidays[t.Format(time.DateOnly)] {
+		return false
+	}
+	_, open := s.Weekly[t.Weekday()]
+	return open
+}
+
+// IsOpen reports whether the store is open at instant t
+func (s *StoreHours) IsOpen(t time.Time) bool {
+	if !s.IsBusinessDay(t) {
+		return false
+	}
+	t = s.local(t)
+	hours := s.Weekly[t.Weekday()]
+	sinceMidnight := t.Sub(startOfDay(t))
+	return sinceMidnight >= hours.Open && sinceMidnight < hours.Close
+}
+
+// NextShipDate returns the date (at midnight, store time) on which an
+// order placed at t ships. It returns the zero time when no business
+// day is found within a year, i.e. the store is configured as closed.
+func (s *StoreHours) NextShipDate(t time.Time) time.Time {
+	t = s.local(t)
+	day := startOfDay(t)
+	if s.IsBusinessDay(t) && t.Sub(day) < s.cutoff(t.Weekday()) {
+		return day
+	}
+	for i := 0; i < 366; i++ {
+		// AddDate (not Add(24*time.Hour)) keeps midnight across DST changes
+		day = day.AddDate(0, 0, 1)
+		if s.IsBusinessDay(day) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+// cutoff is the order cutoff on weekday: Cutoff, or closing time if the
+// store closes earlier (Saturday 16:00 with a 17:00 cutoff)
+func (s *StoreHours) cutoff(weekday time.Weekday) time.Duration {
+	return min(s.Cutoff, s.Weekly[weekday].Close)
+}
+
+// ShippingMessage is the line shown to a customer placing an order at t
+func (s *StoreHours) ShippingMessage(t time.Time) string {
+	ship := s.NextShipDate(t)
+	switch {
+	case ship.IsZero():
+		return "Shipping is currently unavailable"
+	case ship.Equal(startOfDay(s.local(t))):
+		return "Order now and it ships today"
+	default:
+		return fmt.Sprintf("Ships on %s", ship.Format("Monday, Jan 2"))
+	}
+}
+
+// local converts t to the store's time zone
+func (s *StoreHours) local(t time.Time) time.Time {
+	if s.Location == nil {
+		return t.UTC()
+	}
+	return t.In(s.Location)
+}
+
+// startOfDay returns midnight of t's date in t's location
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
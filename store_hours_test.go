@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+)
+
+func TestNextShipDate(t *testing.T) {
+	hours := DefaultStoreHours()
+	hours.AddHoliday(time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC)) // a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	date := func(day int) time.Time { return at(day, 0, 0) }
+
+	tests := []struct {
+		name   string
+		placed time.Time
+		want   time.Time
+	}{
+		{"weekday before cutoff", at(14, 16, 59), date(14)},
+		{"weekday at cutoff", at(14, 17, 0), date(15)},
+		{"Friday after cutoff", at(15, 17, 30), date(16)},
+		{"Saturday before closing", at(16, 15, 59), date(16)},
+		// Saturday closes at 16:00, before the 17:00 cutoff
+		{"Saturday after closing", at(16, 16, 30), date(19)},
+		{"Sunday, then a holiday", at(17, 10, 0), date(19)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, hours.NextShipDate(tt.placed), tt.want)
+		})
+	}
+}
+
+func TestShippingMessage(t *testing.T) {
+	hours := DefaultStoreHours()
+	saturday := time.Date(2024, time.March, 16, 16, 30, 0, 0, time.UTC)
+	assert.Equal(t, hours.ShippingMessage(saturday), "Ships on Monday, Mar 18")
+	assert.Equal(t, hours.ShippingMessage(saturday.Add(-time.Hour)), "Order now and it ships today")
+}
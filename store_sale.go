@@ -0,0 +1,125 @@
+package main
+
+// ------------------- STORE-WIDE SALE -------------------------
+// A sale takes a percentage off every eligible item. Some items are
+// blacked out (new releases, gift cards...) and some have a floor price
+// the sale may not go below.
+//
+// The whole sale configuration is swapped in one atomic step: readers
+// either see the old sale or the new one, never half of each. This uses
+// sync/atomic.Pointer, which needs no lock for readers.
+
+import (
+	"sync/atomic"
+)
+
+// SaleConfig describes one store-wide sale
+type SaleConfig struct {
+	Discount Percent
+	// Blackout lists items excluded from the sale
+	Blackout []PricedItem
+	// BlackoutCategories excludes whole categories (see Categorized)
+	BlackoutCategories []string
+	// Floors maps an item to the lowest price the sale may charge
+	Floors map[PricedItem]Money
+}
+
+// SaleEvent is sent to listeners whenever the sale starts or ends
+type SaleEvent struct {
+	Active   bool
+	Discount Percent
+}
+
+// StoreSale is safe to read from many goroutines at once.
+// Register listeners with OnChange before sharing it.
+type StoreSale struct {
+	current   atomic.Pointer[saleState]
+	listeners []func(SaleEvent)
+}
+
+// saleState is never modified after it is stored, which is what makes
+// sharing it without a lock safe
+type saleState struct {
+	discount   Percent
+	blackout   map[PricedItem]bool
+	categories map[string]bool
+	floors     map[PricedItem]Money
+}
+
+// OnChange registers fn to be called on every Activate/Deactivate,
+// e.g. to clear caches or show a banner
+func (s *StoreSale) OnChange(fn func(SaleEvent)) {
+	s.listeners = append(s.listeners, fn)
+}
+
+// Activate starts (or replaces) the sale
+func (s *StoreSale) Activate(cfg SaleConfig) {
+	state := &saleState{
+		discount:   cfg.Discount,
+		blackout:   make(map[PricedItem]bool),
+		categories: make(map[string]bool),
+		floors:     make(map[PricedItem]Money),
+	}
+	// Copy everything so later changes to cfg can't leak into the sale
+	for _, item := range cfg.Blackout {
+		state.blackout[item] = true
+	}
+	for _, c := range cfg.BlackoutCategories {
+		state.categories[c] = true
+	}
+	for item, floor := range cfg.Floors {
+		state.floors[item] = floor
+	}
+	s.current.Store(state)
+	s.notify(SaleEvent{Active: true, Discount: cfg.Discount})
+}
+
+// Deactivate ends the sale; prices return to normal immediately
+func (s *StoreSale) Deactivate() {
+	if s.current.Swap(nil) != nil {
+		s.notify(SaleEvent{Active: false})
+	}
+}
+
+// Active reports whether a sale is running
+func (s *StoreSale) Active() bool {
+	return s.current.Load() != nil
+}
+
+// Price returns what the item costs right now, sale included
+func (s *StoreSale) Price(item PricedItem) Money {
+	price, _ := s.Apply(item.Price(), DiscountContext{Item: item})
+	return price
+}
+
+// Name and Apply make the sale a DiscountPolicy, so a PricingEngine can
+// weigh it against coupons, member prices and the like
+func (s *StoreSale) Name() string {
+	return "store sale"
+}
+
+func (s *StoreSale) Apply(regular Money, ctx DiscountContext) (Money, bool) {
+	// Load once and use that snapshot for the whole calculation
+	state := s.current.Load()
+	if state == nil || state.blackout[ctx.Item] {
+		return regular, false
+	}
+	if state.categories[categoryOf(ctx.Item)] {
+		return regular, false
+	}
+	sale := regular.Off(state.discount)
+	// A floor only ever raises the sale price, never the regular one
+	if floor, ok := state.floors[ctx.Item]; ok && sale.Less(floor) {
+		if regular.Less(floor) {
+			return regular, false
+		}
+		return floor, true
+	}
+	return sale, true
+}
+
+func (s *StoreSale) notify(e SaleEvent) {
+	for _, fn := range s.listeners {
+		fn(e)
+	}
+}
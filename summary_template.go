@@ -0,0 +1,119 @@
+package main
+
+// ------------------- SUMMARY TEMPLATES -----------------------
+// How an item is summarized in listings can be changed per category
+// with text/template, Go's version of Jinja-style templates:
+//
+//	{{.Title}} — {{.Author}} ({{.Pages}}p) {{currency .Price}}
+//
+// {{.Field}} inserts a field of summaryData; {{currency .Price}} calls
+// one of the functions registered in summaryFuncs. Categories without an
+// override use the defaults below.
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// defaultSummaryTemplates match Book.Summary and the magazine listings
+var defaultSummaryTemplates = map[string]string{
+	CategoryCode:          "{{.Title}} by {{.Author}} - {{currency .Price}}",
+	MagazineCategoryCode:  "{{.Title}} #{{.Issue}} - {{currency .Price}}",
+	EBookCategoryCode:     "{{.Title}} by {{.Author}} ({{.Format}}) - {{currency .Price}}",
+	AudioBookCategoryCode: "{{.Title}} by {{.Author}}, read by {{.Narrator}} - {{with .PriceNote}}{{.}}{{else}}{{currency .Price}}{{end}}",
+	"":                    "{{.Title}} - {{currency .Price}}",
+}
+
+var summaryFuncs = template.FuncMap{
+	"currency": Money.String,
+	"upper":    strings.ToUpper,
+}
+
+// summaryData is what a template can refer to; fields that don't apply
+// to an item (a magazine's Author) are empty
+type summaryData struct {
+	Title    string
+	Author   string
+	Pages    int
+	Issue    int
+	Format   string
+	Narrator string
+	// PriceNote explains the price, for items that are PriceExplainers
+	PriceNote string
+	Price     Money
+	Category  string
+}
+
+// SummaryTemplates renders item summaries, one template per category
+type SummaryTemplates struct {
+	// Languages are the preferred languages for titles, most preferred
+	// first; see ParseLanguagePreferences
+	Languages []string
+
+	byCategory map[string]*template.Template
+}
+
+// NewSummaryTemplates parses the defaults plus overrides (category ->
+// template text). A template that doesn't parse is reported here, not
+// when the first item is rendered.
+func NewSummaryTemplates(overrides map[string]string) (*SummaryTemplates, error) {
+	t := &SummaryTemplates{byCategory: make(map[string]*template.Template)}
+	for category, text := range defaultSummaryTemplates {
+		t.byCategory[category] = template.Must(template.New(category).Funcs(summaryFuncs).Parse(text))
+	}
+	for category, text := range overrides {
+		category = strings.ToUpper(category)
+		parsed, err := template.New(category).Funcs(summaryFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("summary template for %s: %w", category, err)
+		}
+		t.byCategory[category] = parsed
+	}
+	return t, nil
+}
+
+// ParseSummaryOverrides reads "CATEGORY=template" specs, as given on
+// the command line
+func ParseSummaryOverrides(specs []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, spec := range specs {
+		category, text, ok := strings.Cut(spec, "=")
+		if !ok || category == "" {
+			return nil, fmt.Errorf("format %q: want CATEGORY=template", spec)
+		}
+		overrides[category] = text
+	}
+	return overrides, nil
+}
+
+// Render summarizes item with its category's template
+func (t *SummaryTemplates) Render(item PricedItem) (string, error) {
+	text, _ := localizeFor(item, t.Languages)
+	data := summaryData{Title: text.Title, Price: item.Price(), Category: categoryOf(item)}
+	switch v := item.(type) {
+	case *Book:
+		data.Author = v.author
+		data.Pages = v.pageCount
+	case *Magazine:
+		data.Issue = v.issueNumber
+	case *EBook:
+		data.Author = v.author
+		data.Format = v.format.String()
+	case *AudioBook:
+		data.Author = v.author
+		data.Narrator = v.narrator
+	}
+	if explainer, ok := item.(PriceExplainer); ok {
+		data.PriceNote = explainer.PriceNote()
+	}
+	tmpl, ok := t.byCategory[data.Category]
+	if !ok {
+		tmpl = t.byCategory[""]
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,105 @@
+package main
+
+// ------------------- TAX -------------------------------------
+// Sales tax and VAT depend on where the buyer is and on what is bought:
+// Germany charges 7% VAT on books but 19% on most other goods, the UK
+// charges 0% on both books and magazines. A TaxCalculator answers "which
+// rate applies to this item in this region?"; the cart then adds one
+// breakdown line per rate to the order.
+//
+// Catalog prices here are net (tax not included), as in US-style
+// pricing. Tax is rounded once per rate, on the sum of the lines that
+// share it, which is how most invoices show it.
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TaxRate is a rate with the label shown on receipts, e.g. "VAT 7%"
+type TaxRate struct {
+	Label string
+	Rate  Percent
+}
+
+// TaxCalculator finds the rate for an item sold into region
+type TaxCalculator interface {
+	TaxRate(item PricedItem, region string) (TaxRate, error)
+}
+
+// FlatTax charges the same rate on everything, everywhere
+type FlatTax TaxRate
+
+func (f FlatTax) TaxRate(item PricedItem, region string) (TaxRate, error) {
+	return TaxRate(f), nil
+}
+
+// TaxRule is one row of a RegionalTax table. An empty Category matches
+// every item in the region.
+type TaxRule struct {
+	Region   string
+	Category string // e.g. CategoryCode or MagazineCategoryCode
+	TaxRate
+}
+
+// RegionalTax looks rates up by region and item category. A rule for
+// the item's category wins over the region's catch-all rule.
+type RegionalTax struct {
+	Rules []TaxRule
+}
+
+func (r RegionalTax) TaxRate(item PricedItem, region string) (TaxRate, error) {
+	category := categoryOf(item)
+	var fallback *TaxRule
+	for i, rule := range r.Rules {
+		if !strings.EqualFold(rule.Region, region) {
+			continue
+		}
+		if rule.Category == category {
+			return rule.TaxRate, nil
+		}
+		if rule.Category == "" && fallback == nil {
+			fallback = &r.Rules[i]
+		}
+	}
+	if fallback != nil {
+		return fallback.TaxRate, nil
+	}
+	return TaxRate{}, fmt.Errorf("no tax rule for region %q", region)
+}
+
+// TaxLine is one line of a tax breakdown: Rate applied to Taxable
+type TaxLine struct {
+	TaxRate
+	Taxable Money
+	Amount  Money
+}
+
+// taxBreakdown groups lines (unit price × quantity) by tax rate and
+// computes each group's tax
+func taxBreakdown(calc TaxCalculator, region string, lines []OrderLine) ([]TaxLine, error) {
+	var breakdown []TaxLine
+	// taxable[i] adds up breakdown[i]'s lines
+	var taxable []lineSum
+	for _, l := range lines {
+		rate, err := calc.TaxRate(l.Item, region)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", itemTitle(l.Item), err)
+		}
+		i := slices.IndexFunc(breakdown, func(t TaxLine) bool { return t.TaxRate == rate })
+		if i < 0 {
+			breakdown = append(breakdown, TaxLine{TaxRate: rate})
+			taxable = append(taxable, lineSum{})
+			i = len(breakdown) - 1
+		}
+		if err := taxable[i].add(l.Paid, l.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	for i := range breakdown {
+		breakdown[i].Taxable = taxable[i].total
+		breakdown[i].Amount = breakdown[i].Taxable.Portion(breakdown[i].Rate)
+	}
+	return breakdown, nil
+}
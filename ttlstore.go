@@ -0,0 +1,212 @@
+package main
+
+// ------------------- EXPIRING KEYS ---------------------------
+// Reservation holds (see reservations.go) live for a while and then go
+// away, as would sessions or idempotency keys. TTLStore is one place
+// for that, modelled on Redis' SET key value EX seconds:
+//
+//   - a key past its deadline is gone: Get removes it on the spot
+//     ("lazy" expiry, as Redis does)
+//   - keys nobody asks for are removed by a sweep ("active" expiry), so
+//     the store doesn't fill up with dead entries
+//
+// A goroutine or time.AfterFunc per key would be simplest, but 100k
+// keys would mean 100k timers. Instead the deadlines sit in a min-heap
+// (container/heap, like Python's heapq) and a single timer is set for
+// the earliest one. Setting or removing a key is O(log n).
+//
+// onExpire runs for every key that expires (not for Delete), outside
+// the lock, so it may use the store, e.g. to release a reservation.
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// TTLStore maps keys to values that expire. It is safe for concurrent
+// use; call Close when done to stop the sweep timer.
+type TTLStore[K comparable, V any] struct {
+	mu       sync.Mutex
+	entries  map[K]*ttlEntry[K, V]
+	byExpiry ttlHeap[K, V]
+	timer    *time.Timer
+	closed   bool
+	onExpire func(K, V)
+	// now is currentTime, replaceable so callers can control the clock
+	now func() time.Time
+}
+
+type ttlEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+	index   int // position in the heap, kept up to date by Swap
+}
+
+// NewTTLStore returns an empty store; onExpire may be nil
+func NewTTLStore[K comparable, V any](onExpire func(K, V)) *TTLStore[K, V] {
+	return &TTLStore[K, V]{
+		entries:  make(map[K]*ttlEntry[K, V]),
+		onExpire: onExpire,
+		now:      currentTime,
+	}
+}
+
+// Set stores value under key for ttl, replacing any previous value and
+// deadline, like Redis' SET with EX
+func (s *TTLStore[K, V]) Set(key K, value V, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires := s.now().Add(ttl)
+	if e, ok := s.entries[key]; ok {
+		e.value, e.expires = value, expires
+		heap.Fix(&s.byExpiry, e.index)
+	} else {
+		e := &ttlEntry[K, V]{key: key, value: value, expires: expires}
+		s.entries[key] = e
+		heap.Push(&s.byExpiry, e)
+	}
+	s.schedule()
+}
+
+// Get returns the value under key, if it hasn't expired
+func (s *TTLStore[K, V]) Get(key K) (V, bool) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	if !ok {
+		s.mu.Unlock()
+		var zero V
+		return zero, false
+	}
+	if s.now().Before(e.expires) {
+		value := e.value
+		s.mu.Unlock()
+		return value, true
+	}
+	s.remove(e)
+	s.schedule()
+	s.mu.Unlock()
+	s.expired([]*ttlEntry[K, V]{e})
+	var zero V
+	return zero, false
+}
+
+// TTL returns how long key has left, like Redis' TTL command
+func (s *TTLStore[K, V]) TTL(key K) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return 0, false
+	}
+	left := e.expires.Sub(s.now())
+	return left, left > 0
+}
+
+// Delete removes key before it expires; onExpire isn't called
+func (s *TTLStore[K, V]) Delete(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if ok {
+		s.remove(e)
+		s.schedule()
+	}
+	return ok
+}
+
+// Len counts the stored keys, including expired ones not swept yet
+func (s *TTLStore[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Sweep removes every expired key now. The timer calls it when the
+// earliest deadline passes; it can also be called directly.
+func (s *TTLStore[K, V]) Sweep() {
+	s.mu.Lock()
+	now := s.now()
+	var done []*ttlEntry[K, V]
+	for len(s.byExpiry) > 0 && !now.Before(s.byExpiry[0].expires) {
+		e := s.byExpiry[0]
+		s.remove(e)
+		done = append(done, e)
+	}
+	s.schedule()
+	s.mu.Unlock()
+	s.expired(done)
+}
+
+// Close stops the sweep timer. Get still expires keys lazily.
+func (s *TTLStore[K, V]) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// remove takes e out of the map and the heap; the caller holds mu
+func (s *TTLStore[K, V]) remove(e *ttlEntry[K, V]) {
+	heap.Remove(&s.byExpiry, e.index)
+	delete(s.entries, e.key)
+}
+
+// schedule sets the single timer for the earliest deadline; the caller
+// holds mu. In deterministic mode there are no background sweeps.
+func (s *TTLStore[K, V]) schedule() {
+	if s.closed || !backgroundJobs {
+		return
+	}
+	if len(s.byExpiry) == 0 {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		return
+	}
+	delay := max(s.byExpiry[0].expires.Sub(s.now()), 0)
+	if s.timer == nil {
+		s.timer = time.AfterFunc(delay, s.Sweep)
+	} else {
+		s.timer.Reset(delay)
+	}
+}
+
+// expired reports removed entries to onExpire; the caller must not
+// hold mu
+func (s *TTLStore[K, V]) expired(entries []*ttlEntry[K, V]) {
+	if s.onExpire == nil {
+		return
+	}
+	for _, e := range entries {
+		s.onExpire(e.key, e.value)
+	}
+}
+
+// ttlHeap orders entries by deadline for container/heap
+type ttlHeap[K comparable, V any] []*ttlEntry[K, V]
+
+func (h ttlHeap[K, V]) Len() int           { return len(h) }
+func (h ttlHeap[K, V]) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+
+func (h ttlHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *ttlHeap[K, V]) Push(x any) {
+	e := x.(*ttlEntry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *ttlHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"learn-golang/internal/assert"
+	"learn-golang/internal/leaktest"
+)
+
+func TestTTLStoreExpiry(t *testing.T) {
+	leaktest.Check(t)
+	var expired []string
+	store := NewTTLStore(func(k string, v int) { expired = append(expired, fmt.Sprintf("%s=%d", k, v)) })
+	defer store.Close()
+	clock := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return clock }
+
+	store.Set("a", 1, time.Minute)
+	store.Set("b", 2, 2*time.Minute)
+	store.Set("c", 3, 3*time.Minute)
+	store.Set("a", 4, 4*time.Minute) // a new deadline replaces the old one
+	assert.Equal(t, store.Delete("c"), true)
+
+	clock = clock.Add(2 * time.Minute)
+	// Lazy expiry: b is removed by the Get that finds it late
+	_, ok := store.Get("b")
+	assert.Equal(t, ok, false)
+	v, ok := store.Get("a")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, v, 4)
+	left, ok := store.TTL("a")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, left, 2*time.Minute)
+
+	clock = clock.Add(2 * time.Minute)
+	store.Sweep()
+	assert.Equal(t, expired, []string{"b=2", "a=4"})
+	assert.Equal(t, store.Len(), 0)
+}
+
+func TestTTLStoreTimerSweeps(t *testing.T) {
+	leaktest.Check(t)
+	expired := make(chan string, 1)
+	store := NewTTLStore(func(k string, _ int) { expired <- k })
+	t.Cleanup(store.Close)
+	store.Set("hold", 1, 5*time.Millisecond)
+	select {
+	case k := <-expired:
+		assert.Equal(t, k, "hold")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the timer never swept")
+	}
+	assert.Equal(t, store.Len(), 0)
+}
+
+// BenchmarkTTLStore100k sets 100,000 keys with spread-out deadlines and
+// then sweeps them all
+func BenchmarkTTLStore100k(b *testing.B) {
+	const n = 100_000
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("hold-%d", i)
+	}
+	b.ReportAllocs()
+	for range b.N {
+		store := NewTTLStore[string, int](nil)
+		clock := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+		store.now = func() time.Time { return clock }
+		for i, k := range keys {
+			store.Set(k, i, time.Duration(i*7919%n)*time.Millisecond)
+		}
+		clock = clock.Add(time.Duration(n) * time.Millisecond)
+		store.Sweep()
+		if store.Len() != 0 {
+			b.Fatalf("%d keys left after the sweep", store.Len())
+		}
+		store.Close()
+	}
+}
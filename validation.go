@@ -0,0 +1,153 @@
+package main
+
+// ------------------- VALIDATION ------------------------------
+// Every item type has a Validate method that checks all of its fields
+// and reports every problem at once, not just the first one, like a
+// form that highlights each invalid field (think pydantic's
+// ValidationError in Python):
+//
+//	invalid book "": title is required; price cannot be negative
+//
+// The constructors call Validate and return an error, so an invalid
+// item can't be created. UnmarshalJSON calls it too. In source code,
+// where the values are known to be good, wrap a constructor in Must.
+//
+// The fields are collected with a small validation helper. The result
+// is a *ValidationError whose Unwrap returns one FieldError per field,
+// so errors.As finds the individual problems and errors.Is finds the
+// sentinels behind them: errors.Is(err, ErrNegativePrice).
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with one field
+type FieldError struct {
+	Field   string
+	Problem string
+	Err     error // sentinel behind Problem, such as ErrNegativePrice; may be nil
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Problem
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists everything wrong with one value
+type ValidationError struct {
+	Subject string // e.g. `book "Dune"`
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := Map(e.Fields, FieldError.Error)
+	return fmt.Sprintf("invalid %s: %s", e.Subject, strings.Join(problems, "; "))
+}
+
+// Unwrap makes errors.Is and errors.As look at each field's error
+func (e *ValidationError) Unwrap() []error {
+	return Map(e.Fields, func(f FieldError) error { return f })
+}
+
+// validation collects field errors while a Validate method runs
+type validation struct {
+	subject string
+	fields  []FieldError
+}
+
+// check records problem for field unless ok
+func (v *validation) check(ok bool, field, problem string) {
+	if !ok {
+		v.fields = append(v.fields, FieldError{Field: field, Problem: problem})
+	}
+}
+
+// required checks that a text field isn't blank
+func (v *validation) required(field, value string) {
+	v.check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// price checks that a price isn't negative. Whether one was given at
+// all is for the parsing to tell: the zero Money is $0.00, a valid
+// price, so a Money can't be missing.
+func (v *validation) price(field string, m Money) {
+	if m.IsNegative() {
+		v.fields = append(v.fields, FieldError{Field: field, Problem: "cannot be negative", Err: ErrNegativePrice})
+	}
+}
+
+// err returns the collected problems as a *ValidationError, or nil
+func (v *validation) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Subject: v.subject, Fields: v.fields}
+}
+
+// Must returns v, panicking if err is not nil. It is for constructors
+// called with values known to be valid, like MustPercent:
+//
+//	book := Must(NewBook("Dune", "Frank Herbert", Dollars(9.99), ""))
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (b *Book) Validate() error {
+	v := validation{subject: fmt.Sprintf("book %q", b.title)}
+	v.required("title", b.title)
+	v.required("author", b.author)
+	v.price("price", b.price)
+	// 0 means unknown, e.g. a book loaded from a file without it
+	v.check(b.pageCount >= 0 && b.pageCount <= MaxPageCount, "page count",
+		fmt.Sprintf("must be between 1 and %d", MaxPageCount))
+	_, err := b.Excerpt()
+	v.check(err == nil, "excerpt", fmt.Sprintf("is unreadable: %v", err))
+	return v.err()
+}
+
+func (m *Magazine) Validate() error {
+	v := validation{subject: fmt.Sprintf("magazine %q", m.name)}
+	v.required("name", m.name)
+	v.price("price", m.price)
+	v.check(m.issueNumber > 0, "issue number", "must be positive")
+	return v.err()
+}
+
+func (e *EBook) Validate() error {
+	v := validation{subject: fmt.Sprintf("e-book %q", e.title)}
+	v.required("title", e.title)
+	v.required("author", e.author)
+	v.price("price", e.price)
+	_, err := ParseEBookFormat(e.format.String())
+	v.check(err == nil, "format", "must be EPUB, PDF or MOBI")
+	v.check(e.fileSize > 0 && e.fileSize <= MaxEBookSize, "file size",
+		"must be between 1 byte and "+formatFileSize(MaxEBookSize))
+	return v.err()
+}
+
+func (a *AudioBook) Validate() error {
+	v := validation{subject: fmt.Sprintf("audiobook %q", a.title)}
+	v.required("title", a.title)
+	v.required("author", a.author)
+	v.price("price", a.listPrice)
+	v.check(a.duration > 0 && a.duration <= MaxAudioDuration, "duration",
+		fmt.Sprintf("must be between 1s and %v", MaxAudioDuration))
+	v.check(a.Pricing == PayPerTitle || a.Pricing == SubscriptionCredit, "pricing",
+		fmt.Sprintf("must be %q or %q", PayPerTitle, SubscriptionCredit))
+	return v.err()
+}
+
+func (b *Bundle) Validate() error {
+	v := validation{subject: fmt.Sprintf("bundle %q", b.title)}
+	v.required("title", b.title)
+	_, err := b.ContentsTotal()
+	v.check(err == nil, "items", fmt.Sprintf("cannot be added up: %v", err))
+	return v.err()
+}
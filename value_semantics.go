@@ -0,0 +1,111 @@
+package main
+
+// ------------------- VALUES VS POINTERS ----------------------
+// In Python every variable holds a reference to an object:
+//   a = Book(...); b = a; b.price = 5  -> a.price is also 5
+// Go is different: assigning a struct COPIES it. Only pointers share.
+//   a := *Must(NewBook(...)); b := a; b.price = 5  -> a.price is unchanged
+// This file collects small experiments that make the difference visible.
+
+import "fmt"
+
+// ValueSemanticsReport records what each experiment observed.
+// Every field is a yes/no answer to one question about copying.
+type ValueSemanticsReport struct {
+	// Does changing a by-value copy leave the original untouched?
+	CopyIsIndependent bool
+	// Does changing through a second pointer affect the original?
+	PointerAliasShares bool
+	// Does a function that takes Book (not *Book) see its own copy?
+	ValueParamIsCopy bool
+	// Is a plain Book value a PricedItem? (No: methods use *Book receivers)
+	ValueSatisfiesInterface bool
+	// Is a *Book a PricedItem?
+	PointerSatisfiesInterface bool
+	// Did the copy detector notice that a guarded value was copied?
+	CopyDetected bool
+}
+
+// ExploreValueSemantics runs every experiment on fresh books
+// and returns what was observed.
+func ExploreValueSemantics() ValueSemanticsReport {
+	var report ValueSemanticsReport
+
+	// 1. Copy by value: *original dereferences the pointer
+	// and the assignment copies every field into a new Book
+	original := Must(NewBook("Go in Action", "William Kennedy", Dollars(30), ""))
+	copied := *original
+	copied.price = Dollars(10)
+	report.CopyIsIndependent = original.price == Dollars(30)
+
+	// 2. Copy the pointer: both variables point at the same Book
+	// This is what Python does for every assignment
+	alias := original
+	alias.price = Dollars(20)
+	report.PointerAliasShares = original.price == Dollars(20)
+
+	// 3. Passing a struct to a function also copies it
+	discountCopy := func(b Book) { b.price = Money{} }
+	discountCopy(*original)
+	report.ValueParamIsCopy = original.price == Dollars(20)
+
+	// 4. Method sets: Book's methods have pointer receivers (b *Book),
+	// so only *Book has them. A Book value does NOT satisfy PricedItem.
+	// The compiler rejects "var _ PricedItem = Book{}", so we ask at
+	// runtime with a type assertion on an empty interface instead
+	var asValue any = *original
+	_, report.ValueSatisfiesInterface = asValue.(PricedItem)
+	var asPointer any = original
+	_, report.PointerSatisfiesInterface = asPointer.(PricedItem)
+
+	// 5. Copy detection: see copyGuard below
+	guarded := newGuardedBook(original)
+	duplicate := *guarded
+	report.CopyDetected = duplicate.check() != nil && guarded.check() == nil
+
+	return report
+}
+
+// ------------------- COPY DETECTION ----------------------
+// Some types must never be copied after first use (sync.Mutex is one).
+// strings.Builder protects itself by remembering its own address:
+// if the address changes, the value was copied. We use the same trick.
+type copyGuard struct {
+	self *copyGuard
+}
+
+// init remembers where the guard lives
+func (g *copyGuard) init() {
+	g.self = g
+}
+
+// check reports an error if the guard no longer lives where it started
+func (g *copyGuard) check() error {
+	if g.self != g {
+		return fmt.Errorf("value was copied; use a pointer instead")
+	}
+	return nil
+}
+
+// guardedBook embeds a copyGuard next to the Book it protects
+// Embedding promotes check() so callers can write gb.check()
+type guardedBook struct {
+	copyGuard
+	book *Book
+}
+
+func newGuardedBook(b *Book) *guardedBook {
+	gb := &guardedBook{book: b}
+	gb.init()
+	return gb
+}
+
+// printValueSemantics shows the report in a readable form
+func printValueSemantics(r ValueSemanticsReport) {
+	fmt.Println("Copy is independent of original:", r.CopyIsIndependent)
+	fmt.Println("Pointer alias shares changes:   ", r.PointerAliasShares)
+	fmt.Println("Value parameter is a copy:      ", r.ValueParamIsCopy)
+	fmt.Println("Book value is a PricedItem:     ", r.ValueSatisfiesInterface)
+	fmt.Println("*Book is a PricedItem:          ", r.PointerSatisfiesInterface)
+	fmt.Println("Copy detected by guard:         ", r.CopyDetected)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"learn-golang/internal/assert"
+)
+
+func TestExploreValueSemantics(t *testing.T) {
+	assert.Equal(t, ExploreValueSemantics(), ValueSemanticsReport{
+		CopyIsIndependent:         true,
+		PointerAliasShares:        true,
+		ValueParamIsCopy:          true,
+		ValueSatisfiesInterface:   false,
+		PointerSatisfiesInterface: true,
+		CopyDetected:              true,
+	})
+}
+
+func TestCopyIsIndependent(t *testing.T) {
+	original := Must(NewBook("Go in Action", "William Kennedy", Dollars(30), ""))
+	copied := *original
+	copied.price = Dollars(10)
+	copied.title = "Changed"
+	assert.Equal(t, original.Price(), Dollars(30))
+	assert.Equal(t, original.title, "Go in Action")
+}
+
+func TestPointerAliasShares(t *testing.T) {
+	original := Must(NewBook("Go in Action", "William Kennedy", Dollars(30), ""))
+	alias := original
+	assert.NoError(t, alias.SetPrice(Dollars(20)))
+	assert.Equal(t, original.Price(), Dollars(20))
+}
+
+func TestMethodSets(t *testing.T) {
+	book := Must(NewBook("Go in Action", "William Kennedy", Dollars(30), ""))
+	magazine := Must(NewMagazine("Vogue", Dollars(12), 1))
+	tests := []struct {
+		name  string
+		value any
+		want  bool
+	}{
+		{"*Book", book, true},
+		{"Book", *book, false},
+		{"*Magazine", magazine, true},
+		{"Magazine", *magazine, false},
+	}
+	for _, tt := range tests {
+		_, ok := tt.value.(PricedItem)
+		assert.Equal(t, ok, tt.want)
+	}
+}
+
+func TestCopyGuard(t *testing.T) {
+	guarded := newGuardedBook(Must(NewBook("Go in Action", "William Kennedy", Dollars(30), "")))
+	assert.NoError(t, guarded.check())
+	duplicate := *guarded
+	if duplicate.check() == nil {
+		t.Error("a copied guard passed its check")
+	}
+	// The copy still shares the Book: only the guard itself was copied
+	assert.Equal(t, duplicate.book, guarded.book)
+}